@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sast-demo/pkg/lang/external"
+	"sast-demo/pkg/service"
+)
+
+// runCallGraph prints the call graph of a file or directory
+func runCallGraph(args []string) int {
+	fs := flag.NewFlagSet("callgraph", flag.ExitOnError)
+	frontendsPath := fs.String("frontends", "", "JSON file registering external frontends by file extension")
+	format := fs.String("format", "json", "Output format: json, dot or mermaid")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "callgraph: exactly one file or directory is required")
+		return 2
+	}
+	if *format != "json" && *format != "dot" && *format != "mermaid" {
+		fmt.Fprintf(os.Stderr, "callgraph: unknown format %q\n", *format)
+		return 2
+	}
+
+	var opts service.Options
+	if *frontendsPath != "" {
+		cfgs, err := external.LoadConfig(*frontendsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading frontends: %v\n", err)
+			return 2
+		}
+		opts.Frontends = cfgs
+	}
+
+	result, err := service.BuildCallGraph(fs.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", e)
+	}
+
+	switch *format {
+	case "dot":
+		fmt.Print(result.DOT())
+	case "mermaid":
+		fmt.Print(result.Mermaid())
+	default:
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: sast-cli <command> [flags] <path>
+
+Commands:
+  scan       Analyze a file or directory and print findings
+  callgraph  Print the call graph of a file or directory (JSON, DOT or Mermaid)
+  slice      Print the lines that influence, or are influenced by, a source line
+
+Run 'sast-cli <command> -h' for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var code int
+	switch os.Args[1] {
+	case "scan":
+		code = runScan(os.Args[2:])
+	case "callgraph":
+		code = runCallGraph(os.Args[2:])
+	case "slice":
+		code = runSlice(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		code = 2
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sast-demo/pkg/baseline"
+	"sast-demo/pkg/core"
+	"sast-demo/pkg/lang/external"
+	"sast-demo/pkg/service"
+	"strings"
+)
+
+type scanReport struct {
+	Files         []string             `json:"files"`
+	Findings      []core.Vulnerability `json:"findings"`
+	Suppressed    []core.Vulnerability `json:"suppressed,omitempty"`
+	FixedBaseline []baseline.Entry     `json:"fixed_baseline,omitempty"`
+	Errors        []string             `json:"errors,omitempty"`
+}
+
+// runScan returns 1 when new findings are reported, so CI can block them
+func runScan(args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	frontendsPath := fs.String("frontends", "", "JSON file registering external frontends by file extension")
+	pathsPerSink := fs.Int("k", 1, "Number of shortest taint paths to report per source-sink pair")
+	pathBudget := fs.Int("path-budget", 0, "Maximum dataflow nodes processed per taint solve (0 = engine default)")
+	fieldDepth := fs.Int("field-depth", 0, "Fields kept in taint access paths such as cfg.URL (0 = engine default)")
+	contextDepth := fs.Int("context-depth", 0, "Nested calls analyzed through function summaries (0 = engine default)")
+	implicit := fs.Bool("implicit", false, "Also report implicit flows: output decided by a branch on a source")
+	localInput := fs.Bool("local-input-rule", false, "Report command-line input reaching command execution as rce-local (MEDIUM) instead of rce")
+	elements := fs.String("elements", "key", "Container element precision: key (constant indexes and map keys apart) or container (all elements merged)")
+	baselinePath := fs.String("baseline", "", "Baseline file of accepted findings; only new findings are reported")
+	updateBaseline := fs.Bool("update-baseline", false, "Rewrite the baseline entries of the scanned files from this scan")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "scan: exactly one file or directory is required")
+		return 2
+	}
+	if *updateBaseline && *baselinePath == "" {
+		fmt.Fprintln(os.Stderr, "scan: -update-baseline requires -baseline")
+		return 2
+	}
+
+	opts := service.Options{PathsPerSink: *pathsPerSink, PathBudget: *pathBudget, AccessPathDepth: *fieldDepth, ContextDepth: *contextDepth, ImplicitFlows: *implicit, LocalInputRule: *localInput, ElementPrecision: *elements}
+	if *frontendsPath != "" {
+		cfgs, err := external.LoadConfig(*frontendsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading frontends: %v\n", err)
+			return 2
+		}
+		opts.Frontends = cfgs
+	}
+
+	var base *baseline.Baseline
+	if *baselinePath != "" {
+		var err error
+		if base, err = baseline.Load(*baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading baseline: %v\n", err)
+			return 2
+		}
+	}
+
+	files, err := service.CollectFiles(fs.Arg(0), opts.Frontends)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	opts.Root = service.FingerprintRoot(base)
+
+	report := scanReport{Files: files}
+	var all []core.Vulnerability
+	for _, file := range files {
+		result, err := service.AnalyzeWithOptions(file, opts)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		report.Findings = append(report.Findings, result.Vulnerabilities...)
+		report.Suppressed = append(report.Suppressed, result.Suppressed...)
+		all = append(all, result.Vulnerabilities...)
+		all = append(all, result.Suppressed...)
+	}
+
+	if base != nil {
+		split := base.Apply(all, files)
+		report.Findings, report.Suppressed, report.FixedBaseline = split.New, split.Suppressed, split.Fixed
+	}
+
+	if *updateBaseline {
+		base.Update(all, files)
+		if err := base.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving baseline: %v\n", err)
+			return 2
+		}
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printReport(report)
+		if *updateBaseline {
+			fmt.Printf("Baseline %s updated: %d accepted findings\n", base.Path(), base.Len())
+		}
+	}
+
+	if len(report.Findings) > 0 && !*updateBaseline {
+		return 1
+	}
+	return 0
+}
+
+func printReport(r scanReport) {
+	for _, e := range r.Errors {
+		fmt.Printf("⚠️  %s\n", e)
+	}
+	for _, v := range r.Findings {
+		fmt.Printf("[%s] %s %s:%d (%s)\n", v.Severity, v.Type, v.File, v.Line, v.Fingerprint)
+		if v.Source != nil && v.Sink != nil && v.Source != v.Sink {
+			fmt.Printf("    %s -> %s\n", v.Source.Code, v.Sink.Code)
+			if v.Branch != nil {
+				fmt.Printf("    branch: L%d %s\n", v.Branch.Line, v.Branch.Code)
+			}
+			if v.Stored != nil {
+				fmt.Printf("    stored: %s %s, written L%d, read L%d %s\n", v.Stored.Kind, v.Stored.Key, v.Stored.Write.Line, v.Stored.Read.Line, v.Stored.Read.Code)
+			}
+			if len(v.StringParts) > 0 {
+				fmt.Printf("    string: %q\n", core.RenderString(v.StringParts))
+			}
+			if v.QueryContext != "" {
+				fmt.Printf("    query: %s, confidence %s\n", v.QueryContext, v.Confidence)
+			}
+			if len(v.Labels) > 0 {
+				fmt.Printf("    labels: %s\n", strings.Join(v.Labels, ", "))
+			}
+		} else if v.Sink != nil {
+			fmt.Printf("    %s\n", v.Sink.Code)
+		}
+	}
+	for _, v := range r.Suppressed {
+		switch v.Suppression.Kind {
+		case "inline":
+			fmt.Printf("[SUPPRESSED] %s %s:%d (%s)\n    reason: %s\n", v.Type, v.File, v.Line, v.Fingerprint, v.Suppression.Reason)
+		case "guard":
+			fmt.Printf("[GUARDED] %s %s:%d (%s)\n    guard: L%d %s\n", v.Type, v.File, v.Line, v.Fingerprint, v.Guard.Line, v.Guard.Code)
+		}
+	}
+	for _, e := range r.FixedBaseline {
+		fmt.Printf("[FIXED] %s %s:%d (%s)\n", e.Rule, e.File, e.Line, e.Fingerprint)
+	}
+	fmt.Printf("\n%d files, %d new findings", len(r.Files), len(r.Findings))
+	if len(r.Suppressed) > 0 || len(r.FixedBaseline) > 0 {
+		fmt.Printf(", %d suppressed, %d baseline entries fixed", len(r.Suppressed), len(r.FixedBaseline))
+	}
+	fmt.Println()
+}
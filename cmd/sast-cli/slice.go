@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sast-demo/pkg/lang/external"
+	"sast-demo/pkg/service"
+)
+
+// runSlice prints the program slice of a source line
+func runSlice(args []string) int {
+	fs := flag.NewFlagSet("slice", flag.ExitOnError)
+	frontendsPath := fs.String("frontends", "", "JSON file registering external frontends by file extension")
+	line := fs.Int("line", 0, "Source line to slice from (required)")
+	forward := fs.Bool("forward", false, "Slice forward: what the line may influence, rather than what influences it")
+	asJSON := fs.Bool("json", false, "Print the slice as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *line < 1 {
+		fmt.Fprintln(os.Stderr, "slice: a file and -line are required")
+		return 2
+	}
+
+	var opts service.Options
+	if *frontendsPath != "" {
+		cfgs, err := external.LoadConfig(*frontendsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading frontends: %v\n", err)
+			return 2
+		}
+		opts.Frontends = cfgs
+	}
+
+	result, err := service.SliceLine(fs.Arg(0), *line, !*forward, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *asJSON {
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+		return 0
+	}
+
+	// The criterion line is marked with ">>"; skipped lines are shown as one gap
+	fmt.Printf("%s slice of %s:%d\n\n", result.Direction, result.File, result.Line)
+	prev := 0
+	for _, src := range result.Source {
+		if prev > 0 && src.Line > prev+1 {
+			fmt.Println("      ⋮")
+		}
+		mark := "  "
+		if src.Criterion {
+			mark = ">>"
+		}
+		fmt.Printf("%s %4d │ %s\n", mark, src.Line, src.Code)
+		prev = src.Line
+	}
+	fmt.Printf("\n%d lines, %d instructions\n", len(result.Source), len(result.Nodes))
+	return 0
+}
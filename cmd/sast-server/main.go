@@ -1,14 +1,53 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"sast-demo/pkg/baseline"
+	"sast-demo/pkg/lang/external"
 	"sast-demo/pkg/service"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	frontendsPath := flag.String("frontends", "", "JSON file registering external frontends by file extension")
+	pathsPerSink := flag.Int("k", 1, "Number of shortest taint paths to report per source-sink pair")
+	pathBudget := flag.Int("path-budget", 0, "Maximum dataflow nodes processed per taint solve (0 = engine default)")
+	fieldDepth := flag.Int("field-depth", 0, "Fields kept in taint access paths such as cfg.URL (0 = engine default)")
+	contextDepth := flag.Int("context-depth", 0, "Nested calls analyzed through function summaries (0 = engine default)")
+	implicit := flag.Bool("implicit", false, "Also report implicit flows: output decided by a branch on a source")
+	localInput := flag.Bool("local-input-rule", false, "Report command-line input reaching command execution as rce-local (MEDIUM) instead of rce")
+	elements := flag.String("elements", "key", "Container element precision: key (constant indexes and map keys apart) or container (all elements merged)")
+	baselinePath := flag.String("baseline", "", "Baseline file of accepted findings; only new findings are reported")
+	flag.Parse()
+
+	opts := service.Options{PathsPerSink: *pathsPerSink, PathBudget: *pathBudget, AccessPathDepth: *fieldDepth, ContextDepth: *contextDepth, ImplicitFlows: *implicit, LocalInputRule: *localInput, ElementPrecision: *elements}
+	if *baselinePath != "" {
+		b, err := baseline.Load(*baselinePath)
+		if err != nil {
+			fmt.Printf("Error loading baseline: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Baseline = b
+		fmt.Printf("Loaded baseline with %d accepted findings\n", b.Len())
+	}
+	// The same rule as the CLI, so both give a file the same fingerprints
+	opts.Root = service.FingerprintRoot(opts.Baseline)
+	if *frontendsPath != "" {
+		cfgs, err := external.LoadConfig(*frontendsPath)
+		if err != nil {
+			fmt.Printf("Error loading frontends: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Frontends = cfgs
+		fmt.Printf("Registered %d external frontends\n", len(cfgs))
+	}
+
 	r := gin.Default()
 
 	// CORS Middleware
@@ -35,7 +74,21 @@ func main() {
 				return
 			}
 
-			result, err := service.Analyze(file)
+			// ?dataflow=liveness,reaching-definitions attaches dataflow results to the IR
+			scanOpts := opts
+			if names := c.Query("dataflow"); names != "" {
+				scanOpts.Dataflow = strings.Split(names, ",")
+			}
+			// ?implicit=true adds the implicit-flow findings for privacy reviews
+			if v, err := strconv.ParseBool(c.Query("implicit")); err == nil {
+				scanOpts.ImplicitFlows = v
+			}
+			// ?elements=container merges the elements of slices, maps and arrays
+			if v := c.Query("elements"); v != "" {
+				scanOpts.ElementPrecision = v
+			}
+
+			result, err := service.AnalyzeWithOptions(file, scanOpts)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "logs": result.Logs})
 				return
@@ -44,6 +97,82 @@ func main() {
 			c.JSON(http.StatusOK, result)
 		})
 
+		// Regenerate the baseline entries of one file from a fresh scan
+		api.POST("/baseline", func(c *gin.Context) {
+			file := c.Query("file")
+			if file == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "file parameter required"})
+				return
+			}
+			if opts.Baseline == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "server was started without -baseline"})
+				return
+			}
+
+			scanOpts := opts
+			scanOpts.Baseline = nil
+			result, err := service.AnalyzeWithOptions(file, scanOpts)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			opts.Baseline.Update(result.Vulnerabilities, []string{file})
+			if err := opts.Baseline.Save(); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"accepted": len(result.Vulnerabilities), "total": opts.Baseline.Len()})
+		})
+
+		// Call graph of a file or directory; ?format=dot or mermaid renders it as text
+		api.GET("/callgraph", func(c *gin.Context) {
+			file := c.Query("file")
+			if file == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "file parameter required"})
+				return
+			}
+
+			result, err := service.BuildCallGraph(file, opts)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			switch c.DefaultQuery("format", "json") {
+			case "json":
+				c.JSON(http.StatusOK, result)
+			case "dot":
+				c.String(http.StatusOK, result.DOT())
+			case "mermaid":
+				c.String(http.StatusOK, result.Mermaid())
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, dot or mermaid"})
+			}
+		})
+
+		// Program slice of a source line; ?direction=forward for what the line influences
+		api.GET("/slice", func(c *gin.Context) {
+			file := c.Query("file")
+			line, err := strconv.Atoi(c.Query("line"))
+			if file == "" || err != nil || line < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "file and line parameters required"})
+				return
+			}
+			direction := c.DefaultQuery("direction", "backward")
+			if direction != "backward" && direction != "forward" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be backward or forward"})
+				return
+			}
+
+			result, err := service.SliceLine(file, line, direction == "backward", opts)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, result)
+		})
+
 		api.GET("/file", func(c *gin.Context) {
 			path := c.Query("path")
 			if path == "" {
@@ -0,0 +1,156 @@
+#!/usr/bin/env python3
+"""Example external frontend: Python source -> SAST IR (protocol sast-frontend/v1).
+
+Reads one request document from stdin and writes one response document to stdout.
+Only a small subset of Python is lowered (assignments, calls, if/else, return),
+which is enough to run the built-in taint rules on simple scripts.
+"""
+import ast
+import json
+import sys
+
+PROTOCOL = "sast-frontend/v1"
+
+
+class Lowering:
+    def __init__(self):
+        self.functions = {}
+        self.inst_count = 0
+
+    # --- IR helpers ---
+
+    def new_block(self):
+        bid = "B%d" % self.block_count
+        self.block_count += 1
+        block = {"id": bid, "instructions": [], "predecessors": [], "successors": []}
+        self.fn["blocks"][bid] = block
+        return block
+
+    def link(self, src, dst):
+        src["successors"].append(dst["id"])
+        dst["predecessors"].append(src["id"])
+
+    def emit(self, op, result, operands, line, code):
+        inst = {"id": "i%d" % self.inst_count, "op": op, "line": line, "code": code}
+        if result:
+            inst["result"] = result
+        if operands:
+            inst["operands"] = operands
+        self.inst_count += 1
+        self.block["instructions"].append(inst)
+
+    def temp(self):
+        return "t%d" % self.inst_count
+
+    # --- Lowering ---
+
+    def function(self, name, params, body, line):
+        self.fn = {"name": name, "blocks": {}, "entry": "B0"}
+        self.block_count = 0
+        self.block = self.new_block()
+        for p in params:
+            self.emit("PARAM", p, None, line, "param %s" % p)
+        for stmt in body:
+            self.stmt(stmt)
+        self.functions[name] = self.fn
+
+    def stmt(self, s):
+        if isinstance(s, ast.Assign):
+            res = self.expr(s.value)
+            for target in s.targets:
+                name = flat_name(target)
+                self.emit("STORE", name, [res], s.lineno, "%s = %s" % (name, res))
+        elif isinstance(s, ast.Expr):
+            self.expr(s.value)
+        elif isinstance(s, ast.Return):
+            res = self.expr(s.value) if s.value is not None else None
+            self.emit("RET", None, [res] if res else None, s.lineno, "return %s" % (res or ""))
+        elif isinstance(s, ast.If):
+            cond = self.expr(s.test)
+            then_b, else_b, merge = self.new_block(), self.new_block(), self.new_block()
+            self.emit("BRANCH", None, [cond, then_b["id"], else_b["id"]], s.lineno,
+                      "if %s goto %s else %s" % (cond, then_b["id"], else_b["id"]))
+            self.link(self.block, then_b)
+            self.link(self.block, else_b)
+            for branch, body in ((then_b, s.body), (else_b, s.orelse)):
+                self.block = branch
+                for inner in body:
+                    self.stmt(inner)
+                self.emit("JUMP", None, [merge["id"]], s.lineno, "goto %s" % merge["id"])
+                self.link(self.block, merge)
+            self.block = merge
+
+    def expr(self, e):
+        line = getattr(e, "lineno", 0)
+        res = self.temp()
+        if isinstance(e, ast.Constant):
+            self.emit("CONST", res, [repr(e.value)], line, "%s = const %r" % (res, e.value))
+        elif isinstance(e, (ast.Name, ast.Attribute)):
+            name = flat_name(e)
+            self.emit("LOAD", res, [name], line, "%s = load %s" % (res, name))
+        elif isinstance(e, ast.Subscript):
+            container = self.expr(e.value)
+            res = self.temp()
+            self.emit("LOAD", res, [container], line, "%s = load %s" % (res, container))
+        elif isinstance(e, ast.Call):
+            callee = flat_name(e.func)
+            args = [self.expr(a) for a in e.args] + [self.expr(k.value) for k in e.keywords]
+            res = self.temp()
+            self.emit("CALL", res, [callee] + args, line, "%s = call %s(%s)" % (res, callee, args))
+        elif isinstance(e, ast.BinOp):
+            left, right = self.expr(e.left), self.expr(e.right)
+            res = self.temp()
+            self.emit("BINOP", res, [left, type(e.op).__name__, right], line, "%s = %s %s" % (res, left, right))
+        elif isinstance(e, ast.JoinedStr):
+            parts = [self.expr(v.value) for v in e.values if isinstance(v, ast.FormattedValue)]
+            res = self.temp()
+            self.emit("CALL", res, ["format"] + parts, line, "%s = call format(%s)" % (res, parts))
+        else:
+            self.emit("CONST", res, ["?"], line, "%s = const ?" % res)
+        return res
+
+
+def flat_name(e):
+    if isinstance(e, ast.Name):
+        return e.id
+    if isinstance(e, ast.Attribute):
+        return flat_name(e.value) + "." + e.attr
+    return "expr"
+
+
+def ast_tree(node, key="0"):
+    title = type(node).__name__
+    if isinstance(node, ast.Name):
+        title += " (Name: %s)" % node.id
+    elif isinstance(node, (ast.FunctionDef, ast.ClassDef)):
+        title += " (Name: %s)" % node.name
+    children = [ast_tree(c, "%s-%d" % (key, i)) for i, c in enumerate(ast.iter_child_nodes(node))]
+    return {"key": key, "title": title, "line": getattr(node, "lineno", 0), "children": children}
+
+
+def main():
+    req = json.load(sys.stdin)
+    if req.get("protocol") != PROTOCOL:
+        json.dump({"protocol": PROTOCOL, "error": "unsupported protocol %r" % req.get("protocol")}, sys.stdout)
+        return
+    try:
+        tree = ast.parse(req["content"], filename=req["file"])
+    except SyntaxError as err:
+        json.dump({"protocol": PROTOCOL, "error": str(err)}, sys.stdout)
+        return
+
+    low = Lowering()
+    top = [s for s in tree.body if not isinstance(s, ast.FunctionDef)]
+    for s in tree.body:
+        if isinstance(s, ast.FunctionDef):
+            low.function(s.name, [a.arg for a in s.args.args], s.body, s.lineno)
+    if top:
+        low.function("<module>", [], top, 1)
+
+    root = {"key": "root", "title": "File: " + req["file"], "line": 1, "children": [ast_tree(tree)]}
+    json.dump({"protocol": PROTOCOL, "ir": {"functions": low.functions}, "ast": root,
+               "logs": ["python frontend: %d functions" % len(low.functions)]}, sys.stdout)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,17 @@
+import os
+
+import requests
+from flask import request
+
+
+def ssrf():
+    # Source
+    target = request.args.get("url")
+    # Sink
+    requests.get(target)
+
+
+def rce():
+    name = request.args.get("name")
+    cmd = "ls " + name
+    os.system(cmd)
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"net/http"
+	"os/exec"
+)
+
+// Structural rule examples: none of these need a tainted source to be reported
+
+func insecureClient() *http.Client {
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: true},
+	}
+	return &http.Client{Transport: tr}
+}
+
+func checksum(data []byte) [16]byte {
+	return md5.Sum(data)
+}
+
+// A vetted finding stays in the report as suppressed, with its justification
+func etag(body []byte) [16]byte {
+	// sast:ignore weak-hash reason: ETag cache key, not a security boundary
+	return md5.Sum(body)
+}
+
+func runScript(ctx context.Context, script string) error {
+	return exec.CommandContext(ctx, "sh", "-c", script).Run()
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"net/url"
 )
 
 func complexHandler(w http.ResponseWriter, r *http.Request) {
@@ -14,3 +15,48 @@ func complexHandler(w http.ResponseWriter, r *http.Request) {
 		http.Get(target)
 	}
 }
+
+func validatedHandler(w http.ResponseWriter, r *http.Request) {
+	// Guarded sink: only reached when the validator accepts the value
+	next := r.URL.Query().Get("next")
+	if !isValidURL(next) {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return
+	}
+	http.Get(next)
+}
+
+type upstream struct {
+	URL  string
+	Name string
+}
+
+func fieldHandler(w http.ResponseWriter, r *http.Request) {
+	// Field-sensitive: only the URL field is tainted, and copies keep it tainted
+	up := upstream{URL: r.URL.Query().Get("upstream"), Name: "billing"}
+	backup := up
+	http.Get(backup.URL)
+	http.Get("https://" + up.Name + ".internal/health")
+}
+
+func aliasHandler(w http.ResponseWriter, r *http.Request) {
+	// Aliasing: the store through the pointer updates the field of the struct it points to
+	var mirror upstream
+	p := &mirror
+	p.URL = r.URL.Query().Get("mirror")
+	http.Get(mirror.URL)
+}
+
+func debugHandler(w http.ResponseWriter, r *http.Request) {
+	// Dead branch: constant propagation proves the sink never runs
+	probe := r.URL.Query().Get("probe")
+	debug := false
+	if debug {
+		http.Get(probe)
+	}
+}
+
+func isValidURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Host == "api.example.com"
+}
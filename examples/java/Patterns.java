@@ -0,0 +1,29 @@
+import java.security.MessageDigest;
+import org.apache.http.conn.ssl.NoopHostnameVerifier;
+import org.apache.http.impl.client.HttpClients;
+
+// Structural rule examples: none of these need a tainted source to be reported
+public class Patterns {
+
+    public byte[] checksum(byte[] data) throws Exception {
+        MessageDigest md = MessageDigest.getInstance("MD5");
+        return md.digest(data);
+    }
+
+    // A vetted finding stays in the report as suppressed, with its justification
+    public byte[] etag(byte[] body) throws Exception {
+        MessageDigest md = MessageDigest.getInstance("MD5"); // sast:ignore weak-hash reason: ETag cache key, not a security boundary
+        return md.digest(body);
+    }
+
+    public void runScript(String script) throws Exception {
+        new ProcessBuilder("sh", "-c", script).start();
+        Runtime.getRuntime().exec(new String[]{"bash", "-c", script});
+    }
+
+    public Object insecureClient() {
+        return HttpClients.custom()
+                .setSSLHostnameVerifier(new NoopHostnameVerifier())
+                .build();
+    }
+}
@@ -0,0 +1,214 @@
+// Package baseline records accepted findings so that scans only report new ones.
+//
+// A baseline file lists the fingerprints of findings that were present when it
+// was generated, with their files. Findings whose file and fingerprint are listed
+// are suppressed; entries for scanned files that no longer match any finding are
+// reported as fixed.
+// File paths are stored relative to the baseline file so it can be committed.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sast-demo/pkg/core"
+	"sort"
+	"sync"
+)
+
+// FormatVersion is written to every baseline file
+const FormatVersion = 1
+
+// Entry is one accepted finding. File and Fingerprint are used for matching;
+// the other fields help reviewers read the file.
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Rule        string `json:"rule"`
+	File        string `json:"file"` // Relative to the baseline file
+	Line        int    `json:"line"`
+	Code        string `json:"code,omitempty"`
+}
+
+// Baseline is safe for concurrent use
+type Baseline struct {
+	mu      sync.RWMutex
+	path    string
+	entries []Entry
+}
+
+type fileFormat struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Result splits a scan according to the baseline
+type Result struct {
+	New        []core.Vulnerability // Not in the baseline
+	Suppressed []core.Vulnerability // Accepted by the baseline
+	Fixed      []Entry              // Baseline entries for scanned files that no longer occur
+}
+
+// New returns an empty baseline that will be saved at path
+func New(path string) *Baseline {
+	return &Baseline{path: path}
+}
+
+// Load reads a baseline file. A missing file yields an empty baseline, so the
+// first scan with --baseline can create it.
+func Load(path string) (*Baseline, error) {
+	b := New(path)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ff fileFormat
+	if err := json.Unmarshal(data, &ff); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if ff.Version != FormatVersion {
+		return nil, fmt.Errorf("%s: unsupported baseline version %d", path, ff.Version)
+	}
+	b.entries = ff.Entries
+	return b, nil
+}
+
+// Path is where the baseline is read from and saved to
+func (b *Baseline) Path() string {
+	return b.path
+}
+
+// Len returns the number of accepted findings
+func (b *Baseline) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.entries)
+}
+
+// Save writes the baseline with entries in a stable order
+func (b *Baseline) Save() error {
+	b.mu.RLock()
+	entries := append([]Entry(nil), b.entries...)
+	b.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].File != entries[j].File {
+			return entries[i].File < entries[j].File
+		}
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].Fingerprint < entries[j].Fingerprint
+	})
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err := json.MarshalIndent(fileFormat{Version: FormatVersion, Entries: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.path, append(data, '\n'), 0644)
+}
+
+// entryKey identifies a finding: its file relative to the baseline, and its fingerprint
+type entryKey struct {
+	file        string
+	fingerprint string
+}
+
+// Apply splits the findings of a scan covering scannedFiles
+func (b *Baseline) Apply(vulns []core.Vulnerability, scannedFiles []string) Result {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Fingerprints only tell findings apart within a file
+	accepted := make(map[entryKey]bool, len(b.entries))
+	for _, e := range b.entries {
+		accepted[entryKey{e.File, e.Fingerprint}] = true
+	}
+
+	var res Result
+	present := make(map[entryKey]bool, len(vulns))
+	for _, v := range vulns {
+		key := entryKey{b.relative(v.File), v.Fingerprint}
+		present[key] = true
+		switch {
+		case v.Suppression != nil: // Already suppressed inline
+			res.Suppressed = append(res.Suppressed, v)
+		case accepted[key]:
+			v.Suppression = &core.SuppressionInfo{Kind: "baseline"}
+			res.Suppressed = append(res.Suppressed, v)
+		default:
+			res.New = append(res.New, v)
+		}
+	}
+
+	// Entries for files outside this scan are neither fixed nor still present
+	scanned := b.fileSet(scannedFiles)
+	for _, e := range b.entries {
+		if scanned[e.File] && !present[entryKey{e.File, e.Fingerprint}] {
+			res.Fixed = append(res.Fixed, e)
+		}
+	}
+	return res
+}
+
+// Update replaces the entries for scannedFiles with the given findings,
+// keeping entries for files that were not part of the scan
+func (b *Baseline) Update(vulns []core.Vulnerability, scannedFiles []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	scanned := b.fileSet(scannedFiles)
+	var kept []Entry
+	for _, e := range b.entries {
+		if !scanned[e.File] {
+			kept = append(kept, e)
+		}
+	}
+	for _, v := range vulns {
+		// Inline and guard suppressions are justified by the source itself
+		if v.Suppression != nil && v.Suppression.Kind != "baseline" {
+			continue
+		}
+		e := Entry{
+			Fingerprint: v.Fingerprint,
+			Rule:        v.Type,
+			File:        b.relative(v.File),
+			Line:        v.Line,
+		}
+		if v.Sink != nil {
+			e.Code = v.Sink.Code
+		}
+		kept = append(kept, e)
+	}
+	b.entries = kept
+}
+
+func (b *Baseline) fileSet(files []string) map[string]bool {
+	set := make(map[string]bool, len(files))
+	for _, f := range files {
+		set[b.relative(f)] = true
+	}
+	return set
+}
+
+// relative converts a scanned path into the form stored in the baseline
+func (b *Baseline) relative(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	dir, err := filepath.Abs(filepath.Dir(b.path))
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,81 @@
+package baseline
+
+import (
+	"path/filepath"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+func vuln(file, fingerprint string) core.Vulnerability {
+	return core.Vulnerability{Type: "SQL Injection", File: file, Line: 1, Fingerprint: fingerprint}
+}
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a", "h.go"), filepath.Join(dir, "b", "h.go")
+
+	base := New(filepath.Join(dir, "baseline.json"))
+	base.Update([]core.Vulnerability{vuln(a, "f1"), vuln(a, "f2")}, []string{a})
+
+	tests := []struct {
+		name       string
+		vulns      []core.Vulnerability
+		scanned    []string
+		new, fixed int
+	}{
+		{"unchanged", []core.Vulnerability{vuln(a, "f1"), vuln(a, "f2")}, []string{a}, 0, 0},
+		{"one fixed", []core.Vulnerability{vuln(a, "f1")}, []string{a}, 0, 1},
+		{"new finding", []core.Vulnerability{vuln(a, "f1"), vuln(a, "f2"), vuln(a, "f3")}, []string{a}, 1, 0},
+		// The same fingerprint in another file is another finding
+		{"copied file", []core.Vulnerability{vuln(a, "f1"), vuln(a, "f2"), vuln(b, "f1"), vuln(b, "f2")}, []string{a, b}, 2, 0},
+		{"moved file", []core.Vulnerability{vuln(b, "f1"), vuln(b, "f2")}, []string{a, b}, 2, 2},
+		// Entries of files outside the scan are neither present nor fixed
+		{"other file scanned", []core.Vulnerability{vuln(b, "f9")}, []string{b}, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := base.Apply(tt.vulns, tt.scanned)
+			if len(res.New) != tt.new || len(res.Fixed) != tt.fixed {
+				t.Errorf("new %d, fixed %d; want %d, %d", len(res.New), len(res.Fixed), tt.new, tt.fixed)
+			}
+			if got := len(res.New) + len(res.Suppressed); got != len(tt.vulns) {
+				t.Errorf("%d findings split into %d", len(tt.vulns), got)
+			}
+		})
+	}
+}
+
+func TestUpdateKeepsUnscannedFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	base := New(filepath.Join(dir, "baseline.json"))
+	base.Update([]core.Vulnerability{vuln(a, "f1"), vuln(b, "f2")}, []string{a, b})
+	base.Update(nil, []string{a})
+	if base.Len() != 1 || base.entries[0].File != "b.go" {
+		t.Errorf("entries after rescanning a.go clean: %+v", base.entries)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "src", "a.go")
+	base := New(filepath.Join(dir, "baseline.json"))
+	inline := vuln(a, "f2")
+	inline.Suppression = &core.SuppressionInfo{Kind: "inline"}
+	base.Update([]core.Vulnerability{vuln(a, "f1"), inline}, []string{a})
+	if err := base.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(base.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Inline suppressions are justified in the source and not copied into the baseline
+	if loaded.Len() != 1 || loaded.entries[0].File != "src/a.go" || loaded.entries[0].Fingerprint != "f1" {
+		t.Errorf("loaded entries %+v", loaded.entries)
+	}
+	if missing, err := Load(filepath.Join(dir, "none.json")); err != nil || missing.Len() != 0 {
+		t.Errorf("missing baseline: %v, %d entries", err, missing.Len())
+	}
+}
@@ -1,6 +1,11 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 // --- IR Definitions ---
 
@@ -9,7 +14,7 @@ type OpCode string
 const (
 	OpLoad   OpCode = "LOAD"   // x = y
 	OpStore  OpCode = "STORE"  // *x = y
-	OpCall   OpCode = "CALL"   // x = f(...)
+	OpCall   OpCode = "CALL"   // x = f(...); Operands[0] is the callee, Operands[1:] the arguments
 	OpBinOp  OpCode = "BINOP"  // x = y + z
 	OpRet    OpCode = "RET"    // return x
 	OpParam  OpCode = "PARAM"  // parameter definition
@@ -19,12 +24,34 @@ const (
 	OpJump   OpCode = "JUMP"   // goto L1
 )
 
+// ChanField is the field holding the elements of a channel: `ch <- v` stores
+// into ch.<- and `<-ch` loads from it
+const ChanField = "<-"
+
+// ElemField is the field holding the elements of a slice, array or map. An
+// element at a constant index or key is a field below it: `s[0]` is s.[].[0] and
+// `m["id"]` is m.[].[id], while `s[i]` is s.[] itself, so writing at an unknown
+// index reaches every read and writing at a known one reaches reads of that index
+// and reads at unknown indexes.
+const ElemField = "[]"
+
+// ElemPath is the path of an element below its container: .[] for an unknown
+// index or key (""), .[].[key] for a constant one. Keys that cannot be part of an
+// access path count as unknown.
+func ElemPath(key string) string {
+	if key == "" || strings.ContainsAny(key, ".*[]\"'` \t\n") {
+		return "." + ElemField
+	}
+	return "." + ElemField + ".[" + key + "]"
+}
+
 // Instruction represents a single operation in our IR
 type Instruction struct {
 	ID       string   `json:"id"`
 	Op       OpCode   `json:"op"`
 	Result   string   `json:"result,omitempty"`   // Variable name being assigned to
 	Operands []string `json:"operands,omitempty"` // Arguments / Source variables
+	Receiver string   `json:"receiver,omitempty"` // Receiver value of a method call, if known
 	Line     int      `json:"line"`
 	Code     string   `json:"code"` // Human readable string
 }
@@ -41,6 +68,10 @@ type BasicBlock struct {
 	Instructions []*Instruction `json:"instructions"`
 	Predecessors []string       `json:"predecessors"` // Block IDs
 	Successors   []string       `json:"successors"`   // Block IDs
+	// Set by constant propagation: the block can never run, or these successor
+	// edges are never taken because their branch condition is a known constant
+	Unreachable    bool     `json:"unreachable,omitempty"`
+	DeadSuccessors []string `json:"dead_successors,omitempty"`
 }
 
 // FunctionIR holds the CFG and instructions for a single function
@@ -48,6 +79,21 @@ type FunctionIR struct {
 	Name   string                 `json:"name"`
 	Blocks map[string]*BasicBlock `json:"blocks"` // Map BlockID -> Block
 	Entry  string                 `json:"entry"`  // Entry Block ID
+	// Receiver is the name a method's receiver is bound to ("this" in Java), "" for plain functions
+	Receiver string `json:"receiver,omitempty"`
+}
+
+// Params returns the parameter names in declaration order, without the receiver
+func (fn *FunctionIR) Params() []string {
+	var params []string
+	if entry := fn.Blocks[fn.Entry]; entry != nil {
+		for _, inst := range entry.Instructions {
+			if inst.Op == OpParam && inst.Result != fn.Receiver {
+				params = append(params, inst.Result)
+			}
+		}
+	}
+	return params
 }
 
 // ProgramIR holds the IR for the entire file
@@ -60,3 +106,102 @@ func NewProgramIR() *ProgramIR {
 		Functions: make(map[string]*FunctionIR),
 	}
 }
+
+// FunctionNames returns the function names in sorted order, for deterministic traversal
+func (p *ProgramIR) FunctionNames() []string {
+	names := make([]string, 0, len(p.Functions))
+	for name := range p.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BlockIDs returns the entry block first, then the others in natural order (B2 before B10)
+func (fn *FunctionIR) BlockIDs() []string {
+	ids := make([]string, 0, len(fn.Blocks))
+	for id := range fn.Blocks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if (ids[i] == fn.Entry) != (ids[j] == fn.Entry) {
+			return ids[i] == fn.Entry
+		}
+		pi, ni := splitBlockID(ids[i])
+		pj, nj := splitBlockID(ids[j])
+		if pi != pj {
+			return pi < pj
+		}
+		if ni != nj {
+			return ni < nj
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// splitBlockID separates a trailing number from its prefix ("B12" -> "B", 12)
+func splitBlockID(id string) (string, int) {
+	prefix := strings.TrimRight(id, "0123456789")
+	n, err := strconv.Atoi(id[len(prefix):])
+	if err != nil {
+		return id, -1
+	}
+	return prefix, n
+}
+
+// Dominators maps each block to the set of blocks that dominate it: every path
+// from the entry to the block passes through them. A block dominates itself.
+func (fn *FunctionIR) Dominators() map[string]map[string]bool {
+	ids := fn.BlockIDs()
+	dom := make(map[string]map[string]bool, len(ids))
+	for _, id := range ids {
+		if id == fn.Entry {
+			dom[id] = map[string]bool{id: true}
+			continue
+		}
+		all := make(map[string]bool, len(ids))
+		for _, other := range ids {
+			all[other] = true
+		}
+		dom[id] = all
+	}
+
+	// Iterate dom(b) = {b} ∪ ⋂ dom(p) over the predecessors p until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, id := range ids {
+			if id == fn.Entry {
+				continue
+			}
+			var next map[string]bool
+			for _, p := range fn.Blocks[id].Predecessors {
+				pd, ok := dom[p]
+				if !ok {
+					continue
+				}
+				if next == nil {
+					next = make(map[string]bool, len(pd)+1)
+					for b := range pd {
+						next[b] = true
+					}
+					continue
+				}
+				for b := range next {
+					if !pd[b] {
+						delete(next, b)
+					}
+				}
+			}
+			if next == nil {
+				continue // Unreachable: keep "dominated by everything"
+			}
+			next[id] = true
+			if len(next) != len(dom[id]) {
+				dom[id] = next
+				changed = true
+			}
+		}
+	}
+	return dom
+}
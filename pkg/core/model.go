@@ -1,6 +1,9 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // NodeType represents the type of code element
 type NodeType string
@@ -66,6 +69,8 @@ func (g *Graph) AddEdge(from, to *Node, edgeType EdgeType) {
 // Vulnerability represents a detected security issue
 type Vulnerability struct {
 	Type        string
+	RuleID      string
+	Fingerprint string // Stable identity across runs and line shifts (see engine.AssignFingerprints)
 	Severity    string
 	File        string
 	Line        int
@@ -73,8 +78,84 @@ type Vulnerability struct {
 	Source      *Node
 	Sink        *Node
 	Path        []*Node // The propagation path
+	// AlternatePaths are further distinct paths between the same source and sink, shortest first
+	AlternatePaths [][]*Node `json:",omitempty"`
+	// Suppression is set when the finding was accepted instead of reported as new
+	Suppression *SuppressionInfo `json:",omitempty"`
+	// Guard is the validator call that makes the sink safe, for findings suppressed by a guard
+	Guard *Node `json:",omitempty"`
+	// Branch is the condition on the source that decides the sink, for implicit-flow findings
+	Branch *Node `json:",omitempty"`
+	// Labels are the kinds of the sources whose taint reaches the sink ("http-input", "env" ...)
+	Labels []string `json:",omitempty"`
+	// Stored is set for second-order findings: Path runs from the source to the
+	// write into a store, then from a read of the same key to the sink
+	Stored *StoredFlow `json:",omitempty"`
+	// StringParts is the string reaching the sink as built from constants,
+	// concatenation and formatting, when it has constant parts or formatted values
+	StringParts []StringPart `json:",omitempty"`
+	// QueryContext is where the taint lies in the query reaching the sink, for rules
+	// that parse their queries: "quoted", "numeric", "identifier", "order-by",
+	// "fragment" or "placeholder"
+	QueryContext string `json:",omitempty"`
+	// Confidence is how likely the finding is exploitable ("high", "medium",
+	// "low"), set along with QueryContext
+	Confidence string `json:",omitempty"`
+}
+
+// StringPart is a piece of a string built by concatenation or formatting: constant
+// text, or a value not known statically
+type StringPart struct {
+	Text    string // The constant text; empty for values
+	Value   bool   // Whether the part is a value rather than text
+	Verb    string `json:",omitempty"` // The format verb printing the value ("%d"), "" when concatenated
+	Tainted bool   `json:",omitempty"` // Whether the value is on the taint path
+}
+
+// RenderString writes parts as one string, values shown as {%d} or {?} and
+// tainted ones as {tainted %d}
+func RenderString(parts []StringPart) string {
+	var b strings.Builder
+	for _, p := range parts {
+		if !p.Value {
+			b.WriteString(p.Text)
+			continue
+		}
+		verb := p.Verb
+		if verb == "" {
+			verb = "?"
+		}
+		if p.Tainted {
+			verb = "tainted " + verb
+		}
+		b.WriteString("{" + verb + "}")
+	}
+	return b.String()
+}
+
+// StoredFlow is the store a second-order finding passes through
+type StoredFlow struct {
+	Kind  string // "sql", "cache" or "file"
+	Key   string // Table, cache key or file; empty when it could not be resolved
+	Write *Node
+	Read  *Node
+}
+
+// SuppressionInfo explains why a finding is suppressed
+type SuppressionInfo struct {
+	Kind   string // "inline", "baseline" or "guard"
+	Reason string // Justification from the comment, or the validation for guards
+	Line   int    // Line of the suppression comment or guard
 }
 
 func (v Vulnerability) String() string {
 	return fmt.Sprintf("[%s] %s in %s:%d\n  Path: %s -> ... -> %s", v.Severity, v.Type, v.File, v.Line, v.Source.Code, v.Sink.Code)
 }
+
+// PatternMatch is one place where a structural rule pattern matched the syntax tree
+type PatternMatch struct {
+	Line     int
+	Code     string            // Matched source text
+	Function string            // Enclosing function or method
+	Bindings map[string]string // Metavariable ($X) -> matched source text
+}
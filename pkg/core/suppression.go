@@ -0,0 +1,46 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Suppression is a `sast:ignore` comment found by a frontend
+type Suppression struct {
+	Line        int      // Code line the suppression applies to
+	CommentLine int      // Line of the comment itself
+	Rules       []string // Rule IDs, or "*" for every rule
+	Reason      string   // Required justification; suppressions without one are not applied
+}
+
+// sast:ignore <rule-id>[,<rule-id>...] reason: <text>
+var suppressionRegex = regexp.MustCompile(`^sast:ignore\s+([\w*.,-]+)(?:\s+reason:\s*(.*))?$`)
+
+// ParseSuppression interprets the text of a line comment (without the // marker).
+// A trailing comment applies to its own line, a comment on a line of its own to the next line.
+func ParseSuppression(text string, line int, trailing bool) (Suppression, bool) {
+	m := suppressionRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return Suppression{}, false
+	}
+	s := Suppression{
+		Line:        line,
+		CommentLine: line,
+		Rules:       strings.Split(m[1], ","),
+		Reason:      strings.TrimSpace(m[2]),
+	}
+	if !trailing {
+		s.Line = line + 1
+	}
+	return s, true
+}
+
+// Covers reports whether the suppression names the rule
+func (s Suppression) Covers(ruleID string) bool {
+	for _, r := range s.Rules {
+		if r == "*" || r == ruleID {
+			return true
+		}
+	}
+	return false
+}
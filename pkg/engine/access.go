@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"sast-demo/pkg/core"
+	"strings"
+)
+
+// Field-sensitive taint facts.
+//
+// A taint fact is an access path: a variable or value followed by field names
+// (`cfg.URL`, `t5.Header.Host`). Storing into `cfg.URL` taints that field only;
+// reading `cfg` as a whole reads the tainted field along with it, so the fact
+// moves to the result with its field suffix (`c2 := cfg` taints `c2.URL`), and
+// reading a field below a tainted path (`cfg.URL.Host`) is tainted as a whole.
+// Paths longer than the configured depth are truncated, which conservatively
+// stands for every path below the cut. With points-to information, paths are also
+// indexed by the objects they reach, so a store through one alias (`p.Name`) taints
+// loads through another (`s.Name`). The elements of a channel are the field `<-`
+// of it; sends and receives on a channel field of method receivers also meet by
+// the receiver type and the field's name.
+//
+// Variable names are only meaningful inside their function and the closures
+// nested in it (see scope.go): a use in another function is followed only when
+// the name there is the same variable, or a variable of no function, as with a
+// package-level variable. Taint crosses calls through the parameters and returns
+// of the IFDS solver instead (see taint.go).
+
+// truncatePath keeps the root and at most depth fields of an access path
+func truncatePath(p string, depth int) string {
+	if depth < 1 {
+		depth = DefaultAccessPathDepth
+	}
+	i := -1
+	for n := 0; n <= depth; n++ {
+		j := strings.IndexByte(p[i+1:], '.')
+		if j < 0 {
+			return p
+		}
+		i += j + 1
+	}
+	return p[:i]
+}
+
+// taintStep is an instruction reached from a taint fact
+type taintStep struct {
+	inst  *core.Instruction
+	value string // The operand carrying the taint into inst
+	fact  string // The access path tainted by inst, "" if it defines nothing
+	key   string // The fact or alias key inst was found through
+}
+
+// useIndex finds the instructions affected by a tainted access path
+type useIndex struct {
+	depth  int
+	pt     *PointsTo                      // Alias information; may be nil
+	uses   map[string][]*core.Instruction // Operand or alias key -> instructions using it
+	below  map[string][]*core.Instruction // Access path or alias key -> loads of fields strictly below it
+	funcOf map[string]string              // Instruction ID -> function
+	scopes *scopes
+}
+
+func newUseIndex(depth int, pt *PointsTo, sc *scopes) *useIndex {
+	return &useIndex{
+		depth:  depth,
+		pt:     pt,
+		uses:   make(map[string][]*core.Instruction),
+		below:  make(map[string][]*core.Instruction),
+		funcOf: make(map[string]string),
+		scopes: sc,
+	}
+}
+
+func (ui *useIndex) add(fn string, inst *core.Instruction) {
+	ui.funcOf[inst.ID] = fn
+	for i, op := range inst.Operands {
+		// The callee of a call is a name, not a value read
+		if inst.Op == core.OpCall && i == 0 {
+			ui.uses[op] = append(ui.uses[op], inst)
+			continue
+		}
+		op = truncatePath(op, ui.depth)
+		ui.uses[op] = append(ui.uses[op], inst)
+		if inst.Op != core.OpLoad || strings.ContainsAny(op, `"'`) {
+			continue
+		}
+		ui.indexBelow(op, inst)
+		for _, key := range ui.aliasKeys(fn, op) {
+			ui.uses[key] = append(ui.uses[key], inst)
+			ui.indexBelow(key, inst)
+		}
+	}
+	if inst.Receiver != "" {
+		ui.uses[inst.Receiver] = append(ui.uses[inst.Receiver], inst)
+	}
+}
+
+func (ui *useIndex) indexBelow(p string, inst *core.Instruction) {
+	for j := strings.LastIndexByte(p, '.'); j > 0; j = strings.LastIndexByte(p[:j], '.') {
+		ui.below[p[:j]] = append(ui.below[p[:j]], inst)
+	}
+}
+
+func (ui *useIndex) aliasKeys(fn, p string) []string {
+	// An alias key already names an object
+	if strings.HasPrefix(p, "@") {
+		return nil
+	}
+	var keys []string
+	if ui.pt != nil {
+		keys = ui.pt.aliasKeys(fn, p)
+	}
+	if key := ui.chanKey(fn, p); key != "" {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// chanKey names the channel field of a method receiver whose elements p is by
+// the receiver's type (`s.jobs.<-` in a method of Server -> "@chan.Server.jobs"),
+// so that sends and receives in two methods meet even when no points-to set links
+// their receivers. Channels reached from other variables have no such key.
+func (ui *useIndex) chanKey(fn, p string) string {
+	_, root, fields := splitPath(p)
+	rest, ok := strings.CutSuffix(fields, "."+core.ChanField)
+	if !ok || rest == "" {
+		return ""
+	}
+	method := ui.scopes.owner(fn, root)
+	if method == "" || ui.scopes.receivers[method] != root || !strings.Contains(method, ".") {
+		return ""
+	}
+	return "@chan." + method[:strings.LastIndexByte(method, '.')] + rest
+}
+
+// steps lists where taint on fact, an access path of function fn, flows: to users
+// of the path or of an enclosing object, and to loads of fields below it, reached
+// through the path itself or through an alias of it
+func (ui *useIndex) steps(fn, fact string) []taintStep {
+	var steps []taintStep
+	type reach struct {
+		inst *core.Instruction
+		fact string
+	}
+	seen := make(map[reach]bool)
+	owner := ui.scopes.owner(fn, fact)
+	for i, key := range append([]string{fact}, ui.aliasKeys(fn, fact)...) {
+		// Alias keys name objects, which every function may reach
+		visible := func(inst *core.Instruction) bool {
+			g := ui.funcOf[inst.ID]
+			if i > 0 || g == fn {
+				return true
+			}
+			o := ui.scopes.owner(g, fact)
+			return o == "" || o == owner
+		}
+		for p := key; ; {
+			rest := key[len(p):]
+			for _, inst := range ui.uses[p] {
+				if !visible(inst) {
+					continue
+				}
+				step := taintStep{inst: inst, value: p, fact: ui.resultFact(inst, rest), key: key}
+				if r := (reach{inst, step.fact}); !seen[r] {
+					seen[r] = true
+					steps = append(steps, step)
+				}
+			}
+			i := strings.LastIndexByte(p, '.')
+			if i <= 0 {
+				break
+			}
+			p = p[:i]
+		}
+		for _, inst := range ui.below[key] {
+			if !visible(inst) {
+				continue
+			}
+			step := taintStep{inst: inst, value: key, fact: inst.Result, key: key}
+			if r := (reach{inst, step.fact}); !seen[r] {
+				seen[r] = true
+				steps = append(steps, step)
+			}
+		}
+	}
+	return steps
+}
+
+// pathRoot is the variable an access path starts from (`p` for `*p.Name`)
+func pathRoot(p string) string {
+	p = strings.TrimPrefix(p, "*")
+	if i := strings.IndexByte(p, '.'); i >= 0 {
+		return p[:i]
+	}
+	return p
+}
+
+// resultFact is the path tainted by inst when it reads a value whose fields rest
+// are tainted. Copies keep the field suffix; anything computed from the value
+// (calls, arithmetic) is tainted as a whole.
+func (ui *useIndex) resultFact(inst *core.Instruction, rest string) string {
+	if inst.Result == "" {
+		return ""
+	}
+	switch inst.Op {
+	case core.OpStore, core.OpLoad, core.OpPhi:
+		return truncatePath(inst.Result+rest, ui.depth)
+	case core.OpBinOp:
+		// &x and *x copy the reference
+		if len(inst.Operands) == 2 && (inst.Operands[0] == "&" || inst.Operands[0] == "*") {
+			return truncatePath(inst.Result+rest, ui.depth)
+		}
+	}
+	return inst.Result
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTruncatePath(t *testing.T) {
+	tests := []struct {
+		path  string
+		depth int
+		want  string
+	}{
+		{"cfg", 2, "cfg"},
+		{"cfg.URL", 2, "cfg.URL"},
+		{"cfg.URL.Host", 2, "cfg.URL.Host"},
+		{"cfg.URL.Host.Name", 2, "cfg.URL.Host"},
+		{"cfg.URL.Host", 1, "cfg.URL"},
+		{"a.b.c.d.e", 0, "a.b.c.d"}, // DefaultAccessPathDepth
+	}
+	for _, tt := range tests {
+		if got := truncatePath(tt.path, tt.depth); got != tt.want {
+			t.Errorf("truncatePath(%q, %d) = %q, want %q", tt.path, tt.depth, got, tt.want)
+		}
+	}
+	for p, want := range map[string]string{"p": "p", "*p.Name": "p", "t5.Header.Host": "t5"} {
+		if got := pathRoot(p); got != want {
+			t.Errorf("pathRoot(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+const fieldSource = `package main
+
+import (
+	"net/http"
+)
+
+type Config struct {
+	URL  string
+	Name string
+	Next *Config
+}
+
+func store(r *http.Request) {
+	var cfg Config
+	cfg.URL = r.URL.Query().Get("url")
+	http.Get(cfg.URL)
+}
+
+func otherField(r *http.Request) {
+	var cfg Config
+	cfg.Name = r.URL.Query().Get("name")
+	http.Get(cfg.URL)
+}
+
+func structCopy(r *http.Request) {
+	var cfg Config
+	cfg.URL = r.URL.Query().Get("url")
+	c2 := cfg
+	http.Get(c2.URL)
+}
+
+func deep(r *http.Request) {
+	var cfg Config
+	cfg.Next.Next.Name = r.URL.Query().Get("name")
+	http.Get(cfg.Next.Next.URL)
+}
+`
+
+// Taint stays on the stored field and moves with copies of the struct
+func TestFieldSensitivity(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{3, []string{"ssrf@15->16", "ssrf@27->29"}},
+		// At depth 1, cfg.Next.Next.Name is cut to cfg.Next, which stands for
+		// every field below it, so the deep load is tainted too
+		{1, []string{"ssrf@15->16", "ssrf@27->29", "ssrf@34->35"}},
+	}
+	for _, tt := range tests {
+		vulns := scan(t, "main.go", fieldSource, func(c *Config) { c.AccessPathDepth = tt.depth })
+		if got := findings(vulns); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("depth %d: findings %v, want %v", tt.depth, got, tt.want)
+		}
+	}
+}
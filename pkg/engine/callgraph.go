@@ -0,0 +1,320 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sast-demo/pkg/core"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CallGraph links the functions of a program through their OpCall instructions.
+// Library functions are left out unless they are sinks, so the graph shows which
+// entrypoints can reach dangerous calls.
+type CallGraph struct {
+	Nodes []*CallGraphNode `json:"nodes"`
+	Edges []*CallGraphEdge `json:"edges"`
+}
+
+type CallGraphNode struct {
+	ID   string `json:"id"`             // Function name, or the callee text of a library sink
+	File string `json:"file,omitempty"` // Set by callers that merge several files
+	// External nodes are library sinks; Rules lists the IDs of the rules they are a sink of
+	External bool     `json:"external,omitempty"`
+	Sink     bool     `json:"sink,omitempty"`
+	Rules    []string `json:"rules,omitempty"`
+	// Entrypoint is set for main/init and functions nothing in the program calls
+	Entrypoint bool `json:"entrypoint,omitempty"`
+	// ReachesSink is set for functions that call a sink directly or through other functions
+	ReachesSink bool `json:"reaches_sink,omitempty"`
+}
+
+type CallGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Line int    `json:"line"` // First call site
+	// Kind is how the callee was resolved: "name", "points-to" (a function value or the
+	// type of the receiver's object) or "hierarchy" (every method of that name)
+	Kind string `json:"kind"`
+}
+
+// BuildCallGraph resolves every reachable call of prog. Calls are resolved by name
+// and points-to information first; a method call that resolves to nothing falls
+// back to the methods of that name on any type, like class hierarchy analysis
+// without the hierarchy, unless its receiver is returned by a library call
+// (`exec.Command(cmd).Run()`). Calls in blocks constant propagation proves dead are skipped.
+func BuildCallGraph(prog *core.ProgramIR, cfg Config) *CallGraph {
+	MarkDeadCode(prog)
+	pt := AnalyzePointsTo(prog)
+	e := NewEngine(cfg)
+
+	type ruleSinks struct {
+		id    string
+		sinks []callSink
+		regex []*regexp.Regexp
+	}
+	var rules []ruleSinks
+	for _, rule := range cfg.Rules {
+		rules = append(rules, ruleSinks{id: rule.ID, sinks: e.compileCallSinks(rule.CallSinks), regex: e.compileRegexes(rule.Sinks)})
+	}
+	sinkRules := func(call *core.Instruction) []string {
+		var ids []string
+		for _, r := range rules {
+			if e.matchesAny(call.Code, r.regex) {
+				ids = append(ids, r.id)
+				continue
+			}
+			for _, s := range r.sinks {
+				if s.callee.MatchString(call.Operands[0]) && hasSinkPosition(call, s.spec) {
+					ids = append(ids, r.id)
+					break
+				}
+			}
+		}
+		return ids
+	}
+
+	g := &CallGraph{}
+	nodes := make(map[string]*CallGraphNode)
+	for _, name := range prog.FunctionNames() {
+		nodes[name] = &CallGraphNode{ID: name}
+		g.Nodes = append(g.Nodes, nodes[name])
+	}
+	edges := make(map[[2]string]*CallGraphEdge)
+	addEdge := func(from, to string, line int, kind string) {
+		key := [2]string{from, to}
+		if edge := edges[key]; edge != nil {
+			if line < edge.Line {
+				edge.Line = line
+			}
+			return
+		}
+		edges[key] = &CallGraphEdge{From: from, To: to, Line: line, Kind: kind}
+	}
+
+	var external []*CallGraphNode
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		libraryResults := make(map[string]bool) // Values returned by calls of no program function
+		for _, id := range fn.BlockIDs() {
+			for _, inst := range fn.Blocks[id].Instructions {
+				if inst.Op == core.OpCall && len(inst.Operands) > 0 && inst.Result != "" && len(pt.Callees(name, inst)) == 0 {
+					libraryResults[inst.Result] = true
+				}
+			}
+		}
+		for _, id := range fn.BlockIDs() {
+			bb := fn.Blocks[id]
+			if bb.Unreachable {
+				continue
+			}
+			for _, inst := range bb.Instructions {
+				if inst.Op != core.OpCall || len(inst.Operands) == 0 {
+					continue
+				}
+				callee := inst.Operands[0]
+				targets := pt.Callees(name, inst)
+				for _, t := range targets {
+					kind := "points-to"
+					if matchesFunction(t, callee) {
+						kind = "name"
+					}
+					addEdge(name, t, inst.Line, kind)
+				}
+				if len(targets) == 0 && inst.Receiver != "" && !libraryResults[inst.Receiver] {
+					for _, t := range methodsNamed(prog, callee[strings.LastIndexByte(callee, '.')+1:]) {
+						addEdge(name, t, inst.Line, "hierarchy")
+					}
+				}
+				if ids := sinkRules(inst); len(ids) > 0 {
+					sink := nodes[callee]
+					if sink == nil {
+						sink = &CallGraphNode{ID: callee, External: true}
+						nodes[callee] = sink
+						external = append(external, sink)
+					}
+					sink.Sink = true
+					for _, id := range ids {
+						if !slices.Contains(sink.Rules, id) {
+							sink.Rules = append(sink.Rules, id)
+						}
+					}
+					addEdge(name, callee, inst.Line, "name")
+				}
+			}
+		}
+	}
+	sort.Slice(external, func(i, j int) bool { return external[i].ID < external[j].ID })
+	g.Nodes = append(g.Nodes, external...)
+
+	for _, edge := range edges {
+		g.Edges = append(g.Edges, edge)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+
+	// Entrypoints have no caller other than themselves
+	called := make(map[string]bool)
+	callers := make(map[string][]string)
+	for _, edge := range g.Edges {
+		if edge.From != edge.To {
+			called[edge.To] = true
+		}
+		callers[edge.To] = append(callers[edge.To], edge.From)
+	}
+	for _, n := range g.Nodes {
+		n.Entrypoint = !n.External && (!called[n.ID] || isEntryName(n.ID))
+	}
+
+	// Walk back from the sinks to every function that can reach one
+	var queue []string
+	for _, n := range g.Nodes {
+		if n.Sink {
+			queue = append(queue, n.ID)
+		}
+	}
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+		for _, caller := range callers[curr] {
+			if n := nodes[caller]; !n.ReachesSink {
+				n.ReachesSink = true
+				queue = append(queue, caller)
+			}
+		}
+	}
+	return g
+}
+
+// hasSinkPosition reports whether the call has an operand the spec would check,
+// so that `r.URL.Query()` is not taken for a `.Query` sink
+func hasSinkPosition(call *core.Instruction, spec SinkSpec) bool {
+	args := len(call.Operands) - 1
+	if spec.Receiver && call.Receiver != "" {
+		return true
+	}
+	if len(spec.Args) == 0 && !spec.Receiver {
+		return args > 0
+	}
+	for _, idx := range spec.Args {
+		if idx >= 0 && idx < args {
+			return true
+		}
+	}
+	return false
+}
+
+// methodsNamed lists the functions of prog that are methods called method
+func methodsNamed(prog *core.ProgramIR, method string) []string {
+	var names []string
+	for _, name := range prog.FunctionNames() {
+		base := name
+		if i := strings.LastIndexByte(base, '#'); i >= 0 {
+			base = base[:i]
+		}
+		if strings.HasSuffix(base, "."+method) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isEntryName reports whether a function runs without being called: main and init
+func isEntryName(name string) bool {
+	if i := strings.LastIndexByte(name, '#'); i >= 0 {
+		name = name[:i]
+	}
+	name = name[strings.LastIndexByte(name, '.')+1:]
+	return name == "main" || name == "init"
+}
+
+// DOT renders the graph for Graphviz: entrypoints are filled blue, sinks red, and
+// functions that reach a sink have a red border. Edges not resolved by name are dashed.
+func (g *CallGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph callgraph {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	for _, n := range g.Nodes {
+		label := dotEscape(n.ID)
+		if n.File != "" {
+			label += `\n` + dotEscape(n.File)
+		}
+		attrs := []string{fmt.Sprintf("label=\"%s\"", label)}
+		switch {
+		case n.Sink:
+			attrs = append(attrs, "shape=ellipse", "style=filled", "fillcolor=\"#ffccc7\"", "color=\"#cf1322\"")
+		case n.Entrypoint:
+			attrs = append(attrs, "style=filled", "fillcolor=\"#bae0ff\"")
+		}
+		if n.ReachesSink {
+			attrs = append(attrs, "color=\"#cf1322\"", "penwidth=2")
+		}
+		fmt.Fprintf(&b, "  \"%s\" [%s];\n", dotEscape(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, edge := range g.Edges {
+		style := ""
+		if edge.Kind != "name" {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  \"%s\" -> \"%s\" [label=\"L%d\"%s];\n", dotEscape(edge.From), dotEscape(edge.To), edge.Line, style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// Mermaid renders the graph as a Mermaid flowchart with the same highlighting as DOT
+func (g *CallGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		label := mermaidEscape(n.ID)
+		if n.File != "" {
+			label += "<br/>" + mermaidEscape(n.File)
+		}
+		if n.Sink {
+			fmt.Fprintf(&b, "  %s([\"%s\"])\n", id, label)
+		} else {
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, label)
+		}
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		if edge.Kind != "name" {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|L%d| %s\n", ids[edge.From], arrow, edge.Line, ids[edge.To])
+	}
+	b.WriteString("  classDef entry fill:#bae0ff,stroke:#1677ff\n")
+	b.WriteString("  classDef sink fill:#ffccc7,stroke:#cf1322\n")
+	b.WriteString("  classDef reaches stroke:#cf1322,stroke-width:2px\n")
+	for _, n := range g.Nodes {
+		switch {
+		case n.Sink:
+			fmt.Fprintf(&b, "  class %s sink\n", ids[n.ID])
+		case n.Entrypoint && n.ReachesSink:
+			fmt.Fprintf(&b, "  class %s entry\n  style %s stroke:#cf1322,stroke-width:2px\n", ids[n.ID], ids[n.ID])
+		case n.Entrypoint:
+			fmt.Fprintf(&b, "  class %s entry\n", ids[n.ID])
+		case n.ReachesSink:
+			fmt.Fprintf(&b, "  class %s reaches\n", ids[n.ID])
+		}
+	}
+	return b.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
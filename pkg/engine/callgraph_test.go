@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const callGraphSource = `package main
+
+import (
+	"os/exec"
+)
+
+type Runner interface{ Run(cmd string) }
+
+type Shell struct{}
+
+func (s *Shell) Run(cmd string) { exec.Command(cmd).Run() }
+
+type Dry struct{}
+
+func (d *Dry) Run(cmd string) {}
+
+func run(r Runner, cmd string) {
+	r.Run(cmd)
+}
+
+func helper(cmd string) {
+	exec.Command(cmd).Run()
+}
+
+func handle(cmd string) {
+	helper(cmd)
+	var s Runner = &Shell{}
+	s.Run(cmd)
+	if false {
+		helper("dead")
+	}
+}
+
+func unused() {}
+
+func main() {
+	handle("ls")
+	handle("pwd")
+}
+`
+
+func TestCallGraph(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", callGraphSource)
+	g := BuildCallGraph(prog, DefaultRules())
+
+	var edges []string
+	for _, e := range g.Edges {
+		edges = append(edges, e.From+"->"+e.To+"/"+e.Kind)
+	}
+	want := []string{
+		"Shell.Run->exec.Command/name",
+		"handle->Shell.Run/points-to",
+		"handle->helper/name",
+		"helper->exec.Command/name",
+		"main->handle/name",
+		// Nothing the receiver may point to is known, so every Run method is a callee
+		"run->Dry.Run/hierarchy",
+		"run->Shell.Run/hierarchy",
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("edges\n%v\nwant\n%v", edges, want)
+	}
+	for _, e := range g.Edges {
+		if e.From == "main" && e.Line != 37 {
+			t.Errorf("main->handle at line %d, want its first call at 37", e.Line)
+		}
+	}
+
+	var entries, reaching, sinks []string
+	for _, n := range g.Nodes {
+		if n.Entrypoint {
+			entries = append(entries, n.ID)
+		}
+		if n.ReachesSink {
+			reaching = append(reaching, n.ID)
+		}
+		if n.Sink {
+			sinks = append(sinks, n.ID)
+			if !n.External || !reflect.DeepEqual(n.Rules, []string{"rce"}) {
+				t.Errorf("sink %+v", *n)
+			}
+		}
+	}
+	if want := []string{"main", "run", "unused"}; !reflect.DeepEqual(entries, want) {
+		t.Errorf("entrypoints %v, want %v", entries, want)
+	}
+	if want := []string{"Shell.Run", "handle", "helper", "main", "run"}; !reflect.DeepEqual(reaching, want) {
+		t.Errorf("functions reaching a sink %v, want %v", reaching, want)
+	}
+	if want := []string{"exec.Command"}; !reflect.DeepEqual(sinks, want) {
+		t.Errorf("sinks %v, want %v", sinks, want)
+	}
+
+	dot := g.DOT()
+	for _, s := range []string{`"main" -> "handle" [label="L37"]`, `"handle" -> "Shell.Run" [label="L28", style=dashed]`, `"exec.Command" [label="exec.Command", shape=ellipse`} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("DOT output lacks %s:\n%s", s, dot)
+		}
+	}
+	mermaid := g.Mermaid()
+	if !strings.HasPrefix(mermaid, "flowchart LR\n") || !strings.Contains(mermaid, `(["exec.Command"])`) || !strings.Contains(mermaid, "-.->|L28|") {
+		t.Errorf("Mermaid output:\n%s", mermaid)
+	}
+}
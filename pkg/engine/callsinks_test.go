@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+const callSinkSource = `package main
+
+import (
+	"database/sql"
+	"net/http"
+	"os/exec"
+)
+
+func handler(db *sql.DB, r *http.Request) {
+	input := r.URL.Query().Get("q")
+	exec.Command("ls", input).Run()
+	exec.Command(input).Run()
+	db.Query("SELECT name FROM users WHERE id = ?", input)
+	db.Query("SELECT name FROM users WHERE id = " + input)
+}
+`
+
+// Only taint in a sink spec's argument positions is reported: the arguments of
+// the command and the values bound to a query's placeholders are not
+func TestCallSinkPositions(t *testing.T) {
+	vulns := scan(t, "main.go", callSinkSource)
+	if got, want := findings(vulns), []string{"rce@10->12", "sqli@10->14"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+}
+
+func TestCallSinkSpecs(t *testing.T) {
+	tests := []struct {
+		name string
+		spec SinkSpec
+		want []string
+	}{
+		{"second argument", SinkSpec{Callee: "^(os/)?exec\\.Command$", Args: []int{1}}, []string{"rce@10->11"}},
+		{"every argument", SinkSpec{Callee: "^(os/)?exec\\.Command$"}, []string{"rce@10->11", "rce@10->12"}},
+		{"other callee", SinkSpec{Callee: "^exec\\.LookPath$"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vulns := scan(t, "main.go", callSinkSource, func(c *Config) {
+				for i := range c.Rules {
+					if c.Rules[i].ID == "rce" {
+						c.Rules[i].CallSinks = []SinkSpec{tt.spec}
+					}
+				}
+			})
+			var got []string
+			for _, f := range findings(vulns) {
+				if f[:4] == "rce@" {
+					got = append(got, f)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const javaCallSinkSource = `import java.sql.*;
+import javax.servlet.http.HttpServletRequest;
+
+public class Users {
+    public void find(Connection conn, HttpServletRequest request) throws Exception {
+        String id = request.getParameter("id");
+        PreparedStatement ps = conn.prepareStatement("SELECT name FROM users WHERE id = ?");
+        ps.setString(1, id);
+        ps.executeQuery();
+        Statement st = conn.createStatement();
+        st.executeQuery("SELECT name FROM users WHERE id = " + id);
+    }
+}
+`
+
+func TestJavaCallSinkPositions(t *testing.T) {
+	vulns := scan(t, "Users.java", javaCallSinkSource)
+	if got, want := findings(vulns), []string{"sqli@6->11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+const channelSource = `package main
+
+import (
+	"net/http"
+	"os/exec"
+)
+
+var jobs = make(chan string)
+
+type Server struct {
+	tasks chan string
+}
+
+func selectRecv(r *http.Request, quit chan bool) {
+	ch := make(chan string, 1)
+	ch <- r.URL.Query().Get("a")
+	select {
+	case v := <-ch:
+		println(v)
+	case <-quit:
+	}
+}
+
+func namedWorker(ch chan string, r *http.Request) {
+	ch <- r.URL.Query().Get("b")
+}
+
+func closureRecv(r *http.Request) {
+	ch := make(chan string, 1)
+	go func() {
+		exec.Command(<-ch).Run()
+	}()
+	ch <- r.URL.Query().Get("c")
+}
+
+func closureSend(r *http.Request, quit chan bool) {
+	ch := make(chan string, 1)
+	go func() {
+		ch <- r.URL.Query().Get("d")
+	}()
+	select {
+	case v := <-ch:
+		exec.Command(v).Run()
+	case <-quit:
+	}
+}
+
+func worker(in chan string) {
+	for cmd := range in {
+		exec.Command(cmd).Run()
+	}
+}
+
+func handler(r *http.Request) {
+	ch := make(chan string)
+	go worker(ch)
+	ch <- r.URL.Query().Get("e")
+}
+
+func global(r *http.Request) {
+	jobs <- r.URL.Query().Get("f")
+}
+
+func globalWorker() {
+	for name := range jobs {
+		exec.Command(name).Run()
+	}
+}
+
+func (s *Server) Handle(r *http.Request) {
+	s.tasks <- r.URL.Query().Get("g")
+}
+
+func (s *Server) Run() {
+	exec.Command(<-s.tasks).Run()
+}
+`
+
+// Sends reach the receives of the same channel only: a closure shares the
+// channels of the function enclosing it, in both directions, and functions
+// with channels of the same name do not
+func TestChannelFlows(t *testing.T) {
+	want := []string{
+		"rce@33->31", // closureRecv: closure receives what its parent sends
+		"rce@39->43", // closureSend: parent selects what its closure sends
+		"rce@57->50", // handler -> worker, through points-to
+		"rce@61->66", // package-level channel
+		"rce@71->75", // channel field of the receivers of two methods
+	}
+	if got := findings(scan(t, "main.go", channelSource)); !reflect.DeepEqual(got, want) {
+		t.Errorf("findings\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", channelSource)
+	sc := newScopes(prog)
+	tests := []struct {
+		fn, v, owner string
+	}{
+		{"closureRecv.func1", "ch", "closureRecv"},
+		{"closureRecv", "ch", "closureRecv"},
+		{"closureSend.func1", "ch.<-", "closureSend"},
+		{"namedWorker", "ch", "namedWorker"},
+		{"globalWorker", "jobs", ""},
+		{"global", "jobs.<-", ""},
+	}
+	for _, tt := range tests {
+		if got := sc.owner(tt.fn, tt.v); got != tt.owner {
+			t.Errorf("owner of %s in %s = %q, want %q", tt.v, tt.fn, got, tt.owner)
+		}
+	}
+	for name, want := range map[string]string{"a.func1": "a", "a.func1.func12": "a.func1", "a": "", "T.funcs": "", "a.func": ""} {
+		if got := enclosingFunc(name); got != want {
+			t.Errorf("enclosingFunc(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
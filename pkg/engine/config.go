@@ -1,22 +1,175 @@
 package engine
 
+import "slices"
+
 type Rule struct {
+	ID          string     `json:"id"` // Short identifier used by suppressions, e.g. "sqli"
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Severity    string     `json:"severity"`
+	Sources     []string   `json:"sources"`    // Regex patterns
+	Sinks       []string   `json:"sinks"`      // Regex patterns
+	CallSinks   []SinkSpec `json:"call_sinks"` // Sinks that only fire for specific argument positions
+	// Validators are callee regexes of boolean checks; a sink only reached after
+	// a successful check of the tainted value is reported as guarded
+	Validators []string `json:"validators"`
+	// Labels are source labels (Config.SourceLabels) the rule takes as sources in
+	// addition to those matching Sources; sources carrying a RejectLabels label are
+	// not sources of the rule even when they match
+	Labels       []string `json:"labels"`
+	RejectLabels []string `json:"reject_labels"`
+	// QueryLanguage, if set, is the language of the queries the rule's sinks run
+	// (QuerySQL); the query reaching a sink is parsed to tell where the taint lies in
+	// it, and the finding's severity and confidence follow (see sql.go)
+	QueryLanguage string `json:"query_language"`
+}
+
+// QuerySQL is the Rule.QueryLanguage of SQL sinks
+const QuerySQL = "sql"
+
+// SinkSpec names a callee and the call positions that must carry taint.
+// Unlike regex sinks, `db.Query(constQuery, tainted)` does not match a spec with Args [0].
+type SinkSpec struct {
+	Callee   string `json:"callee"`   // Regex matched against the callee (OpCall Operands[0])
+	Args     []int  `json:"args"`     // 0-based argument indexes
+	Receiver bool   `json:"receiver"` // Taint on the receiver object also counts
+	// With neither Args nor Receiver set, every argument is a sink position.
+
+	// Labels, if set, restrict the sink to taint from sources carrying one of
+	// them; taint from a source carrying a RejectLabels label never hits it
+	Labels       []string `json:"labels"`
+	RejectLabels []string `json:"reject_labels"`
+}
+
+// SourceLabel names a kind of source, such as "http-input" or "env". Every
+// instruction matching one of the patterns carries the label, and findings list
+// the labels of the sources reaching their sink.
+type SourceLabel struct {
+	Label    string   `json:"label"`
+	Patterns []string `json:"patterns"` // Regexes matched against the instruction code
+}
+
+// OutParamSpec models a library call that writes taint through pointer arguments
+// instead of returning it, as `json.Unmarshal(body, &v)` and `rows.Scan(&name)` do.
+// When taint reaches the call in one of From, the variables whose address is
+// passed in To become tainted, fields included.
+type OutParamSpec struct {
+	Callee string `json:"callee"` // Regex matched against the callee (OpCall Operands[0])
+	From   []int  `json:"from"`   // 0-based arguments, -1 for the receiver; empty means any
+	To     []int  `json:"to"`     // 0-based arguments written through, -1 for the receiver; empty means every argument not in From
+	// ReturnsReceiver marks builder methods that return their receiver, so that a
+	// chained call (`sb.append(a).append(id)`) writes to the object it started from
+	ReturnsReceiver bool `json:"returns_receiver"`
+}
+
+// FormatSpec describes a printf-style function: the argument holding the format
+// string, with the values it prints following it. Both Go (%[2]s) and Java (%2$s)
+// verbs are understood.
+type FormatSpec struct {
+	Callee string `json:"callee"` // Regex matched against the callee (OpCall Operands[0])
+	Format int    `json:"format"` // 0-based argument of the format string
+}
+
+// StoreSpec models a persistent store. Tainted data written through one of
+// Writes comes back, possibly in another handler, from the Reads of the same key.
+type StoreSpec struct {
+	Kind   string      `json:"kind"` // "sql", "cache" or "file"; sql keys are the tables named in the query
+	Writes []StoreCall `json:"writes"`
+	Reads  []StoreCall `json:"reads"`
+}
+
+// StoreCall is a call writing to or reading from a store
+type StoreCall struct {
+	Callee string `json:"callee"` // Regex matched against the callee (OpCall Operands[0])
+	Key    int    `json:"key"`    // 0-based argument holding the query, cache key or file path; -1 for none
+	Args   []int  `json:"args"`   // Arguments written; empty means every argument
+}
+
+// PatternRule matches code by syntax tree shape instead of data flow.
+// Patterns are written in the target language; `$X` matches any expression
+// and `...` any run of arguments, elements or statements.
+type PatternRule struct {
+	ID          string   `json:"id"`
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Severity    string   `json:"severity"`
-	Sources     []string `json:"sources"` // Regex patterns
-	Sinks       []string `json:"sinks"`   // Regex patterns
+	Language    string   `json:"language"` // "go" or "java"
+	Patterns    []string `json:"patterns"` // Any of these matches
 }
 
 type Config struct {
-	Rules []Rule `json:"rules"`
+	Rules        []Rule        `json:"rules"`
+	PatternRules []PatternRule `json:"pattern_rules"`
+	SourceLabels []SourceLabel `json:"source_labels"`
+	// Stores are the databases, caches and files through which taint reaches second-order sinks
+	Stores []StoreSpec `json:"stores"`
+	// OutParams are the library calls that taint their pointer arguments
+	OutParams []OutParamSpec `json:"out_params"`
+	// Formats are the printf-style functions whose format strings are parsed to
+	// tell which part of a string reaching a sink is tainted
+	Formats []FormatSpec `json:"formats"`
+	// ImplicitRules report sinks that a branch on a source decides, rather than
+	// sinks the source's value reaches; they only run when ImplicitFlows is set
+	ImplicitRules []Rule `json:"implicit_rules"`
+	ImplicitFlows bool   `json:"implicit_flows"`
+
+	// PathsPerSink is how many distinct shortest paths to keep per source-sink pair (k); 0 means 1
+	PathsPerSink int `json:"paths_per_sink"`
+	// PathBudget caps the dataflow nodes (instruction, fact) one taint solve processes; 0 means DefaultPathBudget
+	PathBudget int `json:"path_budget"`
+	// AccessPathDepth is how many fields a taint fact keeps (`v.f.g` is depth 2); 0 means DefaultAccessPathDepth
+	AccessPathDepth int `json:"access_path_depth"`
+	// ContextDepth is how many nested calls function summaries analyze (the k of the
+	// call strings); deeper calls taint their result from any tainted argument. 0 means DefaultContextDepth
+	ContextDepth int `json:"context_depth"`
+	// ElementPrecision is how finely elements of slices, arrays and maps are told
+	// apart: ElementsByKey (the default when empty) or ElementsWhole
+	ElementPrecision string `json:"element_precision"`
 }
 
+const (
+	// ElementsByKey keeps elements at different constant indexes and keys apart
+	ElementsByKey = "key"
+	// ElementsWhole merges all elements of a container: a tainted element taints every read
+	ElementsWhole = "container"
+)
+
+// DefaultPathBudget bounds taint solving when the config does not set one
+const DefaultPathBudget = 10000
+
+// DefaultAccessPathDepth is the field depth of taint facts when the config does not set one
+const DefaultAccessPathDepth = 3
+
+// DefaultContextDepth is the call-string length of function summaries when the config does not set one
+const DefaultContextDepth = 3
+
 // DefaultRules returns a set of built-in rules for the demo
 func DefaultRules() Config {
+	commandSinks := []string{
+		"Runtime\\.getRuntime\\(\\)\\.exec", // Java
+		"ProcessBuilder",                    // Java
+		"os\\.system",                       // Python (external frontend)
+		"subprocess\\.",                     // Python (external frontend)
+	}
+	commandCallSinks := []SinkSpec{
+		// Go: only the program name; tainted arguments to a fixed binary are not RCE
+		{Callee: "^(os/)?exec\\.Command$", Args: []int{0}},
+		{Callee: "^(os/)?exec\\.CommandContext$", Args: []int{1}},
+		{Callee: "^syscall\\.Exec$", Args: []int{0}},
+	}
+	// Receivers of database calls: *sql.DB, Tx, Stmt and Conn in Go, and JDBC
+	// statements and connections, by their usual variable and field names
+	sqlDB := "(?i)(^|\\.)(db|sqldb|database|tx|stmt|conn)"
+	jdbcStmt := "(?i)(^|\\.)(stmt|statement|ps|pstmt|preparedStatement|conn|connection)"
+	commandValidators := []string{
+		// isSafeCommand(cmd), validator.IsAllowedArg(arg), ...
+		"(?i)(^|\\.)is(Valid|Safe|Allowed)(Command|Cmd|Arg|Program)?$",
+	}
+
 	return Config{
 		Rules: []Rule{
 			{
+				ID:          "rce",
 				Name:        "Command Injection (RCE)",
 				Description: "User input flows into command execution",
 				Severity:    "CRITICAL",
@@ -25,46 +178,55 @@ func DefaultRules() Config {
 					"os\\.Args",              // Go
 					"scanner\\.nextLine",     // Java
 					"r\\.URL\\.Query",        // Go
+					"r\\.Body",               // Go
+					"request\\.args",         // Python (external frontend)
 				},
-				Sinks: []string{
-					"Runtime\\.getRuntime\\(\\)\\.exec", // Java
-					"os/exec\\.Command",                 // Go
-					"exec\\.Command",                    // Go
-					"syscall\\.Exec",                    // Go
-					"ProcessBuilder",                    // Java
-				},
+				Sinks:      commandSinks,
+				CallSinks:  commandCallSinks,
+				Validators: commandValidators,
 			},
 			{
+				ID:          "sqli",
 				Name:        "SQL Injection",
 				Description: "User input flows into SQL query",
 				Severity:    "HIGH",
 				Sources: []string{
 					"request\\.getParameter",
 					"r\\.URL\\.Query",
+					"r\\.Body",
 				},
+				QueryLanguage: QuerySQL,
 				Sinks: []string{
-					// Go
-					"sql\\.Exec",
-					"db\\.Query",
-					// JDBC
-					"executeQuery",
-					"execute",
-					// JPA / Hibernate
-					"entityManager\\.createQuery",
-					"session\\.createQuery",
-					"session\\.createSQLQuery",
 					// MyBatis (programmatic)
 					"sqlSession\\.selectOne",
 					"sqlSession\\.selectList",
 				},
+				CallSinks: []SinkSpec{
+					// Go database/sql: the query text, not the bound parameters
+					{Callee: "\\.(Query|QueryRow|Exec|Prepare)$", Args: []int{0}},
+					{Callee: "\\.(QueryContext|QueryRowContext|ExecContext|PrepareContext)$", Args: []int{1}},
+					// JDBC
+					{Callee: "\\.(executeQuery|executeUpdate|execute|addBatch|prepareStatement|prepareCall)$", Args: []int{0}},
+					// JPA / Hibernate
+					{Callee: "(entityManager|session)\\.create(SQL|Native)?Query$", Args: []int{0}},
+					// Spring JdbcTemplate: every argument, so that values bound to the
+					// query's placeholders are reported as such rather than missed
+					{Callee: "(?i)jdbcTemplate\\.(query|queryForObject|queryForList|queryForMap|queryForRowSet|update|execute|batchUpdate)$"},
+				},
+				Validators: []string{
+					// Identifiers that cannot be bound as parameters (ORDER BY columns etc.)
+					"(?i)(^|\\.)is(Valid|Safe|Allowed)(Identifier|Column|Table|SortField)?$",
+				},
 			},
 			{
+				ID:          "xss",
 				Name:        "XSS (Cross-Site Scripting)",
 				Description: "User input flows into HTML output",
 				Severity:    "MEDIUM",
 				Sources: []string{
 					"request\\.getParameter",
 					"r\\.URL\\.Query",
+					"r\\.Body",
 				},
 				Sinks: []string{
 					// Java
@@ -77,12 +239,15 @@ func DefaultRules() Config {
 				},
 			},
 			{
+				ID:          "ssrf",
 				Name:        "SSRF (Server-Side Request Forgery)",
 				Description: "User input controls network request target",
 				Severity:    "HIGH",
 				Sources: []string{
 					"request\\.getParameter",
 					"r\\.URL\\.Query",
+					"r\\.Body",
+					"request\\.args",
 				},
 				Sinks: []string{
 					// Java
@@ -94,15 +259,23 @@ func DefaultRules() Config {
 					"http\\.Get",
 					"http\\.Post",
 					"http\\.NewRequest",
+					// Python (external frontend)
+					"requests\\.(get|post)",
+				},
+				Validators: []string{
+					// isValidURL(target), allowlist.IsAllowedHost(host), ...
+					"(?i)(^|\\.)is(Valid|Safe|Allowed|Trusted)(URL|Host|Domain)?$",
 				},
 			},
 			{
+				ID:          "path-traversal",
 				Name:        "Path Traversal",
 				Description: "User input controls file path",
 				Severity:    "HIGH",
 				Sources: []string{
 					"request\\.getParameter",
 					"r\\.URL\\.Query",
+					"r\\.Body",
 				},
 				Sinks: []string{
 					// Java
@@ -116,7 +289,262 @@ func DefaultRules() Config {
 					"ioutil\\.ReadFile",
 					"os\\.ReadFile",
 				},
+				Validators: []string{
+					"^(path/)?filepath\\.IsLocal$", // Go 1.20
+					"(?i)(^|\\.)is(Valid|Safe|Allowed)(Path|File|FileName)?$",
+				},
 			},
 		},
+		SourceLabels: []SourceLabel{
+			{
+				Label: "http-input",
+				Patterns: []string{
+					"request\\.getParameter", // Java
+					"request\\.getHeader",    // Java
+					"r\\.URL\\.Query",        // Go
+					"r\\.FormValue",          // Go
+					"r\\.Header\\.Get",       // Go
+					"r\\.Body",               // Go
+					"request\\.args",         // Python (external frontend)
+				},
+			},
+			{
+				Label: "cli-arg",
+				Patterns: []string{
+					"os\\.Args",                   // Go
+					"flag\\.(Arg|Args|String)\\b", // Go
+					"scanner\\.nextLine",          // Java console input
+				},
+			},
+			{
+				Label: "env",
+				Patterns: []string{
+					"os\\.(Getenv|LookupEnv)", // Go
+					"System\\.getenv",         // Java
+				},
+			},
+			{
+				Label: "file-content",
+				Patterns: []string{
+					"(os|ioutil)\\.ReadFile",                         // Go
+					"Files\\.(readAllBytes|readAllLines|readString)", // Java
+				},
+			},
+			{
+				Label: "db-read",
+				Patterns: []string{
+					"rows?\\.Scan",                        // Go database/sql
+					"(resultSet|rs)\\.get(String|Object)", // JDBC
+				},
+			},
+			{
+				Label: "pii",
+				Patterns: []string{
+					// Reads of personal fields: user.Email, customer.getPhone() ...
+					"(?i)\\.(get)?(email|ssn|phone|birthdate|dateOfBirth)\\b",
+				},
+			},
+		},
+		Stores: []StoreSpec{
+			{
+				Kind: "sql",
+				// Only calls on database handles: `r.URL.Query()` or `executor.execute(task)`
+				// are not reads or writes of a store
+				Writes: []StoreCall{
+					{Callee: sqlDB + "\\.Exec$", Key: 0},                       // Go database/sql
+					{Callee: sqlDB + "\\.ExecContext$", Key: 1},                // Go database/sql
+					{Callee: jdbcStmt + "\\.(executeUpdate|execute)$", Key: 0}, // JDBC
+					{Callee: "jdbcTemplate\\.update$", Key: 0},                 // Spring
+				},
+				Reads: []StoreCall{
+					{Callee: sqlDB + "\\.(Query|QueryRow)$", Key: 0},               // Go database/sql
+					{Callee: sqlDB + "\\.(QueryContext|QueryRowContext)$", Key: 1}, // Go database/sql
+					{Callee: jdbcStmt + "\\.executeQuery$", Key: 0},                // JDBC
+					{Callee: "jdbcTemplate\\.query(ForObject|ForList)?$", Key: 0},
+				},
+			},
+			{
+				Kind: "cache",
+				Writes: []StoreCall{
+					{Callee: "(?i)(^|\\.)cache\\.(Set|Put)$", Key: 0, Args: []int{1}},
+					{Callee: "^(rdb|redis|client)\\.Set$", Key: 1, Args: []int{2}}, // go-redis
+					{Callee: "(?i)(^|\\.)jedis\\.set$", Key: 0, Args: []int{1}},
+				},
+				Reads: []StoreCall{
+					{Callee: "(?i)(^|\\.)cache\\.Get$", Key: 0},
+					{Callee: "^(rdb|redis|client)\\.Get$", Key: 1},
+					{Callee: "(?i)(^|\\.)jedis\\.get$", Key: 0},
+				},
+			},
+			{
+				Kind: "file",
+				Writes: []StoreCall{
+					{Callee: "^(os|ioutil)\\.WriteFile$", Key: 0, Args: []int{1}},
+					{Callee: "Files\\.write(String)?$", Key: 0, Args: []int{1}},
+				},
+				Reads: []StoreCall{
+					{Callee: "^(os|ioutil)\\.ReadFile$", Key: 0},
+					{Callee: "Files\\.(readAllBytes|readAllLines|readString)$", Key: 0},
+				},
+			},
+		},
+		OutParams: []OutParamSpec{
+			// Go decoding: json/xml/yaml.Unmarshal(data, &v), dec.Decode(&v)
+			{Callee: "^(json|xml|yaml|toml|proto)\\.Unmarshal$", From: []int{0}, To: []int{1}},
+			{Callee: "\\.Decode$", From: []int{-1}, To: []int{0}},
+			// Go database/sql: rows.Scan(&a, &b)
+			{Callee: "\\.Scan$", From: []int{-1}},
+			// Go formatted input: fmt.Sscanf(s, format, &a, &b)
+			{Callee: "^fmt\\.(Sscan|Sscanf|Sscanln|Fscan|Fscanf|Fscanln)$", From: []int{0}},
+			// Go readers: r.Read(buf), io.ReadFull(r, buf), binary.Read(r, order, &v), copy(dst, src)
+			{Callee: "\\.Read$", From: []int{-1}, To: []int{0}},
+			{Callee: "^io\\.(ReadFull|ReadAtLeast)$", From: []int{0}, To: []int{1}},
+			{Callee: "^binary\\.Read$", From: []int{0}, To: []int{2}},
+			{Callee: "^copy$", From: []int{1}, To: []int{0}},
+			// Java: BeanUtils.copyProperties(source, target), System.arraycopy(src, pos, dest, ...)
+			{Callee: "BeanUtils\\.copyProperties$", From: []int{0}, To: []int{1}},
+			{Callee: "System\\.arraycopy$", From: []int{0}, To: []int{2}},
+			// Containers filled through methods: list.add(v), map.put(k, v),
+			// sb.append(v); reads (get, toString, ...) return from the receiver
+			{Callee: "\\.(add|addAll|addFirst|addLast|offer|push|put|putAll|putIfAbsent|set)$", To: []int{-1}},
+			{Callee: "\\.(append|insert)$", To: []int{-1}, ReturnsReceiver: true},
+			// Go builders: b.WriteString(s). Not Write, which is also how responses are written.
+			{Callee: "\\.(WriteString|WriteByte|WriteRune)$", To: []int{-1}},
+		},
+		Formats: []FormatSpec{
+			{Callee: "^fmt\\.(Sprintf|Errorf|Printf)$", Format: 0},
+			{Callee: "^fmt\\.Fprintf$", Format: 1},
+			{Callee: "^log\\.(Printf|Fatalf|Panicf)$", Format: 0},
+			// Java: String.format(fmt, ...), out.printf(fmt, ...)
+			{Callee: "^(java\\.lang\\.)?String\\.format$", Format: 0},
+			{Callee: "\\.printf$", Format: 0},
+		},
+		ImplicitRules: []Rule{
+			{
+				ID:          "implicit-flow",
+				Name:        "Implicit Information Flow",
+				Description: "A branch on sensitive input decides what is written out",
+				Severity:    "LOW",
+				Sources: []string{
+					"request\\.getParameter",
+					"r\\.URL\\.Query",
+					"r\\.FormValue",
+					"os\\.Getenv",
+					"System\\.getenv",
+				},
+				Labels: []string{"pii"},
+				Sinks: []string{
+					// Java
+					"out\\.print",
+					"response\\.getWriter\\(\\)\\.write",
+					"response\\.sendError",
+				},
+				CallSinks: []SinkSpec{
+					// Go: response writers, formatted output and logs
+					{Callee: "^w\\.(Write|WriteHeader)$"},
+					{Callee: "^(fmt\\.(Fprint|Fprintf|Fprintln|Print|Printf|Println)|io\\.WriteString|http\\.(Error|Redirect))$"},
+					{Callee: "^log\\.(Print|Printf|Println)$"},
+				},
+			},
+		},
+		PatternRules: []PatternRule{
+			{
+				ID:          "insecure-tls",
+				Name:        "Insecure TLS Configuration",
+				Description: "Certificate verification is disabled",
+				Severity:    "HIGH",
+				Language:    "go",
+				Patterns: []string{
+					"tls.Config{..., InsecureSkipVerify: true, ...}",
+				},
+			},
+			{
+				ID:          "insecure-tls",
+				Name:        "Insecure TLS Configuration",
+				Description: "Hostname verification is disabled",
+				Severity:    "HIGH",
+				Language:    "java",
+				Patterns: []string{
+					"new NoopHostnameVerifier()",
+					"$X.setHostnameVerifier(NoopHostnameVerifier.INSTANCE)",
+				},
+			},
+			{
+				ID:          "weak-hash",
+				Name:        "Weak Hash Algorithm",
+				Description: "MD5 and SHA-1 are not collision resistant",
+				Severity:    "MEDIUM",
+				Language:    "go",
+				Patterns: []string{
+					"md5.New()",
+					"md5.Sum(...)",
+					"sha1.New()",
+					"sha1.Sum(...)",
+				},
+			},
+			{
+				ID:          "weak-hash",
+				Name:        "Weak Hash Algorithm",
+				Description: "MD5 and SHA-1 are not collision resistant",
+				Severity:    "MEDIUM",
+				Language:    "java",
+				Patterns: []string{
+					`MessageDigest.getInstance("MD5")`,
+					`MessageDigest.getInstance("SHA-1")`,
+					`MessageDigest.getInstance("SHA1")`,
+				},
+			},
+			{
+				ID:          "shell-exec",
+				Name:        "Shell Command Execution",
+				Description: "Command is run through a shell interpreter",
+				Severity:    "HIGH",
+				Language:    "go",
+				Patterns: []string{
+					`exec.Command("sh", "-c", ...)`,
+					`exec.Command("bash", "-c", ...)`,
+					`exec.CommandContext($CTX, "sh", "-c", ...)`,
+					`exec.CommandContext($CTX, "bash", "-c", ...)`,
+				},
+			},
+			{
+				ID:          "shell-exec",
+				Name:        "Shell Command Execution",
+				Description: "Command is run through a shell interpreter",
+				Severity:    "HIGH",
+				Language:    "java",
+				Patterns: []string{
+					`new ProcessBuilder("sh", "-c", ...)`,
+					`new ProcessBuilder("bash", "-c", ...)`,
+					`Runtime.getRuntime().exec(new String[]{"sh", "-c", ...})`,
+					`Runtime.getRuntime().exec(new String[]{"bash", "-c", ...})`,
+				},
+			},
+		},
+	}
+}
+
+// SplitLocalInput reports command injection from command-line arguments and
+// console input (sources labeled "cli-arg") under a separate MEDIUM rule,
+// "rce-local", instead of the CRITICAL "rce": whoever passes arguments to a
+// command-line tool can usually run commands already. It is opt-in, as it changes
+// the rule, severity and fingerprint of findings accepted in existing baselines.
+func (c *Config) SplitLocalInput() {
+	for i, r := range c.Rules {
+		if r.ID != "rce" {
+			continue
+		}
+		c.Rules[i].RejectLabels = append(slices.Clip(r.RejectLabels), "cli-arg")
+		c.Rules = append(c.Rules, Rule{
+			ID:          "rce-local",
+			Name:        "Command Injection (Local Input)",
+			Description: "Command-line arguments or console input flow into command execution",
+			Severity:    "MEDIUM",
+			Labels:      []string{"cli-arg"},
+			Sinks:       r.Sinks,
+			CallSinks:   r.CallSinks,
+			Validators:  r.Validators,
+		})
+		return
 	}
 }
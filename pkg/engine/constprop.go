@@ -0,0 +1,483 @@
+package engine
+
+import (
+	"fmt"
+	"sast-demo/pkg/core"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sparse conditional constant propagation (Wegman-Zadeck) over the IR.
+//
+// Temporaries are assigned once; variables may be stored several times, so the
+// value of a variable is the meet of its stores in executable blocks. A variable
+// that may be read before any local store (parameters, fields, globals) is never
+// constant, and neither is a field path (cfg.Debug), a dereference (*p) or a
+// variable whose address is taken, which stores through aliases can change behind
+// its back. Variables a function shares with its closures (`outer.func1`) are not
+// constant either: calling the closure stores to them behind the function's back,
+// and the other way round. Branches whose condition folds to a constant make only one successor
+// executable; blocks that never become executable are dead.
+
+type latticeState int
+
+const (
+	latticeTop    latticeState = iota // No executable definition seen yet
+	latticeConst                      // A single known value
+	latticeBottom                     // Not a constant
+)
+
+// cell is a lattice value; val holds an int64, bool or string constant
+type cell struct {
+	state latticeState
+	val   interface{}
+}
+
+var bottomCell = cell{state: latticeBottom}
+
+func constCell(v interface{}) cell { return cell{state: latticeConst, val: v} }
+
+func meet(a, b cell) cell {
+	switch {
+	case a.state == latticeTop:
+		return b
+	case b.state == latticeTop:
+		return a
+	case a.state == latticeConst && b.state == latticeConst && a.val == b.val:
+		return a
+	}
+	return bottomCell
+}
+
+// MarkDeadCode runs constant propagation on every function and marks the blocks
+// that can never execute as Unreachable and the branch edges never taken as
+// DeadSuccessors. It returns the number of unreachable blocks.
+func MarkDeadCode(prog *core.ProgramIR) int {
+	dead := 0
+	for _, name := range prog.FunctionNames() {
+		dead += newConstProp(prog.Functions[name], sharedVariables(prog, name)).run()
+	}
+	return dead
+}
+
+// sharedVariables returns the variables function name shares with the closures
+// nested in it, or for a closure with the functions enclosing it and their other
+// closures: those both refer to and at least one of them stores
+func sharedVariables(prog *core.ProgramIR, name string) map[string]bool {
+	root := outermostFunc(name)
+	own, ownStores := closureVariables(prog.Functions[name])
+	shared := make(map[string]bool)
+	for other, fn := range prog.Functions {
+		if other == name || outermostFunc(other) != root {
+			continue
+		}
+		refs, stores := closureVariables(fn)
+		for v := range refs {
+			if own[v] && (stores[v] || ownStores[v]) {
+				shared[v] = true
+			}
+		}
+	}
+	return shared
+}
+
+// closureVariables returns the whole variables fn loads or stores, and those it stores
+func closureVariables(fn *core.FunctionIR) (refs, stores map[string]bool) {
+	refs, stores = make(map[string]bool), make(map[string]bool)
+	if fn == nil {
+		return
+	}
+	for _, bb := range fn.Blocks {
+		for _, inst := range bb.Instructions {
+			switch {
+			case inst.Op == core.OpLoad && len(inst.Operands) > 0 && !strings.ContainsAny(inst.Operands[0], ".*"):
+				refs[inst.Operands[0]] = true
+			case (inst.Op == core.OpStore || inst.Op == core.OpParam) && inst.Result != "" && !strings.ContainsAny(inst.Result, ".*"):
+				refs[inst.Result] = true
+				stores[inst.Result] = true
+			}
+		}
+	}
+	return
+}
+
+// constStrings runs constant propagation on function name and returns the
+// operands, temporaries and variables, that hold a known string
+func constStrings(prog *core.ProgramIR, name string) map[string]string {
+	cp := newConstProp(prog.Functions[name], sharedVariables(prog, name))
+	cp.run()
+	strs := make(map[string]string)
+	for name, c := range cp.values {
+		if s, ok := c.val.(string); ok && c.state == latticeConst {
+			strs[name] = s
+		}
+	}
+	return strs
+}
+
+type constProp struct {
+	fn        *core.FunctionIR
+	shared    map[string]bool // Variables shared with closures
+	values    map[string]cell
+	defined   map[string]bool                // Results of some instruction in the function
+	stores    map[string][]*core.Instruction // Variable -> its stores and parameter definitions
+	uses      map[string][]*core.Instruction
+	blockOf   map[*core.Instruction]*core.BasicBlock
+	indexOf   map[*core.Instruction]int
+	execBlock map[string]bool
+	execEdge  map[[2]string]bool
+	flowWork  [][2]string
+	ssaWork   []*core.Instruction
+}
+
+func newConstProp(fn *core.FunctionIR, shared map[string]bool) *constProp {
+	cp := &constProp{
+		fn:        fn,
+		shared:    shared,
+		values:    make(map[string]cell),
+		defined:   make(map[string]bool),
+		stores:    make(map[string][]*core.Instruction),
+		uses:      make(map[string][]*core.Instruction),
+		blockOf:   make(map[*core.Instruction]*core.BasicBlock),
+		indexOf:   make(map[*core.Instruction]int),
+		execBlock: make(map[string]bool),
+		execEdge:  make(map[[2]string]bool),
+	}
+	for _, id := range fn.BlockIDs() {
+		bb := fn.Blocks[id]
+		for i, inst := range bb.Instructions {
+			cp.blockOf[inst] = bb
+			cp.indexOf[inst] = i
+			if inst.Result != "" {
+				cp.defined[inst.Result] = true
+			}
+			if (inst.Op == core.OpStore || inst.Op == core.OpParam) && !strings.ContainsAny(inst.Result, ".*") {
+				cp.stores[inst.Result] = append(cp.stores[inst.Result], inst)
+			}
+			for _, op := range inst.Operands {
+				cp.uses[op] = append(cp.uses[op], inst)
+			}
+		}
+	}
+	return cp
+}
+
+// run propagates to a fixed point, marks the function and returns its number of dead blocks
+func (cp *constProp) run() int {
+	for _, bb := range cp.fn.Blocks {
+		bb.Unreachable = false
+		bb.DeadSuccessors = nil
+	}
+	if cp.fn.Blocks[cp.fn.Entry] == nil {
+		return 0
+	}
+	cp.markUninitialized()
+
+	cp.flowWork = append(cp.flowWork, [2]string{"", cp.fn.Entry})
+	for len(cp.flowWork) > 0 || len(cp.ssaWork) > 0 {
+		if len(cp.flowWork) > 0 {
+			edge := cp.flowWork[0]
+			cp.flowWork = cp.flowWork[1:]
+			if cp.execEdge[edge] {
+				continue
+			}
+			cp.execEdge[edge] = true
+			if cp.execBlock[edge[1]] {
+				continue
+			}
+			bb := cp.fn.Blocks[edge[1]]
+			if bb == nil {
+				// A successor or branch target naming no block of the function
+				continue
+			}
+			cp.execBlock[edge[1]] = true
+			for _, inst := range bb.Instructions {
+				cp.visit(inst)
+			}
+			cp.visitEdges(bb)
+			continue
+		}
+		inst := cp.ssaWork[0]
+		cp.ssaWork = cp.ssaWork[1:]
+		if cp.execBlock[cp.blockOf[inst].ID] {
+			cp.visit(inst)
+		}
+	}
+
+	dead := 0
+	for _, id := range cp.fn.BlockIDs() {
+		bb := cp.fn.Blocks[id]
+		if !cp.execBlock[id] {
+			bb.Unreachable = true
+			dead++
+			continue
+		}
+		for _, succ := range bb.Successors {
+			if !cp.execEdge[[2]string{id, succ}] {
+				bb.DeadSuccessors = append(bb.DeadSuccessors, succ)
+			}
+		}
+	}
+	return dead
+}
+
+// markUninitialized sends to bottom every variable with a load that no store
+// dominates, every variable whose address is taken and those shared with closures
+func (cp *constProp) markUninitialized() {
+	for v := range cp.shared {
+		cp.values[v] = bottomCell
+	}
+	loaded := make(map[string]string) // Temporary -> variable it was loaded from
+	for inst := range cp.blockOf {
+		if inst.Op == core.OpLoad && len(inst.Operands) > 0 {
+			loaded[inst.Result] = inst.Operands[0]
+		}
+	}
+	for inst := range cp.blockOf {
+		if inst.Op == core.OpBinOp && len(inst.Operands) == 2 && inst.Operands[0] == "&" {
+			if v, ok := loaded[inst.Operands[1]]; ok {
+				cp.values[v] = bottomCell
+			}
+		}
+	}
+
+	dom := cp.fn.Dominators()
+	for v, defs := range cp.stores {
+		for _, use := range cp.uses[v] {
+			if use.Op != core.OpLoad || use.Operands[0] != v {
+				continue
+			}
+			covered := false
+			for _, d := range defs {
+				db, ub := cp.blockOf[d], cp.blockOf[use]
+				if (db == ub && cp.indexOf[d] < cp.indexOf[use]) || (db != ub && dom[ub.ID][db.ID]) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				cp.values[v] = bottomCell
+				break
+			}
+		}
+	}
+}
+
+func (cp *constProp) visit(inst *core.Instruction) {
+	switch inst.Op {
+	case core.OpBranch:
+		cp.visitEdges(cp.blockOf[inst])
+	case core.OpStore, core.OpParam:
+		v := cell{}
+		for _, d := range cp.stores[inst.Result] {
+			if !cp.execBlock[cp.blockOf[d].ID] {
+				continue
+			}
+			if d.Op == core.OpParam || len(d.Operands) == 0 {
+				v = bottomCell
+				break
+			}
+			v = meet(v, cp.value(d.Operands[0]))
+		}
+		cp.update(inst.Result, v)
+	default:
+		if inst.Result != "" {
+			cp.update(inst.Result, cp.eval(inst))
+		}
+	}
+}
+
+// visitEdges makes the successors of bb executable; a branch on a known condition takes one side
+func (cp *constProp) visitEdges(bb *core.BasicBlock) {
+	if n := len(bb.Instructions); n > 0 {
+		last := bb.Instructions[n-1]
+		if last.Op == core.OpBranch && len(last.Operands) == 3 {
+			c := cp.value(last.Operands[0])
+			if c.state == latticeTop {
+				return
+			}
+			if b, ok := c.val.(bool); ok && c.state == latticeConst {
+				target := last.Operands[2]
+				if b {
+					target = last.Operands[1]
+				}
+				// A target that is not a successor of the block is malformed IR: take every successor
+				if slices.Contains(bb.Successors, target) {
+					cp.flowWork = append(cp.flowWork, [2]string{bb.ID, target})
+					return
+				}
+			}
+		}
+	}
+	for _, succ := range bb.Successors {
+		cp.flowWork = append(cp.flowWork, [2]string{bb.ID, succ})
+	}
+}
+
+// update lowers the value of name and revisits its uses when it changed
+func (cp *constProp) update(name string, c cell) {
+	old, ok := cp.values[name]
+	next := meet(old, c)
+	if ok && next == old {
+		return
+	}
+	cp.values[name] = next
+	cp.ssaWork = append(cp.ssaWork, cp.uses[name]...)
+}
+
+// value is the lattice value of an operand; operands defined nowhere in the
+// function are literals (as in bytecode comparisons against 0) or unknown
+func (cp *constProp) value(op string) cell {
+	if c, ok := cp.values[op]; ok {
+		return c
+	}
+	if cp.defined[op] {
+		return cell{}
+	}
+	return parseLiteral(op)
+}
+
+func (cp *constProp) eval(inst *core.Instruction) cell {
+	ops := inst.Operands
+	switch inst.Op {
+	case core.OpConst:
+		if len(ops) > 0 {
+			return parseLiteral(ops[0])
+		}
+	case core.OpLoad:
+		// Only whole variables (and true/false); loads of temporaries are field or element reads
+		if len(ops) > 0 && !strings.ContainsAny(ops[0], ".*") && (len(cp.stores[ops[0]]) > 0 || !cp.defined[ops[0]]) {
+			return cp.value(ops[0])
+		}
+	case core.OpPhi:
+		c := cell{}
+		for _, op := range ops {
+			c = meet(c, cp.value(op))
+		}
+		return c
+	case core.OpBinOp:
+		if len(ops) == 2 {
+			return foldUnary(ops[0], cp.value(ops[1]))
+		}
+		if len(ops) == 3 {
+			return foldBinary(cp.value(ops[0]), ops[1], cp.value(ops[2]))
+		}
+	}
+	return bottomCell
+}
+
+// parseLiteral reads an int, bool or double-quoted string literal as written by the frontends
+func parseLiteral(s string) cell {
+	switch s {
+	case "true":
+		return constCell(true)
+	case "false":
+		return constCell(false)
+	}
+	if n, err := strconv.ParseInt(strings.TrimRight(s, "lL"), 0, 64); err == nil && s != "" {
+		return constCell(n)
+	}
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '`') {
+		if str, err := strconv.Unquote(s); err == nil {
+			return constCell(str)
+		}
+	}
+	return bottomCell
+}
+
+func foldUnary(op string, x cell) cell {
+	if x.state != latticeConst {
+		return x
+	}
+	switch v := x.val.(type) {
+	case bool:
+		if op == "!" {
+			return constCell(!v)
+		}
+	case int64:
+		if op == "-" {
+			return constCell(-v)
+		}
+	}
+	return bottomCell
+}
+
+func foldBinary(a cell, op string, b cell) cell {
+	// false && x and true || x are known whatever x is
+	for _, c := range []cell{a, b} {
+		if v, ok := c.val.(bool); ok && c.state == latticeConst && ((op == "&&" && !v) || (op == "||" && v)) {
+			return c
+		}
+	}
+	if a.state != latticeConst || b.state != latticeConst {
+		if a.state == latticeBottom || b.state == latticeBottom {
+			return bottomCell
+		}
+		return cell{}
+	}
+
+	// Values of different kinds (0 and false, say) come from frontends that
+	// disagree about types; comparing them would not tell anything
+	if op == "==" || op == "!=" {
+		if fmt.Sprintf("%T", a.val) != fmt.Sprintf("%T", b.val) {
+			return bottomCell
+		}
+		return constCell((a.val == b.val) == (op == "=="))
+	}
+	switch x := a.val.(type) {
+	case int64:
+		y, ok := b.val.(int64)
+		if !ok {
+			break
+		}
+		switch op {
+		case "+":
+			return constCell(x + y)
+		case "-":
+			return constCell(x - y)
+		case "*":
+			return constCell(x * y)
+		case "/", "%":
+			if y == 0 {
+				break
+			}
+			if op == "/" {
+				return constCell(x / y)
+			}
+			return constCell(x % y)
+		case "<":
+			return constCell(x < y)
+		case "<=":
+			return constCell(x <= y)
+		case ">":
+			return constCell(x > y)
+		case ">=":
+			return constCell(x >= y)
+		case "cmp":
+			switch {
+			case x < y:
+				return constCell(int64(-1))
+			case x > y:
+				return constCell(int64(1))
+			}
+			return constCell(int64(0))
+		}
+	case bool:
+		y, ok := b.val.(bool)
+		if !ok {
+			break
+		}
+		switch op {
+		case "&&":
+			return constCell(x && y)
+		case "||":
+			return constCell(x || y)
+		}
+	case string:
+		if y, ok := b.val.(string); ok && op == "+" {
+			return constCell(x + y)
+		}
+	}
+	return bottomCell
+}
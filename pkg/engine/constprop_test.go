@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+func TestDeadBranchFindings(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"constant false", `
+	debug := false
+	if debug {
+		exec.Command(r.URL.Query().Get("cmd")).Run()
+	}`, nil},
+		{"folded comparison", `
+	mode := 1 + 1
+	if mode == 3 {
+		exec.Command(r.URL.Query().Get("cmd")).Run()
+	}`, nil},
+		{"constant true", `
+	enabled := true
+	if enabled {
+		exec.Command(r.URL.Query().Get("cmd")).Run()
+	}`, []string{"rce@11->11"}},
+		// Calling the closure changes the variable before the branch
+		{"reassigned in closure", `
+	enabled := false
+	func() {
+		enabled = true
+	}()
+	if enabled {
+		exec.Command(r.URL.Query().Get("cmd")).Run()
+	}`, []string{"rce@14->14"}},
+		{"stored by closure argument", `
+	mode := 0
+	set := func(m int) { mode = m }
+	set(2)
+	if mode == 2 {
+		exec.Command(r.URL.Query().Get("cmd")).Run()
+	}`, []string{"rce@13->13"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package main\n\nimport (\n\t\"net/http\"\n\t\"os/exec\"\n)\n\nfunc handler(r *http.Request) {" + tt.body + "\n}\n"
+			if got := findings(scan(t, "main.go", src)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A closure's own copy of a constant is not one either when the parent shares it
+func TestSharedVariables(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", `package main
+
+func outer() {
+	n := 1
+	other := 2
+	f := func() {
+		n = 2
+	}
+	f()
+	println(n, other)
+}
+
+func unrelated() {
+	n := 1
+	println(n)
+}
+`)
+	for name, want := range map[string][]string{
+		"outer":       {"n"},
+		"outer.func1": {"n"},
+		"unrelated":   nil,
+	} {
+		var got []string
+		for v := range sharedVariables(prog, name) {
+			got = append(got, v)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s shares %v, want %v", name, got, want)
+		}
+	}
+}
+
+// Branch targets from an external frontend are not trusted to name successors
+func TestMalformedBranch(t *testing.T) {
+	block := func(id string, succs []string, insts ...*core.Instruction) *core.BasicBlock {
+		return &core.BasicBlock{ID: id, Successors: succs, Instructions: insts}
+	}
+	tests := []struct {
+		name   string
+		branch []string
+		succs  []string
+		dead   int
+	}{
+		{"unknown target", []string{"true", "0", "0"}, []string{"B1", "B2"}, 0},
+		{"known target", []string{"true", "B1", "B2"}, []string{"B1", "B2"}, 1},
+		{"target not a successor", []string{"true", "B2", "B1"}, []string{"B1"}, 1},
+		{"missing successor block", []string{"c", "B1", "B9"}, []string{"B1", "B9"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := &core.FunctionIR{Name: "f", Entry: "B0", Blocks: map[string]*core.BasicBlock{
+				"B0": block("B0", tt.succs, &core.Instruction{ID: "i1", Op: core.OpBranch, Operands: tt.branch}),
+				"B1": block("B1", nil),
+				"B2": block("B2", nil),
+			}}
+			prog := &core.ProgramIR{Functions: map[string]*core.FunctionIR{"f": fn}}
+			if dead := MarkDeadCode(prog); dead != tt.dead {
+				t.Errorf("%d dead blocks, want %d", dead, tt.dead)
+			}
+		})
+	}
+}
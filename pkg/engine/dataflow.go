@@ -0,0 +1,487 @@
+package engine
+
+import (
+	"fmt"
+	"sast-demo/pkg/core"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Intraprocedural dataflow analysis over basic blocks.
+//
+// A problem supplies a lattice (Top, Meet, Equal), the fact at the function's
+// boundary and a transfer function per instruction; the solver iterates the
+// block facts to a fixed point with a worklist. Forward problems flow from the
+// entry along Successors and meet over Predecessors; backward problems flow from
+// the exits the other way. Blocks and edges constant propagation proved dead are
+// left out, so run MarkDeadCode first to prune them.
+
+// Direction is the way facts flow through the CFG
+type Direction int
+
+const (
+	Forward  Direction = iota // From the entry, meeting over predecessors
+	Backward                  // From the exits, meeting over successors
+)
+
+func (d Direction) String() string {
+	if d == Backward {
+		return "backward"
+	}
+	return "forward"
+}
+
+// DataflowProblem is a monotone dataflow analysis of one function; F is its fact type
+type DataflowProblem[F any] interface {
+	Direction() Direction
+	// Boundary is the fact entering the entry block (forward) or leaving the exit blocks (backward)
+	Boundary() F
+	// Top is the fact every other block starts from, the identity of Meet: the
+	// empty set for a may analysis (union), every fact for a must analysis (intersection)
+	Top() F
+	Meet(a, b F) F
+	Equal(a, b F) bool
+	// Transfer maps the fact before inst to the fact after it (forward), or the
+	// fact after it to the fact before it (backward). It must not modify f.
+	Transfer(inst *core.Instruction, f F) F
+}
+
+// DataflowResult holds the fixed point of a problem: the facts before the first
+// and after the last instruction of every reachable block, in program order for
+// either direction
+type DataflowResult[F any] struct {
+	Func    *core.FunctionIR
+	Before  map[string]F // Block ID -> fact before its first instruction
+	After   map[string]F // Block ID -> fact after its last instruction
+	problem DataflowProblem[F]
+	blockOf map[*core.Instruction]*core.BasicBlock
+}
+
+// SolveDataflow computes the fixed point of a problem on fn
+func SolveDataflow[F any](fn *core.FunctionIR, p DataflowProblem[F]) *DataflowResult[F] {
+	r := &DataflowResult[F]{
+		Func:    fn,
+		Before:  make(map[string]F),
+		After:   make(map[string]F),
+		problem: p,
+		blockOf: make(map[*core.Instruction]*core.BasicBlock),
+	}
+	if fn.Blocks[fn.Entry] == nil {
+		return r
+	}
+
+	// in is the side facts flow into, out the side they leave
+	in, out := r.Before, r.After
+	flowPreds, flowSuccs := livePredecessors(fn), liveSuccessors(fn)
+	if p.Direction() == Backward {
+		in, out = r.After, r.Before
+		flowPreds, flowSuccs = flowSuccs, flowPreds
+	}
+
+	var worklist []string
+	queued := make(map[string]bool)
+	for _, id := range fn.BlockIDs() {
+		bb := fn.Blocks[id]
+		if bb.Unreachable {
+			continue
+		}
+		for _, inst := range bb.Instructions {
+			r.blockOf[inst] = bb
+		}
+		out[id] = p.Top()
+		worklist = append(worklist, id)
+		queued[id] = true
+	}
+	if p.Direction() == Backward {
+		slices.Reverse(worklist)
+	}
+
+	for len(worklist) > 0 {
+		id := worklist[0]
+		worklist = worklist[1:]
+		queued[id] = false
+
+		fact := p.Top()
+		if p.Direction() == Forward && id == fn.Entry || p.Direction() == Backward && len(flowPreds[id]) == 0 {
+			fact = p.Boundary()
+		}
+		for _, pred := range flowPreds[id] {
+			fact = p.Meet(fact, out[pred])
+		}
+		in[id] = fact
+		next := r.transferBlock(fn.Blocks[id], fact)
+		if p.Equal(next, out[id]) {
+			continue
+		}
+		out[id] = next
+		for _, succ := range flowSuccs[id] {
+			if !queued[succ] {
+				queued[succ] = true
+				worklist = append(worklist, succ)
+			}
+		}
+	}
+	return r
+}
+
+// transferBlock applies the transfer function of every instruction of bb in flow order
+func (r *DataflowResult[F]) transferBlock(bb *core.BasicBlock, f F) F {
+	if r.problem.Direction() == Backward {
+		for i := len(bb.Instructions) - 1; i >= 0; i-- {
+			f = r.problem.Transfer(bb.Instructions[i], f)
+		}
+		return f
+	}
+	for _, inst := range bb.Instructions {
+		f = r.problem.Transfer(inst, f)
+	}
+	return f
+}
+
+// FactBefore returns the fact right before inst, replaying its block; ok is false
+// for instructions of unreachable blocks
+func (r *DataflowResult[F]) FactBefore(inst *core.Instruction) (f F, ok bool) {
+	before, _, ok := r.around(inst)
+	return before, ok
+}
+
+// FactAfter returns the fact right after inst, replaying its block
+func (r *DataflowResult[F]) FactAfter(inst *core.Instruction) (f F, ok bool) {
+	_, after, ok := r.around(inst)
+	return after, ok
+}
+
+func (r *DataflowResult[F]) around(inst *core.Instruction) (before, after F, ok bool) {
+	bb := r.blockOf[inst]
+	if bb == nil {
+		return before, after, false
+	}
+	if r.problem.Direction() == Backward {
+		after = r.After[bb.ID]
+		for i := len(bb.Instructions) - 1; i >= 0; i-- {
+			before = r.problem.Transfer(bb.Instructions[i], after)
+			if bb.Instructions[i] == inst {
+				return before, after, true
+			}
+			after = before
+		}
+		return before, after, false
+	}
+	before = r.Before[bb.ID]
+	for _, i := range bb.Instructions {
+		after = r.problem.Transfer(i, before)
+		if i == inst {
+			return before, after, true
+		}
+		before = after
+	}
+	return before, after, false
+}
+
+// liveSuccessors maps each reachable block to the successors it can branch to
+func liveSuccessors(fn *core.FunctionIR) map[string][]string {
+	succs := make(map[string][]string)
+	for id, bb := range fn.Blocks {
+		if bb.Unreachable {
+			continue
+		}
+		for _, succ := range bb.Successors {
+			if s := fn.Blocks[succ]; s != nil && !s.Unreachable && !slices.Contains(bb.DeadSuccessors, succ) {
+				succs[id] = append(succs[id], succ)
+			}
+		}
+	}
+	return succs
+}
+
+// livePredecessors maps each reachable block to the predecessors that can branch to it
+func livePredecessors(fn *core.FunctionIR) map[string][]string {
+	preds := make(map[string][]string)
+	for id, bb := range fn.Blocks {
+		if bb.Unreachable {
+			continue
+		}
+		for _, pred := range bb.Predecessors {
+			if p := fn.Blocks[pred]; p != nil && !p.Unreachable && !slices.Contains(p.DeadSuccessors, id) {
+				preds[id] = append(preds[id], pred)
+			}
+		}
+	}
+	return preds
+}
+
+// FactSet is a set of facts named by strings, the fact type of the built-in analyses
+type FactSet map[string]bool
+
+// Sorted lists the facts in order
+func (s FactSet) Sorted() []string {
+	facts := make([]string, 0, len(s))
+	for f := range s {
+		facts = append(facts, f)
+	}
+	sort.Strings(facts)
+	return facts
+}
+
+func (s FactSet) union(o FactSet) FactSet {
+	u := make(FactSet, len(s)+len(o))
+	for f := range s {
+		u[f] = true
+	}
+	for f := range o {
+		u[f] = true
+	}
+	return u
+}
+
+func (s FactSet) equal(o FactSet) bool {
+	if len(s) != len(o) {
+		return false
+	}
+	for f := range s {
+		if !o[f] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s FactSet) clone() FactSet {
+	c := make(FactSet, len(s))
+	for f := range s {
+		c[f] = true
+	}
+	return c
+}
+
+// Liveness is the backward may analysis of the variables whose current value may
+// still be read. A fact is a variable, a name stored to or bound as a parameter;
+// temporaries are left out. Storing a field or through a pointer (`cfg.URL = x`,
+// `*p = x`) reads the variable rather than redefining it.
+type Liveness struct {
+	vars map[string]bool
+}
+
+// NewLiveness collects the variables of fn
+func NewLiveness(fn *core.FunctionIR) *Liveness {
+	return &Liveness{vars: variables(fn)}
+}
+
+func (*Liveness) Direction() Direction      { return Backward }
+func (*Liveness) Boundary() FactSet         { return FactSet{} }
+func (*Liveness) Top() FactSet              { return FactSet{} }
+func (*Liveness) Meet(a, b FactSet) FactSet { return a.union(b) }
+func (*Liveness) Equal(a, b FactSet) bool   { return a.equal(b) }
+func (l *Liveness) Transfer(inst *core.Instruction, live FactSet) FactSet {
+	next := live.clone()
+	if v := definedVar(inst); l.vars[v] {
+		delete(next, v)
+	}
+	for _, v := range readVars(inst) {
+		if l.vars[v] {
+			next[v] = true
+		}
+	}
+	return next
+}
+
+// ReachingDefinitions is the forward may analysis of the definitions that may
+// reach a point without being overwritten. A fact is the ID of a parameter or a
+// store defining a whole variable; temporaries are assigned once and left out.
+type ReachingDefinitions struct {
+	defs map[string][]string // Variable -> IDs of its definitions
+	inst map[string]*core.Instruction
+}
+
+// NewReachingDefinitions indexes the definitions of fn
+func NewReachingDefinitions(fn *core.FunctionIR) *ReachingDefinitions {
+	rd := &ReachingDefinitions{defs: make(map[string][]string), inst: make(map[string]*core.Instruction)}
+	for _, id := range fn.BlockIDs() {
+		for _, inst := range fn.Blocks[id].Instructions {
+			if v := storedVar(inst); v != "" {
+				rd.defs[v] = append(rd.defs[v], inst.ID)
+				rd.inst[inst.ID] = inst
+			}
+		}
+	}
+	return rd
+}
+
+func (*ReachingDefinitions) Direction() Direction      { return Forward }
+func (*ReachingDefinitions) Boundary() FactSet         { return FactSet{} }
+func (*ReachingDefinitions) Top() FactSet              { return FactSet{} }
+func (*ReachingDefinitions) Meet(a, b FactSet) FactSet { return a.union(b) }
+func (*ReachingDefinitions) Equal(a, b FactSet) bool   { return a.equal(b) }
+func (rd *ReachingDefinitions) Transfer(inst *core.Instruction, reaching FactSet) FactSet {
+	v := storedVar(inst)
+	if v == "" {
+		return reaching
+	}
+	next := reaching.clone()
+	for _, id := range rd.defs[v] {
+		delete(next, id)
+	}
+	next[inst.ID] = true
+	return next
+}
+
+// label shows a definition as the variable and the line it is defined on
+func (rd *ReachingDefinitions) label(fact string) string {
+	if inst := rd.inst[fact]; inst != nil {
+		return fmt.Sprintf("%s@L%d", inst.Result, inst.Line)
+	}
+	return fact
+}
+
+// definedVar is the variable inst overwrites as a whole, "" if none
+func definedVar(inst *core.Instruction) string {
+	if inst.Result == "" || strings.ContainsAny(inst.Result, ".*") {
+		return ""
+	}
+	return inst.Result
+}
+
+// storedVar is the variable a store or parameter defines as a whole, "" if none
+func storedVar(inst *core.Instruction) string {
+	if inst.Op != core.OpStore && inst.Op != core.OpParam {
+		return ""
+	}
+	return definedVar(inst)
+}
+
+// variables are the names fn stores to or binds as parameters, as opposed to temporaries
+func variables(fn *core.FunctionIR) map[string]bool {
+	vars := make(map[string]bool)
+	for _, bb := range fn.Blocks {
+		for _, inst := range bb.Instructions {
+			if (inst.Op == core.OpStore || inst.Op == core.OpParam) && inst.Result != "" {
+				vars[pathRoot(inst.Result)] = true
+			}
+		}
+	}
+	return vars
+}
+
+// readVars lists the variables inst reads: the roots of its value operands, and
+// of its result when it stores a field or through a pointer (`cfg.URL`, `*p`)
+func readVars(inst *core.Instruction) []string {
+	ops := valueOperands(inst)
+	if inst.Result != "" && definedVar(inst) == "" {
+		ops = append(ops, inst.Result)
+	}
+	var vars []string
+	for _, op := range ops {
+		if v := pathRoot(strings.TrimPrefix(op, "&")); v != "" {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// valueOperands lists the operands and receiver inst reads as values, without
+// callees, branch targets, operators and literals
+func valueOperands(inst *core.Instruction) []string {
+	var ops []string
+	switch inst.Op {
+	case core.OpConst, core.OpJump:
+	case core.OpCall:
+		if len(inst.Operands) > 1 {
+			ops = inst.Operands[1:]
+		}
+	case core.OpBranch:
+		if len(inst.Operands) > 0 {
+			ops = inst.Operands[:1]
+		}
+	case core.OpBinOp:
+		switch len(inst.Operands) {
+		case 2:
+			ops = inst.Operands[1:]
+		case 3:
+			ops = []string{inst.Operands[0], inst.Operands[2]}
+		}
+	default:
+		ops = inst.Operands
+	}
+	if inst.Receiver != "" {
+		ops = append(ops, inst.Receiver)
+	}
+
+	var values []string
+	for _, op := range ops {
+		v := strings.TrimPrefix(strings.TrimPrefix(op, "&"), "*")
+		if v == "" || !isIdentStart(v[0]) || v == "true" || v == "false" || v == "nil" || v == "null" {
+			continue
+		}
+		values = append(values, op)
+	}
+	return values
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// DataflowView is the display form of a built-in analysis over a program: the
+// facts before and after every reachable block
+type DataflowView struct {
+	Analysis  string                            `json:"analysis"`
+	Direction string                            `json:"direction"`
+	Functions map[string]map[string]*BlockFacts `json:"functions"` // Function -> block ID -> facts
+}
+
+// BlockFacts are the facts at the two ends of a block, in program order
+type BlockFacts struct {
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// dataflowAnalyses are the built-in analyses by name
+var dataflowAnalyses = map[string]func(fn *core.FunctionIR) DataflowProblem[FactSet]{
+	"liveness":             func(fn *core.FunctionIR) DataflowProblem[FactSet] { return NewLiveness(fn) },
+	"reaching-definitions": func(fn *core.FunctionIR) DataflowProblem[FactSet] { return NewReachingDefinitions(fn) },
+}
+
+// DataflowAnalyses lists the names of the built-in analyses
+func DataflowAnalyses() []string {
+	var names []string
+	for name := range dataflowAnalyses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunDataflow runs a built-in analysis by name on every function of prog
+func RunDataflow(prog *core.ProgramIR, name string) (*DataflowView, error) {
+	newProblem, ok := dataflowAnalyses[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown dataflow analysis %q (available: %s)", name, strings.Join(DataflowAnalyses(), ", "))
+	}
+	view := &DataflowView{Analysis: name, Functions: make(map[string]map[string]*BlockFacts)}
+	for _, fnName := range prog.FunctionNames() {
+		fn := prog.Functions[fnName]
+		p := newProblem(fn)
+		view.Direction = p.Direction().String()
+		label := func(f string) string { return f }
+		if l, ok := p.(interface{ label(string) string }); ok {
+			label = l.label
+		}
+		render := func(s FactSet) []string {
+			facts := make([]string, 0, len(s))
+			for f := range s {
+				facts = append(facts, label(f))
+			}
+			sort.Strings(facts)
+			return facts
+		}
+
+		r := SolveDataflow(fn, p)
+		blocks := make(map[string]*BlockFacts)
+		for id, before := range r.Before {
+			blocks[id] = &BlockFacts{Before: render(before), After: render(r.After[id])}
+		}
+		view.Functions[fnName] = blocks
+	}
+	return view, nil
+}
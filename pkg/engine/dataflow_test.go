@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"sort"
+	"testing"
+)
+
+const dataflowSource = `package main
+
+func f(a int) int {
+	x := 1
+	y := a
+	if a > 0 {
+		x = 2
+	}
+	for i := 0; i < a; i++ {
+		y = y + x
+	}
+	if false {
+		y = 0
+	}
+	return y
+}
+`
+
+// instAt returns the first instruction of fn with op on line
+func instAt(t *testing.T, fn *core.FunctionIR, op core.OpCode, line int) *core.Instruction {
+	t.Helper()
+	for _, id := range fn.BlockIDs() {
+		for _, inst := range fn.Blocks[id].Instructions {
+			if inst.Op == op && inst.Line == line {
+				return inst
+			}
+		}
+	}
+	t.Fatalf("no %s on line %d", op, line)
+	return nil
+}
+
+func TestReachingDefinitions(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", dataflowSource)
+	MarkDeadCode(prog)
+	fn := prog.Functions["f"]
+	rd := NewReachingDefinitions(fn)
+	r := SolveDataflow(fn, DataflowProblem[FactSet](rd))
+
+	facts, ok := r.FactBefore(instAt(t, fn, core.OpRet, 15))
+	if !ok {
+		t.Fatal("return not reached")
+	}
+	var defs []string
+	for _, f := range facts.Sorted() {
+		if l := rd.label(f); l[0] == 'x' || l[0] == 'y' {
+			defs = append(defs, l)
+		}
+	}
+	sort.Strings(defs)
+	// Both definitions of x and y reach the return through the branch and the
+	// loop's back edge; y = 0 is in a dead block
+	want := []string{"x@L4", "x@L7", "y@L10", "y@L5"}
+	if !reflect.DeepEqual(defs, want) {
+		t.Errorf("definitions reaching the return %v, want %v", defs, want)
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", dataflowSource)
+	MarkDeadCode(prog)
+	fn := prog.Functions["f"]
+	r := SolveDataflow(fn, DataflowProblem[FactSet](NewLiveness(fn)))
+
+	tests := []struct {
+		inst   *core.Instruction
+		before bool
+		want   []string
+	}{
+		// x := 1 is overwritten or read later, so x is live after it but not before
+		{instAt(t, fn, core.OpStore, 4), true, []string{"a"}},
+		{instAt(t, fn, core.OpStore, 4), false, []string{"a", "x"}},
+		// Reaching the loop without x = 2 still reads x
+		{instAt(t, fn, core.OpStore, 7), true, []string{"a", "y"}},
+		{instAt(t, fn, core.OpLoad, 15), true, []string{"y"}},
+	}
+	for _, tt := range tests {
+		get := r.FactAfter
+		if tt.before {
+			get = r.FactBefore
+		}
+		facts, ok := get(tt.inst)
+		if !ok {
+			t.Fatalf("%s not reached", tt.inst.Code)
+		}
+		if got := facts.Sorted(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("live (before %v) %s at line %d: %v, want %v", tt.before, tt.inst.Code, tt.inst.Line, got, tt.want)
+		}
+	}
+}
+
+func TestRunDataflow(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", dataflowSource)
+	view, err := RunDataflow(prog, "liveness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Direction != "backward" || len(view.Functions["f"]) == 0 {
+		t.Errorf("view %s with %d blocks of f", view.Direction, len(view.Functions["f"]))
+	}
+	if _, err := RunDataflow(prog, "available-expressions"); err == nil {
+		t.Error("an unknown analysis was accepted")
+	}
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"regexp"
+	"sast-demo/pkg/core"
+)
+
+// Container elements.
+//
+// Frontends name the elements of slices, arrays and maps by access paths below
+// the container (see core.ElemField): s.[] for an unknown index and s.[].[0] for
+// a constant one. Every analysis then treats elements like fields, so a tainted
+// element taints reads of the container and of its index, and a container tainted
+// as a whole (os.Args) taints all of its elements. With ElementsWhole the
+// constant indexes are dropped before the analyses run, and all elements are one.
+
+var elemKeyPath = regexp.MustCompile(`\.\[\]\.\[[^.\[\]]*\]`)
+
+// mergeElementKeys rewrites the element paths of prog to the whole container's
+func mergeElementKeys(prog *core.ProgramIR) {
+	merge := func(s string) string {
+		return elemKeyPath.ReplaceAllLiteralString(s, "."+core.ElemField)
+	}
+	for _, fn := range prog.Functions {
+		for _, bb := range fn.Blocks {
+			for _, inst := range bb.Instructions {
+				inst.Result = merge(inst.Result)
+				for i, op := range inst.Operands {
+					inst.Operands[i] = merge(op)
+				}
+				inst.Code = merge(inst.Code)
+			}
+		}
+	}
+}
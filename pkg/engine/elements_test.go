@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+const elementSource = `package main
+
+import (
+	"net/http"
+	"os/exec"
+)
+
+func slices(r *http.Request, i int) {
+	args := []string{"ls", "-l"}
+	args[0] = r.URL.Query().Get("cmd")
+	exec.Command(args[1]).Run()
+	exec.Command(args[0]).Run()
+	exec.Command(args[i]).Run()
+}
+
+func maps(r *http.Request, key string) {
+	m := map[string]string{}
+	m["cmd"] = r.URL.Query().Get("cmd")
+	exec.Command(m["dir"]).Run()
+	exec.Command(m[key]).Run()
+}
+`
+
+// Elements at distinct constant indexes are kept apart unless ElementsWhole merges them
+func TestElements(t *testing.T) {
+	tests := []struct {
+		precision string
+		want      []string
+	}{
+		{ElementsByKey, []string{"rce@10->12", "rce@10->13", "rce@18->20"}},
+		{ElementsWhole, []string{"rce@10->11", "rce@10->12", "rce@10->13", "rce@18->19", "rce@18->20"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.precision, func(t *testing.T) {
+			vulns := scan(t, "main.go", elementSource, func(c *Config) { c.ElementPrecision = tt.precision })
+			if got := findings(vulns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
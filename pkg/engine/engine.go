@@ -1,12 +1,28 @@
 package engine
 
 import (
+	"fmt"
 	"regexp"
 	"sast-demo/pkg/core"
+	"sort"
+	"strings"
 )
 
 type Engine struct {
 	Config Config
+	Stats  Stats // Counters of the last AnalyzeIR run
+}
+
+// Stats summarizes the taint solving of an analysis run
+type Stats struct {
+	NodesExplored   int // Exploded nodes (instruction, fact) the solver processed
+	BudgetExhausted int // Solves that stopped at PathBudget
+	Guarded         int // Findings suppressed because a validator guards the sink
+	DeadBlocks      int // Blocks constant propagation proved unreachable
+	Summaries       int // Callee solves computed
+	SummariesReused int // Calls answered from an already computed callee solve
+	StoredFlows     int // Findings through a write to and a read from a store
+	ImplicitFlows   int // Findings of the implicit rules
 }
 
 func NewEngine(cfg Config) *Engine {
@@ -60,9 +76,18 @@ func (e *Engine) AnalyzeLegacy(graph *core.Graph) []core.Vulnerability {
 func (e *Engine) AnalyzeIR(prog *core.ProgramIR, filePath string) []core.Vulnerability {
 	var vulns []core.Vulnerability
 
+	if e.Config.ElementPrecision == ElementsWhole {
+		mergeElementKeys(prog)
+	}
+
+	// 0. Prune branches whose condition is a known constant; nothing in a dead
+	// block is a source, a sink or a step of a path
+	deadBlocks := MarkDeadCode(prog)
+	pointsTo := AnalyzePointsTo(prog)
+
 	// 1. Build Use-Def chains
-	// Map: VariableName -> [Instructions that use it]
-	useMap := make(map[string][]*core.Instruction)
+	// Access path -> [Instructions that use it]
+	uses := newUseIndex(e.Config.AccessPathDepth, pointsTo, newScopes(prog))
 	// Map: InstructionID -> BlockID
 	instToBlock := make(map[string]string)
 	// Map: InstructionID -> FunctionName
@@ -70,54 +95,189 @@ func (e *Engine) AnalyzeIR(prog *core.ProgramIR, filePath string) []core.Vulnera
 	// List of all instructions for linear scanning
 	var allInsts []*core.Instruction
 
-	for _, fn := range prog.Functions {
-		for _, bb := range fn.Blocks {
+	// Functions and blocks are maps; walk them in a fixed order so that use lists,
+	// and therefore the paths found, are the same on every run
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		for _, id := range fn.BlockIDs() {
+			bb := fn.Blocks[id]
+			if bb.Unreachable {
+				continue
+			}
 			for _, inst := range bb.Instructions {
 				allInsts = append(allInsts, inst)
 				instToBlock[inst.ID] = bb.ID
 				instToFunc[inst.ID] = fn.Name
-				for _, op := range inst.Operands {
-					useMap[op] = append(useMap[op], inst)
-				}
+				uses.add(name, inst)
 			}
 		}
 	}
+	depth := e.Config.ContextDepth
+	if depth < 1 {
+		depth = DefaultContextDepth
+	}
+	budget := e.Config.PathBudget
+	if budget < 1 {
+		budget = DefaultPathBudget
+	}
+	k := e.Config.PathsPerSink
+	if k < 1 {
+		k = 1
+	}
+
+	// Taint propagation does not depend on the rule, so each source is solved
+	// once; rules only differ in the sinks looked for in the solution
+	icfg := NewICFG(prog, pointsTo)
+	flows := newTaintFlows(uses, prog, e.Config.OutParams)
+	solver := NewSolver(icfg, flows, depth, budget, k+2)
+	solved := make(map[*core.Instruction]*Solution)
+	solve := func(source *core.Instruction) *Solution {
+		sol := solved[source]
+		if sol == nil {
+			sol = solver.Solve(taintSeeds(icfg, source, uses.depth))
+			solved[source] = sol
+		}
+		return sol
+	}
+	stores := e.newStoreModel(prog, flows, allInsts, instToFunc)
+	strs := e.newStringModel(prog, instToFunc)
+
+	e.Stats = Stats{DeadBlocks: deadBlocks}
+	guards := newGuardFinder(prog, instToBlock, instToFunc)
+	labeler := e.newSourceLabeler()
 
 	// 2. Scan for Vulnerabilities
 	for _, rule := range e.Config.Rules {
 		sourceRegexes := e.compileRegexes(rule.Sources)
-		sinkRegexes := e.compileRegexes(rule.Sinks)
+		validators := e.compileRegexes(rule.Validators)
+		sinks := e.newSinkMatcher(flows, rule)
+		ruleStart := len(vulns)
 
 		for _, inst := range allInsts {
 			// Check if instruction is a Source
 			// We check the full code string or just the function call part
-			if e.matchesAny(inst.Code, sourceRegexes) {
-				// Start Taint Tracking
-				if path := e.findPathToSinkIR(inst, sinkRegexes, useMap); path != nil {
-					// Validate Control Flow (CFG Reachability)
-					if !e.validatePath(path, prog, instToBlock, instToFunc) {
-						continue
-					}
-
-					sinkInst := path[len(path)-1]
-					vulns = append(vulns, core.Vulnerability{
-						Type:        rule.Name,
-						Severity:    rule.Severity,
-						File:        filePath,
-						Line:        inst.Line,
-						Description: rule.Description,
-						Source:      e.instToNode(inst, filePath, instToBlock, instToFunc),
-						Sink:        e.instToNode(sinkInst, filePath, instToBlock, instToFunc),
-						Path:        e.pathInstToNode(path, filePath, instToBlock, instToFunc),
-					})
+			labels := labeler.labels(inst)
+			if !e.isRuleSource(rule, sourceRegexes, inst, labels) {
+				continue
+			}
+			sol := solve(inst)
+
+			// Guarded paths are set aside: the sink is only reported if some other path is unguarded
+			var guardedSinks []*core.Instruction
+			guarded := make(map[string]*guardedPath)
+			accept := func(path []*core.Instruction) bool {
+				g := guards.find(path, validators)
+				if g == nil {
+					return true
+				}
+				sink := path[len(path)-1]
+				if guarded[sink.ID] == nil {
+					guarded[sink.ID] = &guardedPath{path: path, guard: g}
+					guardedSinks = append(guardedSinks, sink)
 				}
+				return false
+			}
+			found := sinks.withLabels(labels).findSinks(sol, k, accept)
+			reported := make(map[string]bool)
+			for _, sp := range found {
+				reported[sp.sink.ID] = true
+				vuln := core.Vulnerability{
+					Type:        rule.Name,
+					RuleID:      rule.ID,
+					Severity:    rule.Severity,
+					File:        filePath,
+					Line:        inst.Line,
+					Description: rule.Description,
+					Source:      e.instToNode(inst, filePath, instToBlock, instToFunc),
+					Sink:        e.instToNode(sp.sink, filePath, instToBlock, instToFunc),
+					Path:        e.pathInstToNode(sp.paths[0], filePath, instToBlock, instToFunc),
+					Labels:      labels,
+				}
+				for _, alt := range sp.paths[1:] {
+					vuln.AlternatePaths = append(vuln.AlternatePaths, e.pathInstToNode(alt, filePath, instToBlock, instToFunc))
+				}
+				strs.describeString(&vuln, rule, sp.paths[0])
+				vulns = append(vulns, vuln)
+			}
+
+			for _, sink := range guardedSinks {
+				if reported[sink.ID] {
+					continue
+				}
+				gp := guarded[sink.ID]
+				callee := gp.guard.call.Operands[0]
+				vuln := core.Vulnerability{
+					Type:        rule.Name,
+					RuleID:      rule.ID,
+					Severity:    rule.Severity,
+					File:        filePath,
+					Line:        inst.Line,
+					Description: rule.Description,
+					Source:      e.instToNode(inst, filePath, instToBlock, instToFunc),
+					Sink:        e.instToNode(sink, filePath, instToBlock, instToFunc),
+					Path:        e.pathInstToNode(gp.path, filePath, instToBlock, instToFunc),
+					Labels:      labels,
+					Suppression: &core.SuppressionInfo{
+						Kind:   "guard",
+						Reason: fmt.Sprintf("sink only runs after %s succeeds", callee),
+						Line:   gp.guard.branch.Line,
+					},
+					Guard: e.instToNode(gp.guard.call, filePath, instToBlock, instToFunc),
+				}
+				strs.describeString(&vuln, rule, gp.path)
+				vulns = append(vulns, vuln)
+				e.Stats.Guarded++
+			}
+
+			// Second-order: the taint written to a store comes back from its reads
+			storedLabels := append(append([]string(nil), labels...), "stored")
+			sort.Strings(storedLabels)
+			unguarded := func(path []*core.Instruction) bool { return guards.find(path, validators) == nil }
+			for _, sf := range stores.secondOrder(sol, solve, sinks.withLabels(storedLabels), unguarded) {
+				vuln := core.Vulnerability{
+					Type:        "Stored " + rule.Name,
+					RuleID:      rule.ID,
+					Severity:    rule.Severity,
+					File:        filePath,
+					Line:        inst.Line,
+					Description: rule.Description + ", after a round trip through a " + sf.kind + " store",
+					Source:      e.instToNode(inst, filePath, instToBlock, instToFunc),
+					Sink:        e.instToNode(sf.sink, filePath, instToBlock, instToFunc),
+					Path:        e.pathInstToNode(sf.path, filePath, instToBlock, instToFunc),
+					Labels:      storedLabels,
+					Stored: &core.StoredFlow{
+						Kind:  sf.kind,
+						Key:   sf.key,
+						Write: e.instToNode(sf.write, filePath, instToBlock, instToFunc),
+						Read:  e.instToNode(sf.read, filePath, instToBlock, instToFunc),
+					},
+				}
+				strs.describeString(&vuln, rule, sf.path)
+				vulns = append(vulns, vuln)
+				e.Stats.StoredFlows++
 			}
 		}
+		mergeSinkLabels(vulns[ruleStart:])
+	}
+
+	if e.Config.ImplicitFlows && len(e.Config.ImplicitRules) > 0 {
+		pdg := BuildPDG(prog, pointsTo)
+		vulns = append(vulns, e.analyzeImplicitFlows(pdg, allInsts, labeler, filePath, instToBlock, instToFunc)...)
 	}
 
+	e.Stats.NodesExplored = solver.NodesProcessed
+	e.Stats.BudgetExhausted = solver.Truncated
+	e.Stats.Summaries = solver.Summaries
+	e.Stats.SummariesReused = solver.SummariesReused
 	return vulns
 }
 
+// guardedPath is a taint path whose sink is protected by a validation
+type guardedPath struct {
+	path  []*core.Instruction
+	guard *guard
+}
+
 func (e *Engine) compileRegexes(patterns []string) []*regexp.Regexp {
 	var regexes []*regexp.Regexp
 	for _, s := range patterns {
@@ -128,6 +288,51 @@ func (e *Engine) compileRegexes(patterns []string) []*regexp.Regexp {
 	return regexes
 }
 
+// callSink is a SinkSpec with its callee pattern compiled
+type callSink struct {
+	callee *regexp.Regexp
+	spec   SinkSpec
+}
+
+func (e *Engine) compileCallSinks(specs []SinkSpec) []callSink {
+	var sinks []callSink
+	for _, s := range specs {
+		if r, err := regexp.Compile(s.Callee); err == nil {
+			sinks = append(sinks, callSink{callee: r, spec: s})
+		}
+	}
+	return sinks
+}
+
+// hitsCallSink reports whether a tainted value used by inst lands in one of the sink positions
+func (e *Engine) hitsCallSink(inst *core.Instruction, value string, sinks []callSink) bool {
+	if inst.Op != core.OpCall || len(inst.Operands) == 0 {
+		return false
+	}
+	args := inst.Operands[1:]
+	for _, s := range sinks {
+		if !s.callee.MatchString(inst.Operands[0]) {
+			continue
+		}
+		if s.spec.Receiver && inst.Receiver == value {
+			return true
+		}
+		if len(s.spec.Args) == 0 && !s.spec.Receiver {
+			for _, a := range args {
+				if a == value {
+					return true
+				}
+			}
+		}
+		for _, idx := range s.spec.Args {
+			if idx >= 0 && idx < len(args) && args[idx] == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (e *Engine) matchesAny(s string, regexes []*regexp.Regexp) bool {
 	for _, r := range regexes {
 		if r.MatchString(s) {
@@ -166,37 +371,21 @@ func (e *Engine) findPathToSinkLegacy(g *core.Graph, start *core.Node, sinkRegex
 	return nil
 }
 
-func (e *Engine) findPathToSinkIR(start *core.Instruction, sinkRegexes []*regexp.Regexp, useMap map[string][]*core.Instruction) []*core.Instruction {
-	queue := [][]*core.Instruction{{start}}
-	visited := make(map[string]bool)
-	visited[start.ID] = true
-
-	for len(queue) > 0 {
-		path := queue[0]
-		queue = queue[1:]
-		curr := path[len(path)-1]
-
-		// Check sink
-		if len(path) > 1 && e.matchesAny(curr.Code, sinkRegexes) {
-			return path
+func onPath(path []*core.Instruction, inst *core.Instruction) bool {
+	for _, p := range path {
+		if p.ID == inst.ID {
+			return true
 		}
+	}
+	return false
+}
 
-		// Propagate taint: If curr produces a result, find all instructions that use it
-		if curr.Result != "" {
-			if users, ok := useMap[curr.Result]; ok {
-				for _, nextInst := range users {
-					if !visited[nextInst.ID] {
-						visited[nextInst.ID] = true
-						newPath := make([]*core.Instruction, len(path))
-						copy(newPath, path)
-						newPath = append(newPath, nextInst)
-						queue = append(queue, newPath)
-					}
-				}
-			}
-		}
+func pathKey(path []*core.Instruction) string {
+	ids := make([]string, len(path))
+	for i, p := range path {
+		ids[i] = p.ID
 	}
-	return nil
+	return strings.Join(ids, ",")
 }
 
 func (e *Engine) instToNode(i *core.Instruction, file string, instToBlock map[string]string, instToFunc map[string]string) *core.Node {
@@ -218,89 +407,3 @@ func (e *Engine) pathInstToNode(path []*core.Instruction, file string, instToBlo
 	}
 	return nodes
 }
-
-// validatePath checks if the taint path is valid according to the CFG
-func (e *Engine) validatePath(path []*core.Instruction, prog *core.ProgramIR, instToBlock map[string]string, instToFunc map[string]string) bool {
-	for i := 0; i < len(path)-1; i++ {
-		curr := path[i]
-		next := path[i+1]
-
-		fName1 := instToFunc[curr.ID]
-		fName2 := instToFunc[next.ID]
-
-		// Skip inter-procedural checks for now
-		if fName1 != fName2 {
-			continue
-		}
-
-		fn := prog.Functions[fName1]
-		if fn == nil {
-			continue
-		}
-
-		b1ID := instToBlock[curr.ID]
-		b2ID := instToBlock[next.ID]
-
-		if b1ID == b2ID {
-			// Same block: check order
-			block := fn.Blocks[b1ID]
-			if !e.isOrderedInBlock(curr, next, block) {
-				return false
-			}
-		} else {
-			// Different blocks: check CFG reachability
-			if !e.isReachable(b1ID, b2ID, fn) {
-				return false
-			}
-		}
-	}
-	return true
-}
-
-// isOrderedInBlock checks if a comes before b in the block
-func (e *Engine) isOrderedInBlock(a, b *core.Instruction, block *core.BasicBlock) bool {
-	foundA := false
-	for _, inst := range block.Instructions {
-		if inst.ID == a.ID {
-			foundA = true
-		}
-		if inst.ID == b.ID {
-			return foundA // If foundA is true, A is before B. If false, B is before A (invalid).
-		}
-	}
-	return false
-}
-
-// isReachable checks if startBlock can reach endBlock in the function CFG
-func (e *Engine) isReachable(startID, endID string, fn *core.FunctionIR) bool {
-	if startID == endID {
-		return true
-	}
-
-	visited := make(map[string]bool)
-	queue := []string{startID}
-	visited[startID] = true
-
-	for len(queue) > 0 {
-		currID := queue[0]
-		queue = queue[1:]
-
-		if currID == endID {
-			return true
-		}
-
-		currBlock, ok := fn.Blocks[currID]
-		if !ok {
-			continue
-		}
-
-		for _, succID := range currBlock.Successors {
-			if !visited[succID] {
-				visited[succID] = true
-				queue = append(queue, succID)
-			}
-		}
-	}
-
-	return false
-}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sast-demo/pkg/core"
+	"sort"
+	"strings"
+)
+
+var (
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+	// IR temporaries are renumbered whenever code above them changes
+	tempRegex = regexp.MustCompile(`\bt\d+\b`)
+)
+
+// normalizeCode reduces a code snippet to the parts that identify it independently of layout
+func normalizeCode(code string) string {
+	code = whitespaceRegex.ReplaceAllString(strings.TrimSpace(code), " ")
+	return tempRegex.ReplaceAllString(code, "t")
+}
+
+// SortVulnerabilities orders findings by file and location so output is comparable between runs
+func SortVulnerabilities(vulns []core.Vulnerability) {
+	sort.SliceStable(vulns, func(i, j int) bool {
+		a, b := vulns[i], vulns[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if as, bs := nodeLine(a.Sink), nodeLine(b.Sink); as != bs {
+			return as < bs
+		}
+		return nodeCode(a.Sink) < nodeCode(b.Sink)
+	})
+}
+
+// AssignFingerprints sets Fingerprint on every finding. The hash covers the rule ID
+// (not its display name, which may be reworded), whether the flow is second-order, the
+// file's path relative to root (the baseline or working directory; the file's
+// directory when empty), the
+// enclosing function and the normalized source and sink code, but no line numbers,
+// so unrelated edits above a finding keep its fingerprint while the same code in
+// another directory gets another one. Identical findings within a function are told
+// apart by their order of occurrence; call it after SortVulnerabilities.
+func AssignFingerprints(vulns []core.Vulnerability, root string) {
+	seen := make(map[string]int)
+	for i := range vulns {
+		v := &vulns[i]
+		fn := ""
+		if v.Sink != nil {
+			fn = v.Sink.Function
+		}
+		if fn == "" && v.Source != nil {
+			fn = v.Source.Function
+		}
+
+		rule := v.RuleID
+		if rule == "" {
+			rule = v.Type
+		}
+		if v.Stored != nil {
+			rule += "/stored"
+		}
+
+		h := sha256.New()
+		for _, part := range []string{
+			rule,
+			fingerprintPath(v.File, root),
+			fn,
+			normalizeCode(nodeCode(v.Source)),
+			normalizeCode(nodeCode(v.Sink)),
+		} {
+			h.Write([]byte(part))
+			h.Write([]byte{0})
+		}
+		base := hex.EncodeToString(h.Sum(nil))[:16]
+
+		seen[base]++
+		if n := seen[base]; n > 1 {
+			v.Fingerprint = fmt.Sprintf("%s-%d", base, n)
+		} else {
+			v.Fingerprint = base
+		}
+	}
+}
+
+// fingerprintPath is file relative to root, with forward slashes
+func fingerprintPath(file, root string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, file); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(file)
+}
+
+func nodeLine(n *core.Node) int {
+	if n == nil {
+		return 0
+	}
+	return n.Line
+}
+
+func nodeCode(n *core.Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.Code
+}
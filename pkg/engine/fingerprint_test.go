@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+func finding(file string, line int) core.Vulnerability {
+	return core.Vulnerability{
+		Type:   "SQL Injection",
+		RuleID: "sqli",
+		File:   file,
+		Line:   line,
+		Source: &core.Node{Line: line, Function: "handler", Code: `id := r.URL.Query().Get("id")`},
+		Sink:   &core.Node{Line: line + 1, Function: "handler", Code: "db.Query(q)"},
+	}
+}
+
+func fingerprint(v core.Vulnerability, root string) string {
+	vulns := []core.Vulnerability{v}
+	AssignFingerprints(vulns, root)
+	return vulns[0].Fingerprint
+}
+
+func TestFingerprintStability(t *testing.T) {
+	base := fingerprint(finding("/src/a/h.go", 10), "/src")
+	tests := []struct {
+		name string
+		edit func(v *core.Vulnerability)
+		same bool
+	}{
+		{"line shift", func(v *core.Vulnerability) { v.Line += 5; v.Source.Line += 5; v.Sink.Line += 5 }, true},
+		{"reformatted code", func(v *core.Vulnerability) { v.Sink.Code = "db.Query( q )" }, false},
+		{"renamed rule", func(v *core.Vulnerability) { v.Type = "SQL injection (database)" }, true},
+		{"other rule", func(v *core.Vulnerability) { v.RuleID = "xss" }, false},
+		{"same name in another directory", func(v *core.Vulnerability) { v.File = "/src/b/h.go" }, false},
+		{"other function", func(v *core.Vulnerability) { v.Sink.Function = "other" }, false},
+		{"second-order", func(v *core.Vulnerability) { v.Stored = &core.StoredFlow{Kind: "sql"} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := finding("/src/a/h.go", 10)
+			tt.edit(&v)
+			if got := fingerprint(v, "/src"); (got == base) != tt.same {
+				t.Errorf("fingerprint %s, base %s: same = %v, want %v", got, base, got == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestFingerprintNormalizesTemporaries(t *testing.T) {
+	a, b := finding("/src/h.go", 1), finding("/src/h.go", 1)
+	a.Sink.Code = "t12 = call db.Query"
+	b.Sink.Code = "t40 =  call db.Query"
+	if fa, fb := fingerprint(a, ""), fingerprint(b, ""); fa != fb {
+		t.Errorf("temporaries and spacing changed the fingerprint: %s != %s", fa, fb)
+	}
+}
+
+func TestFingerprintDuplicates(t *testing.T) {
+	vulns := []core.Vulnerability{finding("/src/h.go", 1), finding("/src/h.go", 9)}
+	AssignFingerprints(vulns, "/src")
+	if vulns[1].Fingerprint != vulns[0].Fingerprint+"-2" {
+		t.Errorf("identical findings: %q and %q, want a -2 suffix on the second", vulns[0].Fingerprint, vulns[1].Fingerprint)
+	}
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"regexp"
+	"sast-demo/pkg/core"
+)
+
+// guardFinder decides whether a taint path is protected by a validator check.
+//
+// A path is guarded when some branch tests the result of a validator call on a
+// value of the path, and the block entered when the check succeeds dominates the
+// sink: the sink cannot run unless the validation passed. Negated checks
+// (`if !isValid(u) { return }`) and comparisons with false/0, as emitted for
+// bytecode, guard through their false edge.
+type guardFinder struct {
+	prog        *core.ProgramIR
+	instToBlock map[string]string
+	instToFunc  map[string]string
+	defs        map[string][]*core.Instruction // Value or variable -> instructions assigning it
+	branches    map[string][]*core.Instruction // Function -> its branch instructions
+	doms        map[string]map[string]map[string]bool
+}
+
+// guard is a successful validation that dominates a sink
+type guard struct {
+	call   *core.Instruction // The validator call
+	branch *core.Instruction
+}
+
+func newGuardFinder(prog *core.ProgramIR, instToBlock, instToFunc map[string]string) *guardFinder {
+	gf := &guardFinder{
+		prog:        prog,
+		instToBlock: instToBlock,
+		instToFunc:  instToFunc,
+		defs:        make(map[string][]*core.Instruction),
+		branches:    make(map[string][]*core.Instruction),
+		doms:        make(map[string]map[string]map[string]bool),
+	}
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		for _, id := range fn.BlockIDs() {
+			for _, inst := range fn.Blocks[id].Instructions {
+				if inst.Result != "" {
+					gf.defs[inst.Result] = append(gf.defs[inst.Result], inst)
+				}
+				if inst.Op == core.OpBranch && len(inst.Operands) == 3 {
+					gf.branches[name] = append(gf.branches[name], inst)
+				}
+			}
+		}
+	}
+	return gf
+}
+
+func (gf *guardFinder) dominators(fnName string) map[string]map[string]bool {
+	d, ok := gf.doms[fnName]
+	if !ok {
+		d = gf.prog.Functions[fnName].Dominators()
+		gf.doms[fnName] = d
+	}
+	return d
+}
+
+// find returns the first validation guarding the sink at the end of path, or nil
+func (gf *guardFinder) find(path []*core.Instruction, validators []*regexp.Regexp) *guard {
+	if len(validators) == 0 || len(path) < 2 {
+		return nil
+	}
+	sink := path[len(path)-1]
+	fnName := gf.instToFunc[sink.ID]
+	fn := gf.prog.Functions[fnName]
+	if fn == nil {
+		return nil
+	}
+
+	// Values carried along the path: temporaries, and the variables they pass through
+	onPath := make(map[string]bool)
+	for _, inst := range path {
+		if inst.Result != "" {
+			onPath[inst.Result] = true
+		}
+		if inst.Op == core.OpLoad && len(inst.Operands) > 0 {
+			onPath[inst.Operands[0]] = true
+		}
+	}
+
+	dom := gf.dominators(fnName)
+	sinkBlock := gf.instToBlock[sink.ID]
+	for _, br := range gf.branches[fnName] {
+		call, positive := gf.validation(br.Operands[0], validators, 0)
+		if call == nil {
+			continue
+		}
+		success := br.Operands[1]
+		if !positive {
+			success = br.Operands[2]
+		}
+		// The success edge must be the only way into its block, and that block must dominate the sink
+		if !dom[sinkBlock][success] || !onlyPredecessor(fn, success, gf.instToBlock[br.ID]) {
+			continue
+		}
+		for _, v := range gf.checkedValues(call) {
+			if onPath[v] && !gf.reassignedAfter(path, v, success, dom) {
+				return &guard{call: call, branch: br}
+			}
+		}
+	}
+	return nil
+}
+
+// validation traces a branch condition back to a validator call. positive is
+// false when the condition is the negated result of the call.
+func (gf *guardFinder) validation(cond string, validators []*regexp.Regexp, depth int) (call *core.Instruction, positive bool) {
+	defs := gf.defs[cond]
+	if depth > 8 || len(defs) != 1 {
+		return nil, false
+	}
+	def := defs[0]
+	switch def.Op {
+	case core.OpCall:
+		for _, r := range validators {
+			if len(def.Operands) > 0 && r.MatchString(def.Operands[0]) {
+				return def, true
+			}
+		}
+	case core.OpLoad, core.OpStore:
+		// ok := isValid(u); if ok { ... }
+		if len(def.Operands) > 0 {
+			return gf.validation(def.Operands[0], validators, depth+1)
+		}
+	case core.OpBinOp:
+		ops := def.Operands
+		if len(ops) == 2 && ops[0] == "!" {
+			call, positive := gf.validation(ops[1], validators, depth+1)
+			return call, !positive
+		}
+		if len(ops) == 3 && (ops[1] == "==" || ops[1] == "!=") {
+			for _, side := range [][2]string{{ops[0], ops[2]}, {ops[2], ops[0]}} {
+				if !gf.isFalse(side[1]) {
+					continue
+				}
+				call, positive := gf.validation(side[0], validators, depth+1)
+				if ops[1] == "==" {
+					positive = !positive
+				}
+				return call, positive
+			}
+		}
+	}
+	return nil, false
+}
+
+// isFalse recognizes false and 0, either inline or loaded from a constant
+func (gf *guardFinder) isFalse(v string) bool {
+	if v == "false" || v == "0" {
+		return true
+	}
+	defs := gf.defs[v]
+	return len(defs) == 1 && defs[0].Op == core.OpConst && len(defs[0].Operands) > 0 &&
+		(defs[0].Operands[0] == "false" || defs[0].Operands[0] == "0")
+}
+
+// checkedValues are the values a validator call inspects: its arguments and
+// receiver, plus the variables they were loaded from
+func (gf *guardFinder) checkedValues(call *core.Instruction) []string {
+	var vals []string
+	add := func(v string) {
+		if v == "" {
+			return
+		}
+		vals = append(vals, v)
+		if defs := gf.defs[v]; len(defs) == 1 && defs[0].Op == core.OpLoad && len(defs[0].Operands) > 0 {
+			vals = append(vals, defs[0].Operands[0])
+		}
+	}
+	for _, a := range call.Operands[1:] {
+		add(a)
+	}
+	add(call.Receiver)
+	return vals
+}
+
+// reassignedAfter reports whether the path stores into v after the check succeeded,
+// in which case the validated value is not the one reaching the sink
+func (gf *guardFinder) reassignedAfter(path []*core.Instruction, v, success string, dom map[string]map[string]bool) bool {
+	for _, inst := range path {
+		if inst.Op == core.OpStore && inst.Result == v && dom[gf.instToBlock[inst.ID]][success] {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyPredecessor reports whether every edge into blockID comes from predID
+func onlyPredecessor(fn *core.FunctionIR, blockID, predID string) bool {
+	bb := fn.Blocks[blockID]
+	if bb == nil {
+		return false
+	}
+	for _, p := range bb.Predecessors {
+		if p != predID {
+			return false
+		}
+	}
+	return len(bb.Predecessors) > 0
+}
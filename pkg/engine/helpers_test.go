@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sast-demo/pkg/core"
+	"sast-demo/pkg/lang/golang"
+	"sast-demo/pkg/lang/java"
+	"sort"
+	"testing"
+)
+
+// sourceIR generates the IR of a Go (.go) or Java (.java) source file named name
+func sourceIR(t *testing.T, name, src string) (*core.ProgramIR, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var prog *core.ProgramIR
+	var err error
+	if filepath.Ext(name) == ".java" {
+		prog, err = java.NewJavaIRGenerator().Generate(path)
+	} else {
+		prog, err = golang.NewIRGenerator().Generate(path)
+	}
+	if err != nil {
+		t.Fatalf("IR of %s: %v", name, err)
+	}
+	return prog, path
+}
+
+// scan runs the default rules, adjusted by configure, over a source file
+func scan(t *testing.T, name, src string, configure ...func(*Config)) []core.Vulnerability {
+	t.Helper()
+	prog, path := sourceIR(t, name, src)
+	cfg := DefaultRules()
+	for _, c := range configure {
+		c(&cfg)
+	}
+	return NewEngine(cfg).AnalyzeIR(prog, path)
+}
+
+// findings summarizes findings as "rule@sourceLine->sinkLine", sorted
+func findings(vulns []core.Vulnerability) []string {
+	var out []string
+	for _, v := range vulns {
+		out = append(out, fmt.Sprintf("%s@%d->%d", v.RuleID, nodeLine(v.Source), nodeLine(v.Sink)))
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,371 @@
+package engine
+
+import (
+	"fmt"
+	"sast-demo/pkg/core"
+	"slices"
+)
+
+// IFDS-style dataflow solving.
+//
+// A problem is stated as flow functions over the interprocedural CFG: each maps
+// one fact holding before an instruction to the facts holding after it. The
+// solver tabulates the reachable exploded nodes (instruction, fact) with a
+// worklist, so every node is processed once per solve no matter how many paths
+// lead to it. Each node keeps links to the nodes it was reached from, and paths
+// are rebuilt from those links only when a result is reported.
+//
+// Calls of program functions are solved on demand as separate, cached solves of
+// the callee from the facts entering it (summaries), down to a fixed call depth;
+// their exit facts are mapped back to the call site. A top-level solve, started
+// from seeds rather than a call, has no caller to return to, so facts leaving a
+// function continue at every call site of it (unbalanced returns).
+
+// ICFG is the interprocedural control flow graph of a program at instruction
+// granularity. An instruction leads to the next one in its block, the last one to
+// the first instruction of each successor block; empty blocks are skipped, and
+// blocks or edges constant propagation proved dead are left out. Calls are linked
+// to the program functions they may invoke.
+type ICFG struct {
+	prog    *core.ProgramIR
+	succs   map[*core.Instruction][]*core.Instruction
+	funcOf  map[*core.Instruction]string
+	entries map[string][]*core.Instruction
+	callees map[*core.Instruction][]string
+	callers map[string][]*core.Instruction
+}
+
+// NewICFG builds the ICFG of prog, resolving calls with pt
+func NewICFG(prog *core.ProgramIR, pt *PointsTo) *ICFG {
+	g := &ICFG{
+		prog:    prog,
+		succs:   make(map[*core.Instruction][]*core.Instruction),
+		funcOf:  make(map[*core.Instruction]string),
+		entries: make(map[string][]*core.Instruction),
+		callees: make(map[*core.Instruction][]string),
+		callers: make(map[string][]*core.Instruction),
+	}
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		g.entries[name] = firstInsts(fn, fn.Entry, make(map[string]bool))
+		for _, id := range fn.BlockIDs() {
+			bb := fn.Blocks[id]
+			if bb.Unreachable {
+				continue
+			}
+			for i, inst := range bb.Instructions {
+				g.funcOf[inst] = name
+				for _, target := range pt.Callees(name, inst) {
+					g.callees[inst] = append(g.callees[inst], target)
+					g.callers[target] = append(g.callers[target], inst)
+				}
+				switch {
+				case inst.Op == core.OpRet:
+				case i+1 < len(bb.Instructions):
+					g.succs[inst] = []*core.Instruction{bb.Instructions[i+1]}
+				default:
+					visited := make(map[string]bool)
+					for _, succ := range bb.Successors {
+						if !slices.Contains(bb.DeadSuccessors, succ) {
+							g.succs[inst] = append(g.succs[inst], firstInsts(fn, succ, visited)...)
+						}
+					}
+				}
+			}
+		}
+	}
+	return g
+}
+
+// firstInsts lists the instructions that run first when control enters a block
+func firstInsts(fn *core.FunctionIR, id string, visited map[string]bool) []*core.Instruction {
+	bb := fn.Blocks[id]
+	if bb == nil || bb.Unreachable || visited[id] {
+		return nil
+	}
+	visited[id] = true
+	if len(bb.Instructions) > 0 {
+		return bb.Instructions[:1]
+	}
+	var insts []*core.Instruction
+	for _, succ := range bb.Successors {
+		if !slices.Contains(bb.DeadSuccessors, succ) {
+			insts = append(insts, firstInsts(fn, succ, visited)...)
+		}
+	}
+	return insts
+}
+
+// Succs returns the instructions that may run right after inst
+func (g *ICFG) Succs(inst *core.Instruction) []*core.Instruction { return g.succs[inst] }
+
+// FuncOf returns the function an instruction belongs to
+func (g *ICFG) FuncOf(inst *core.Instruction) string { return g.funcOf[inst] }
+
+// Entries returns the first instructions of a function
+func (g *ICFG) Entries(fn string) []*core.Instruction { return g.entries[fn] }
+
+// Callees returns the program functions a call may invoke
+func (g *ICFG) Callees(call *core.Instruction) []string { return g.callees[call] }
+
+// Callers returns the calls that may invoke a function
+func (g *ICFG) Callers(fn string) []*core.Instruction { return g.callers[fn] }
+
+// Flow is a fact produced by a flow function
+type Flow struct {
+	Fact string
+	// Transfer marks facts the instruction creates, rather than lets through;
+	// only those instructions appear in reconstructed paths
+	Transfer bool
+	// At, if set, makes the fact hold before At instead of after the instruction:
+	// an edge outside the CFG, such as a global written in one function and read
+	// in another. Solves confined to one function drop such edges into others.
+	At *core.Instruction
+}
+
+// FlowFunctions define a distributive dataflow problem for the Solver
+type FlowFunctions interface {
+	// Normal is the flow through an instruction other than a call of a program function
+	Normal(fn string, inst *core.Instruction, fact string) []Flow
+	// Call maps a fact before a call to the facts at the entry of callee
+	Call(call *core.Instruction, callee string, fact string) []string
+	// Return maps a fact at an exit of callee to the facts after the call
+	Return(call *core.Instruction, callee string, exit *core.Instruction, fact string) []string
+	// CallToReturn is the flow past a call beside the callees; entered reports
+	// whether the fact was passed into callees that could all be solved
+	CallToReturn(fn string, call *core.Instruction, fact string, entered bool) []Flow
+}
+
+// Node is an exploded node: a fact holding before an instruction
+type Node struct {
+	Inst *core.Instruction
+	Fact string
+}
+
+// predLink records how a node was reached: from another node through the
+// instructions via, or from a seed when from.Inst is nil
+type predLink struct {
+	from Node
+	via  []*core.Instruction
+}
+
+// CallEntry is a call whose callee was solved from a fact reaching the call
+type CallEntry struct {
+	Node   Node      // The fact before the call
+	Callee *Solution // The callee's solve from the fact entering it
+}
+
+// Solution holds the exploded nodes a solve reached
+type Solution struct {
+	Func      string // For callee solves: the function solved
+	EntryFact string // For callee solves: the fact the callee was entered with
+	Nodes     []Node // In the order they were reached
+	Exits     []Node // Reached nodes without a successor
+	Calls     []CallEntry
+	Truncated bool // The node budget ran out
+	preds     map[Node][]predLink
+}
+
+// Reached reports whether a solve reached a node
+func (s *Solution) Reached(n Node) bool { return s.preds[n] != nil }
+
+// Solver tabulates FlowFunctions over an ICFG
+type Solver struct {
+	icfg     *ICFG
+	flows    FlowFunctions
+	depth    int // Call levels solved through callee summaries
+	budget   int // Nodes processed per solve
+	maxPreds int // Predecessor links kept per node, for alternative paths
+
+	summaries map[string]*Solution
+	active    map[string]bool // Callees being solved, to cut recursion
+
+	// Counters across all solves
+	NodesProcessed  int
+	Summaries       int
+	SummariesReused int
+	Truncated       int
+}
+
+// NewSolver prepares a solver; depth bounds nested callee solves, budget the
+// nodes processed per solve, and maxPreds the alternative predecessors kept per node
+func NewSolver(icfg *ICFG, flows FlowFunctions, depth, budget, maxPreds int) *Solver {
+	return &Solver{
+		icfg:      icfg,
+		flows:     flows,
+		depth:     depth,
+		budget:    budget,
+		maxPreds:  max(maxPreds, 1),
+		summaries: make(map[string]*Solution),
+		active:    make(map[string]bool),
+	}
+}
+
+// Seed is a starting node of a top-level solve and the instructions that produced it
+type Seed struct {
+	Node Node
+	Via  []*core.Instruction
+}
+
+// Solve tabulates the nodes reachable from the seeds
+func (s *Solver) Solve(seeds []Seed) *Solution {
+	return s.solve("", seeds, s.depth)
+}
+
+// summary solves callee entered with fact, depth call levels deep; nil when the
+// depth is exhausted or callee is already being solved (recursion)
+func (s *Solver) summary(callee, fact string, depth int) *Solution {
+	if depth < 0 || s.active[callee] {
+		return nil
+	}
+	key := fmt.Sprintf("%s|%s|%d", callee, fact, depth)
+	if sol, ok := s.summaries[key]; ok {
+		s.SummariesReused++
+		return sol
+	}
+	s.Summaries++
+	s.active[callee] = true
+	defer delete(s.active, callee)
+
+	var seeds []Seed
+	for _, entry := range s.icfg.Entries(callee) {
+		seeds = append(seeds, Seed{Node: Node{entry, fact}})
+	}
+	sol := s.solve(callee, seeds, depth)
+	sol.EntryFact = fact
+	s.summaries[key] = sol
+	return sol
+}
+
+func (s *Solver) solve(scope string, seeds []Seed, depth int) *Solution {
+	sol := &Solution{Func: scope, preds: make(map[Node][]predLink)}
+	var worklist []Node
+	propagate := func(n Node, link predLink) {
+		if n.Inst == nil {
+			return
+		}
+		if scope != "" && s.icfg.FuncOf(n.Inst) != scope {
+			return
+		}
+		links, seen := sol.preds[n]
+		if !seen {
+			sol.preds[n] = []predLink{link}
+			sol.Nodes = append(sol.Nodes, n)
+			worklist = append(worklist, n)
+			return
+		}
+		if len(links) < s.maxPreds && link.from != n && !slices.ContainsFunc(links, func(l predLink) bool { return l.from == link.from }) {
+			sol.preds[n] = append(links, link)
+		}
+	}
+	flowTo := func(from Node, flows []Flow) {
+		for _, f := range flows {
+			var via []*core.Instruction
+			if f.Transfer {
+				via = []*core.Instruction{from.Inst}
+			}
+			if f.At != nil {
+				propagate(Node{f.At, f.Fact}, predLink{from: from, via: via})
+				continue
+			}
+			for _, succ := range s.icfg.Succs(from.Inst) {
+				propagate(Node{succ, f.Fact}, predLink{from: from, via: via})
+			}
+		}
+	}
+	for _, seed := range seeds {
+		propagate(seed.Node, predLink{via: seed.Via})
+	}
+
+	processed := 0
+	for len(worklist) > 0 {
+		if processed >= s.budget {
+			sol.Truncated = true
+			s.Truncated++
+			break
+		}
+		processed++
+		n := worklist[0]
+		worklist = worklist[1:]
+		fn := s.icfg.FuncOf(n.Inst)
+
+		if len(s.icfg.Succs(n.Inst)) == 0 {
+			sol.Exits = append(sol.Exits, n)
+			// With no caller to return to, taint leaving the function reaches every call site
+			if scope == "" {
+				for _, call := range s.icfg.Callers(fn) {
+					for _, fact := range s.flows.Return(call, fn, n.Inst, n.Fact) {
+						for _, succ := range s.icfg.Succs(call) {
+							propagate(Node{succ, fact}, predLink{from: n, via: []*core.Instruction{n.Inst, call}})
+						}
+					}
+				}
+			}
+		}
+
+		callees := s.icfg.Callees(n.Inst)
+		if len(callees) == 0 {
+			flowTo(n, s.flows.Normal(fn, n.Inst, n.Fact))
+			continue
+		}
+
+		entered, cut := false, false
+		for _, callee := range callees {
+			for _, fact := range s.flows.Call(n.Inst, callee, n.Fact) {
+				sub := s.summary(callee, fact, depth-1)
+				if sub == nil {
+					cut = true
+					continue
+				}
+				entered = true
+				sol.Calls = append(sol.Calls, CallEntry{Node: n, Callee: sub})
+				for _, exit := range sub.Exits {
+					for _, ret := range s.flows.Return(n.Inst, callee, exit.Inst, exit.Fact) {
+						for _, succ := range s.icfg.Succs(n.Inst) {
+							propagate(Node{succ, ret}, predLink{from: n, via: []*core.Instruction{n.Inst}})
+						}
+					}
+				}
+			}
+		}
+		flowTo(n, s.flows.CallToReturn(fn, n.Inst, n.Fact, entered && !cut))
+	}
+	s.NodesProcessed += processed
+	return sol
+}
+
+// Paths rebuilds up to limit distinct paths to a reached node from the seeds,
+// shortest first. A path lists the instructions that transferred the fact, ending
+// with the last one before n.
+func (sol *Solution) Paths(n Node, limit int) [][]*core.Instruction {
+	var paths [][]*core.Instruction
+	seen := make(map[string]bool)
+	onStack := make(map[Node]bool)
+	steps := 0
+	var walk func(n Node, suffix []*core.Instruction)
+	walk = func(n Node, suffix []*core.Instruction) {
+		if len(paths) >= limit || steps > 1000*limit {
+			return
+		}
+		steps++
+		onStack[n] = true
+		defer delete(onStack, n)
+		for _, link := range sol.preds[n] {
+			next := make([]*core.Instruction, 0, len(link.via)+len(suffix))
+			next = append(append(next, link.via...), suffix...)
+			if link.from.Inst == nil {
+				if key := pathKey(next); !seen[key] {
+					seen[key] = true
+					paths = append(paths, next)
+				}
+				continue
+			}
+			if !onStack[link.from] {
+				walk(link.from, next)
+			}
+		}
+	}
+	walk(n, nil)
+	slices.SortStableFunc(paths, func(a, b []*core.Instruction) int { return len(a) - len(b) })
+	return paths
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"sort"
+	"testing"
+)
+
+// reach is the simplest IFDS problem: one fact that holds wherever control goes
+type reach struct{}
+
+func (reach) Normal(fn string, inst *core.Instruction, fact string) []Flow {
+	return []Flow{{Fact: fact, Transfer: inst.Op == core.OpCall}}
+}
+func (reach) Call(call *core.Instruction, callee, fact string) []string { return []string{fact} }
+func (reach) Return(call *core.Instruction, callee string, exit *core.Instruction, fact string) []string {
+	return []string{fact}
+}
+func (reach) CallToReturn(fn string, call *core.Instruction, fact string, entered bool) []Flow {
+	if entered {
+		return nil
+	}
+	return []Flow{{Fact: fact}}
+}
+
+const reachSource = `package main
+
+func countdown(n int) int {
+	if n > 0 {
+		return countdown(n - 1)
+	}
+	return 0
+}
+
+func main() {
+	x := countdown(3)
+	if false {
+		println("dead")
+	}
+	println(x)
+}
+`
+
+// reachedCalls lists the lines of the calls of callee a solve reached
+func reachedCalls(sol *Solution, callee string) []int {
+	var lines []int
+	for _, n := range sol.Nodes {
+		if n.Inst.Op == core.OpCall && n.Inst.Operands[0] == callee {
+			lines = append(lines, n.Inst.Line)
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func TestSolver(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", reachSource)
+	MarkDeadCode(prog)
+	icfg := NewICFG(prog, AnalyzePointsTo(prog))
+	seeds := func(fn string) []Seed {
+		var s []Seed
+		for _, entry := range icfg.Entries(fn) {
+			s = append(s, Seed{Node: Node{entry, "x"}})
+		}
+		return s
+	}
+
+	// Recursion is cut rather than followed forever, and the dead branch is never entered
+	solver := NewSolver(icfg, reach{}, DefaultContextDepth, DefaultPathBudget, 1)
+	sol := solver.Solve(seeds("main"))
+	if got := reachedCalls(sol, "println"); !reflect.DeepEqual(got, []int{15}) {
+		t.Errorf("println calls reached at lines %v, want [15]", got)
+	}
+	if len(sol.Calls) != 1 || sol.Calls[0].Callee.Func != "countdown" || solver.Summaries != 1 {
+		t.Errorf("%d calls entered, %d summaries; want countdown once", len(sol.Calls), solver.Summaries)
+	}
+	for _, n := range sol.Nodes {
+		if n.Inst.Op == core.OpCall && n.Inst.Operands[0] == "println" {
+			paths := sol.Paths(n, 2)
+			if len(paths) != 1 || len(paths[0]) != 1 || paths[0][0].Operands[0] != "countdown" {
+				t.Errorf("paths to println: %v", paths)
+			}
+		}
+	}
+
+	// Facts leaving a function solved from its own entry return to every call site
+	sol = NewSolver(icfg, reach{}, DefaultContextDepth, DefaultPathBudget, 1).Solve(seeds("countdown"))
+	if got := reachedCalls(sol, "println"); !reflect.DeepEqual(got, []int{15}) {
+		t.Errorf("println calls reached from countdown at lines %v, want [15]", got)
+	}
+
+	// The budget bounds each solve: countdown's stops before returning, so nothing
+	// after the call is reached
+	solver = NewSolver(icfg, reach{}, DefaultContextDepth, 2, 1)
+	sol = solver.Solve(seeds("main"))
+	if solver.Truncated == 0 || len(sol.Calls) != 1 || !sol.Calls[0].Callee.Truncated {
+		t.Errorf("budget 2: %d solves truncated", solver.Truncated)
+	}
+	if got := reachedCalls(sol, "println"); got != nil {
+		t.Errorf("budget 2: println calls reached at lines %v", got)
+	}
+}
+
+const killSource = `package main
+
+import (
+	"net/http"
+	"os/exec"
+)
+
+func handler(r *http.Request) {
+	cmd := r.URL.Query().Get("cmd")
+	exec.Command(cmd).Run()
+	cmd = "ls"
+	exec.Command(cmd).Run()
+}
+`
+
+const builderSource = `import javax.servlet.http.HttpServletRequest;
+import java.sql.Statement;
+
+public class Report {
+    public void find(Statement stmt, HttpServletRequest request) throws Exception {
+        String id = request.getParameter("id");
+        StringBuilder sb = new StringBuilder();
+        sb.append("SELECT * FROM t WHERE id = ").append(id);
+        stmt.executeQuery(sb.toString());
+        StringBuilder safe = new StringBuilder();
+        safe.append("SELECT 1");
+        stmt.executeQuery(safe.toString());
+    }
+}
+`
+
+func TestTaintFlows(t *testing.T) {
+	// A reassignment kills the taint of the variable
+	if got, want := findings(scan(t, "main.go", killSource)), []string{"rce@9->10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+	// A chained append writes to the builder it started from
+	if got, want := findings(scan(t, "Report.java", builderSource)), []string{"sqli@6->9"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+}
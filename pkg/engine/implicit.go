@@ -0,0 +1,157 @@
+package engine
+
+import "sast-demo/pkg/core"
+
+// Implicit flows.
+//
+// `if secret == "x" { w.Write([]byte("yes")) }` copies no tainted value, yet the
+// output tells whether the condition held. In implicit-flow mode the taint of a
+// branch condition spreads to what the branch decides: the instructions control
+// dependent on it, the values they store and, for calls run only under the
+// branch, the callee bodies. The walk goes over the program dependence graph
+// forward from the source, context sensitively as forward slices do, and a sink
+// is reported when it is reached only through such a control dependence; sinks
+// the source reaches by data alone are left to the taint rules.
+
+// implicitState is a node of the walk: an instruction and how it was reached
+type implicitState struct {
+	inst     *core.Instruction
+	entered  bool // A callee was entered, so the walk may no longer return to callers
+	implicit bool // Some step was a control dependence
+	runs     bool // Whether inst runs at all depends on the source: reached through a control dependence or a call that does
+}
+
+// implicitFlow is a sink reached only through a branch on the source
+type implicitFlow struct {
+	sink   *core.Instruction
+	branch *core.Instruction // First branch on the source along the path
+	path   []*core.Instruction
+}
+
+// findImplicitFlows walks the dependences of source forward and returns the
+// sinks that are reached implicitly but not by data alone, in walk order
+func (g *PDG) findImplicitFlows(source *core.Instruction, isSink func(*core.Instruction) bool) []implicitFlow {
+	type step struct {
+		from implicitState
+		kind DepKind
+	}
+	start := implicitState{inst: source}
+	parent := map[implicitState]*step{start: nil}
+	explicit := make(map[*core.Instruction]bool)
+	var sinks []implicitState
+	seen := make(map[*core.Instruction]bool)
+
+	for work := []implicitState{start}; len(work) > 0; work = work[1:] {
+		s := work[0]
+		if s.inst != source && s.inst.Op == core.OpCall && isSink(s.inst) {
+			if !s.implicit {
+				explicit[s.inst] = true
+			} else if !seen[s.inst] {
+				seen[s.inst] = true
+				sinks = append(sinks, s)
+			}
+		}
+		for _, d := range g.users[s.inst] {
+			next := implicitState{
+				inst:     d.Inst,
+				entered:  s.entered,
+				implicit: s.implicit || d.Kind == DepControl,
+				runs:     d.Kind == DepControl || d.Kind == DepCall,
+			}
+			switch d.Kind {
+			case DepParamIn:
+				next.entered = true
+			case DepCall:
+				// A callee runs whenever its call does; only calls the source
+				// decides on make the callee body depend on it
+				if !s.runs {
+					continue
+				}
+				next.entered = true
+			case DepParamOut:
+				if s.entered {
+					continue
+				}
+			}
+			if _, ok := parent[next]; ok {
+				continue
+			}
+			parent[next] = &step{from: s, kind: d.Kind}
+			work = append(work, next)
+		}
+	}
+
+	var flows []implicitFlow
+	for _, s := range sinks {
+		if explicit[s.inst] {
+			continue
+		}
+		f := implicitFlow{sink: s.inst}
+		for at := s; ; {
+			f.path = append([]*core.Instruction{at.inst}, f.path...)
+			p := parent[at]
+			if p == nil {
+				break
+			}
+			if p.kind == DepControl {
+				f.branch = p.from.inst
+			}
+			at = p.from
+		}
+		flows = append(flows, f)
+	}
+	return flows
+}
+
+// analyzeImplicitFlows reports the implicit flows of the implicit rules
+func (e *Engine) analyzeImplicitFlows(pdg *PDG, allInsts []*core.Instruction, labeler *sourceLabeler, filePath string, instToBlock, instToFunc map[string]string) []core.Vulnerability {
+	var vulns []core.Vulnerability
+	for _, rule := range e.Config.ImplicitRules {
+		sources := e.compileRegexes(rule.Sources)
+		sinkRegexes := e.compileRegexes(rule.Sinks)
+		calls := e.compileCallSinks(rule.CallSinks)
+		ruleStart := len(vulns)
+
+		for _, inst := range allInsts {
+			labels := labeler.labels(inst)
+			if !e.isRuleSource(rule, sources, inst, labels) {
+				continue
+			}
+			isSink := func(inst *core.Instruction) bool {
+				return e.matchesAny(inst.Code, sinkRegexes) || matchesCallee(inst, calls, labels)
+			}
+			for _, f := range pdg.findImplicitFlows(inst, isSink) {
+				vulns = append(vulns, core.Vulnerability{
+					Type:        rule.Name,
+					RuleID:      rule.ID,
+					Severity:    rule.Severity,
+					File:        filePath,
+					Line:        inst.Line,
+					Description: rule.Description,
+					Source:      e.instToNode(inst, filePath, instToBlock, instToFunc),
+					Sink:        e.instToNode(f.sink, filePath, instToBlock, instToFunc),
+					Path:        e.pathInstToNode(f.path, filePath, instToBlock, instToFunc),
+					Branch:      e.instToNode(f.branch, filePath, instToBlock, instToFunc),
+					Labels:      labels,
+				})
+				e.Stats.ImplicitFlows++
+			}
+		}
+		mergeSinkLabels(vulns[ruleStart:])
+	}
+	return vulns
+}
+
+// matchesCallee reports whether a call is of one of the callees of sinks accepting
+// labels. The whole call counts: running it at all is what an implicit flow observes.
+func matchesCallee(inst *core.Instruction, sinks []callSink, labels []string) bool {
+	if inst.Op != core.OpCall || len(inst.Operands) == 0 {
+		return false
+	}
+	for _, s := range sinks {
+		if s.callee.MatchString(inst.Operands[0]) && labelsAccepted(labels, s.spec.Labels, s.spec.RejectLabels) {
+			return true
+		}
+	}
+	return false
+}
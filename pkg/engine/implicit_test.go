@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const implicitSource = `package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+func confirm(w http.ResponseWriter) {
+	w.Write([]byte("yes"))
+}
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	secret := os.Getenv("SECRET")
+	if secret == "x" {
+		w.Write([]byte("match"))
+	}
+	if len(secret) > 8 {
+		confirm(w)
+	}
+	fmt.Fprintln(w, secret)
+	w.Write([]byte("done"))
+}
+`
+
+// implicitFindings keeps the findings of the implicit-flow rule
+func implicitFindings(vulns []string) []string {
+	var out []string
+	for _, v := range vulns {
+		if strings.HasPrefix(v, "implicit-flow@") {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func TestImplicitFlows(t *testing.T) {
+	// Off by default
+	if got := implicitFindings(findings(scan(t, "main.go", implicitSource))); got != nil {
+		t.Errorf("implicit findings without ImplicitFlows: %v", got)
+	}
+
+	prog, path := sourceIR(t, "main.go", implicitSource)
+	cfg := DefaultRules()
+	cfg.ImplicitFlows = true
+	e := NewEngine(cfg)
+	vulns := e.AnalyzeIR(prog, path)
+	// The writes decided by a branch on the secret, directly and in a callee; not
+	// the one the secret reaches by data, nor the one that runs either way
+	want := []string{"implicit-flow@14->10", "implicit-flow@14->16"}
+	if got := implicitFindings(findings(vulns)); !reflect.DeepEqual(got, want) {
+		t.Errorf("implicit findings %v, want %v", got, want)
+	}
+	if e.Stats.ImplicitFlows != len(want) {
+		t.Errorf("Stats.ImplicitFlows = %d, want %d", e.Stats.ImplicitFlows, len(want))
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"regexp"
+	"sast-demo/pkg/core"
+	"sort"
+	"strings"
+)
+
+// sourceLabeler gives the source labels of instructions
+type sourceLabeler struct {
+	patterns []labelPattern
+	cache    map[*core.Instruction][]string
+}
+
+type labelPattern struct {
+	label string
+	re    *regexp.Regexp
+}
+
+func (e *Engine) newSourceLabeler() *sourceLabeler {
+	l := &sourceLabeler{cache: make(map[*core.Instruction][]string)}
+	for _, sl := range e.Config.SourceLabels {
+		for _, re := range e.compileRegexes(sl.Patterns) {
+			l.patterns = append(l.patterns, labelPattern{label: sl.Label, re: re})
+		}
+	}
+	return l
+}
+
+// labels lists the labels of inst, sorted
+func (l *sourceLabeler) labels(inst *core.Instruction) []string {
+	if labels, ok := l.cache[inst]; ok {
+		return labels
+	}
+	var labels []string
+	for _, p := range l.patterns {
+		if p.re.MatchString(inst.Code) && !hasLabel(labels, p.label) {
+			labels = append(labels, p.label)
+		}
+	}
+	sort.Strings(labels)
+	l.cache[inst] = labels
+	return labels
+}
+
+// isRuleSource reports whether inst, carrying labels, is a source of rule
+func (e *Engine) isRuleSource(rule Rule, sources []*regexp.Regexp, inst *core.Instruction, labels []string) bool {
+	if hasAnyLabel(labels, rule.RejectLabels) {
+		return false
+	}
+	return e.matchesAny(inst.Code, sources) || hasAnyLabel(labels, rule.Labels)
+}
+
+// labelsAccepted reports whether taint from a source carrying labels passes the
+// Labels and RejectLabels of a sink
+func labelsAccepted(labels, accept, reject []string) bool {
+	if hasAnyLabel(labels, reject) {
+		return false
+	}
+	return len(accept) == 0 || hasAnyLabel(labels, accept)
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyLabel(labels, of []string) bool {
+	for _, l := range of {
+		if hasLabel(labels, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSinkLabels gives the findings of one rule the labels of every source
+// reaching their sink, not only of the source they are reported for
+func mergeSinkLabels(vulns []core.Vulnerability) {
+	bySink := make(map[string][]string)
+	for _, v := range vulns {
+		for _, l := range v.Labels {
+			if !hasLabel(bySink[v.Sink.ID], l) {
+				bySink[v.Sink.ID] = append(bySink[v.Sink.ID], l)
+			}
+		}
+	}
+	for i := range vulns {
+		if labels := bySink[vulns[i].Sink.ID]; len(labels) > len(vulns[i].Labels) {
+			vulns[i].Labels = append([]string(nil), labels...)
+			sort.Strings(vulns[i].Labels)
+		}
+	}
+}
+
+// withLabels returns the matcher for taint from sources carrying labels: call
+// sinks whose labels reject the taint are left out
+func (m *sinkMatcher) withLabels(labels []string) *sinkMatcher {
+	key := strings.Join(labels, ",")
+	if lm := m.labeled[key]; lm != nil {
+		return lm
+	}
+	lm := &sinkMatcher{
+		e:       m.e,
+		flows:   m.flows,
+		regexes: m.regexes,
+		callee:  make(map[*Solution][][]*core.Instruction),
+	}
+	for _, c := range m.calls {
+		if labelsAccepted(labels, c.spec.Labels, c.spec.RejectLabels) {
+			lm.calls = append(lm.calls, c)
+		}
+	}
+	m.labeled[key] = lm
+	return lm
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+const localInputSource = `package main
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+)
+
+func handler(r *http.Request) {
+	cmd := r.URL.Query().Get("cmd")
+	exec.Command(cmd).Run()
+}
+
+func main() {
+	exec.Command(os.Args[1]).Run()
+}
+`
+
+func TestLocalInputRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		want      []string
+		severity  map[string]string
+	}{
+		{"default", func(*Config) {}, []string{"rce@10->11", "rce@15->15"},
+			map[string]string{"rce": "CRITICAL"}},
+		{"split", (*Config).SplitLocalInput, []string{"rce-local@15->15", "rce@10->11"},
+			map[string]string{"rce": "CRITICAL", "rce-local": "MEDIUM"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vulns := scan(t, "main.go", localInputSource, tt.configure)
+			if got := findings(vulns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings %v, want %v", got, tt.want)
+			}
+			for _, v := range vulns {
+				if v.Severity != tt.severity[v.RuleID] {
+					t.Errorf("%s severity %s, want %s", v.RuleID, v.Severity, tt.severity[v.RuleID])
+				}
+			}
+		})
+	}
+}
+
+// A sink spec's labels restrict the sources whose taint it accepts
+func TestSinkLabels(t *testing.T) {
+	vulns := scan(t, "main.go", localInputSource, func(c *Config) {
+		for i := range c.Rules {
+			if c.Rules[i].ID == "rce" {
+				c.Rules[i].CallSinks = []SinkSpec{{Callee: "^(os/)?exec\\.Command$", Args: []int{0}, Labels: []string{"http-input"}}}
+			}
+		}
+	})
+	if got, want := findings(vulns), []string{"rce@10->11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+	if len(vulns) == 1 && !reflect.DeepEqual(vulns[0].Labels, []string{"http-input"}) {
+		t.Errorf("labels %v, want [http-input]", vulns[0].Labels)
+	}
+}
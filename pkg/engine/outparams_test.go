@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+const outParamSource = `package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os/exec"
+)
+
+type Job struct{ Cmd string }
+
+func decode(r *http.Request) {
+	var job Job
+	json.NewDecoder(r.Body).Decode(&job)
+	exec.Command(job.Cmd).Run()
+}
+
+func unmarshal(r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var job Job
+	json.Unmarshal(body, &job)
+	exec.Command(job.Cmd).Run()
+}
+
+func constant() {
+	var job Job
+	json.Unmarshal([]byte("{}"), &job)
+	exec.Command(job.Cmd).Run()
+}
+
+func copied(r *http.Request) {
+	dst := make([]byte, 8)
+	copy(dst, r.URL.Query().Get("cmd"))
+	exec.Command(string(dst)).Run()
+}
+`
+
+// Decoding writes the taint of its input through the pointer it is given
+func TestOutParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		want      []string
+	}{
+		{"default", func(*Config) {}, []string{"rce@14->15", "rce@19->22", "rce@33->34"}},
+		{"none", func(c *Config) { c.OutParams = nil }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findings(scan(t, "main.go", outParamSource, tt.configure)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pathsSource = `package main
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func handler(db *sql.DB, r *http.Request) {
+	input := r.URL.Query().Get("q")
+	var arg string
+	if len(input) > 3 {
+		arg = strings.ToLower(input)
+	} else {
+		arg = strings.TrimSpace(input)
+	}
+	exec.Command(arg).Run()
+	db.Query("SELECT * FROM t WHERE a = " + input)
+	os.Open(input)
+}
+`
+
+// One source reaching several sinks yields a finding per sink
+func TestAllSinks(t *testing.T) {
+	vulns := scan(t, "main.go", pathsSource)
+	want := []string{"path-traversal@12->21", "rce@12->19", "sqli@12->20"}
+	if got := findings(vulns); !reflect.DeepEqual(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+}
+
+func TestPathsPerSink(t *testing.T) {
+	tests := []struct {
+		k         int
+		alternate int // Alternate paths of the rce finding
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 1},
+		{5, 1}, // Only two distinct paths exist
+	}
+	for _, tt := range tests {
+		vulns := scan(t, "main.go", pathsSource, func(c *Config) { c.PathsPerSink = tt.k })
+		for _, v := range vulns {
+			if v.RuleID != "rce" {
+				continue
+			}
+			if len(v.AlternatePaths) != tt.alternate {
+				t.Errorf("k=%d: %d alternate paths, want %d", tt.k, len(v.AlternatePaths), tt.alternate)
+			}
+			for _, alt := range v.AlternatePaths {
+				if reflect.DeepEqual(alt, v.Path) {
+					t.Errorf("k=%d: alternate path repeats the first one", tt.k)
+				}
+			}
+		}
+	}
+}
+
+// A solve that runs out of budget stops and is counted, without failing the scan
+func TestPathBudget(t *testing.T) {
+	prog, path := sourceIR(t, "main.go", pathsSource)
+	cfg := DefaultRules()
+	cfg.PathBudget = 2
+	e := NewEngine(cfg)
+	e.AnalyzeIR(prog, path)
+	if e.Stats.BudgetExhausted == 0 {
+		t.Error("no solve stopped at a budget of 2 nodes")
+	}
+	if e.Stats.NodesExplored == 0 {
+		t.Error("no nodes explored")
+	}
+
+	e = NewEngine(DefaultRules())
+	e.AnalyzeIR(prog, path)
+	if e.Stats.BudgetExhausted != 0 {
+		t.Errorf("%d solves stopped at the default budget", e.Stats.BudgetExhausted)
+	}
+}
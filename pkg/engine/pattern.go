@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"fmt"
+	"sast-demo/pkg/core"
+	"sort"
+	"strings"
+)
+
+// PatternMatcher finds structural pattern matches in one parsed source file
+type PatternMatcher interface {
+	Language() string
+	Match(pattern string) ([]core.PatternMatch, error)
+}
+
+// AnalyzePatterns runs every pattern rule for the matcher's language.
+// Invalid patterns are reported as errors without stopping the other rules.
+func (e *Engine) AnalyzePatterns(m PatternMatcher, filePath string) ([]core.Vulnerability, []error) {
+	var vulns []core.Vulnerability
+	var errs []error
+
+	for _, rule := range e.Config.PatternRules {
+		if rule.Language != m.Language() {
+			continue
+		}
+		seen := make(map[string]bool) // One finding per location, even if several patterns match
+		for _, pattern := range rule.Patterns {
+			matches, err := m.Match(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %q: %v", rule.Name, err))
+				continue
+			}
+			for _, match := range matches {
+				key := fmt.Sprintf("%d:%s", match.Line, match.Code)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				node := &core.Node{
+					ID:       fmt.Sprintf("pattern:%d", match.Line),
+					Type:     core.NodeCall,
+					Code:     match.Code,
+					Line:     match.Line,
+					File:     filePath,
+					Function: match.Function,
+				}
+				vulns = append(vulns, core.Vulnerability{
+					Type:        rule.Name,
+					RuleID:      rule.ID,
+					Severity:    rule.Severity,
+					File:        filePath,
+					Line:        match.Line,
+					Description: rule.Description + describeBindings(match.Bindings),
+					Source:      node,
+					Sink:        node,
+					Path:        []*core.Node{node},
+				})
+			}
+		}
+	}
+	return vulns, errs
+}
+
+// describeBindings renders metavariable bindings as " ($X = foo, $Y = bar)"
+func describeBindings(b map[string]string) string {
+	if len(b) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(b))
+	for k := range b {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, k := range names {
+		parts[i] = k + " = " + b[k]
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
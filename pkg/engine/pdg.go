@@ -0,0 +1,553 @@
+package engine
+
+import (
+	"sast-demo/pkg/core"
+	"sort"
+	"strings"
+)
+
+// Program dependence graph and slicing.
+//
+// Each function contributes data dependences (a read depends on the definitions
+// reaching it: the one definition of a temporary, the reaching stores of a
+// variable, every store into its fields) and control dependences (an instruction
+// depends on the branch deciding whether its block runs, computed from
+// post-dominators). Functions are linked as in a system dependence graph: a
+// parameter depends on the arguments passed at each call site (param-in), a call
+// on the returns of its callees (param-out), and the instructions a callee runs
+// unconditionally on the calls of it (call). Summary edges stand for the paths
+// through a callee from a parameter to a return, so a call of a program function
+// depends only on the arguments that can reach its result.
+//
+// Slices follow Horwitz-Reps-Binkley: a backward slice first ascends to callers
+// without entering callees, then enters callees without ascending again, so it
+// only contains call sites that can actually reach the criterion.
+
+// DepKind is the kind of a dependence edge
+type DepKind string
+
+const (
+	DepData     DepKind = "data"
+	DepControl  DepKind = "control"
+	DepParamIn  DepKind = "param-in"  // Parameter on an argument of a call site
+	DepParamOut DepKind = "param-out" // Call on a return of its callee
+	DepCall     DepKind = "call"      // Callee instruction on a call site running it
+	DepSummary  DepKind = "summary"   // Call on an argument reaching the callee's result
+)
+
+// Dep is an edge of the PDG: the instruction depended on and how
+type Dep struct {
+	Inst *core.Instruction
+	Kind DepKind
+}
+
+// PDG is the dependence graph of a program, merged across its functions
+type PDG struct {
+	deps    map[*core.Instruction][]Dep // Instruction -> what it depends on
+	users   map[*core.Instruction][]Dep // Instruction -> what depends on it
+	funcOf  map[*core.Instruction]string
+	byLine  map[int][]*core.Instruction
+	insts   map[string][]*core.Instruction // Function -> its reachable instructions
+	params  map[string][]*core.Instruction // Function -> PARAM instructions
+	returns map[string][]*core.Instruction
+	// Call of a program function -> argument position (-1 for the receiver) ->
+	// definitions of the argument
+	args map[*core.Instruction]map[int][]*core.Instruction
+	// Stores into objects by alias key ("@o3.URL"), and by the keys of enclosing objects
+	pt        *PointsTo
+	heapAt    map[string][]*core.Instruction
+	heapBelow map[string][]*core.Instruction
+}
+
+// BuildPDG builds the dependence graph of prog, resolving calls with pt; with a
+// nil pt the points-to sets are computed first. Blocks constant propagation
+// proved dead are left out.
+func BuildPDG(prog *core.ProgramIR, pt *PointsTo) *PDG {
+	if pt == nil {
+		pt = AnalyzePointsTo(prog)
+	}
+	g := &PDG{
+		deps:    make(map[*core.Instruction][]Dep),
+		users:   make(map[*core.Instruction][]Dep),
+		funcOf:  make(map[*core.Instruction]string),
+		byLine:  make(map[int][]*core.Instruction),
+		insts:   make(map[string][]*core.Instruction),
+		params:  make(map[string][]*core.Instruction),
+		returns: make(map[string][]*core.Instruction),
+		args:    make(map[*core.Instruction]map[int][]*core.Instruction),
+
+		pt:        pt,
+		heapAt:    make(map[string][]*core.Instruction),
+		heapBelow: make(map[string][]*core.Instruction),
+	}
+
+	// Stores by variable, for reads in functions that do not define the name
+	// themselves: package-level variables
+	globals := make(map[string][]*core.Instruction)
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		for _, id := range fn.BlockIDs() {
+			bb := fn.Blocks[id]
+			if bb.Unreachable {
+				continue
+			}
+			for _, inst := range bb.Instructions {
+				g.funcOf[inst] = name
+				g.insts[name] = append(g.insts[name], inst)
+				if inst.Line > 0 {
+					g.byLine[inst.Line] = append(g.byLine[inst.Line], inst)
+				}
+				switch inst.Op {
+				case core.OpParam:
+					g.params[name] = append(g.params[name], inst)
+				case core.OpRet:
+					g.returns[name] = append(g.returns[name], inst)
+				case core.OpStore:
+					globals[pathRoot(inst.Result)] = append(globals[pathRoot(inst.Result)], inst)
+					if storedVar(inst) != "" {
+						break
+					}
+					for _, key := range pt.aliasKeys(name, inst.Result) {
+						g.heapAt[key] = append(g.heapAt[key], inst)
+						for j := strings.LastIndexByte(key, '.'); j > 0; j = strings.LastIndexByte(key[:j], '.') {
+							g.heapBelow[key[:j]] = append(g.heapBelow[key[:j]], inst)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	callees := make(map[*core.Instruction][]string)
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		for _, id := range fn.BlockIDs() {
+			if fn.Blocks[id].Unreachable {
+				continue
+			}
+			for _, inst := range fn.Blocks[id].Instructions {
+				if targets := pt.Callees(name, inst); len(targets) > 0 {
+					callees[inst] = targets
+				}
+			}
+		}
+		g.addDataDeps(name, fn, globals, callees)
+		g.addControlDeps(fn)
+	}
+
+	for call, targets := range callees {
+		for _, target := range targets {
+			g.linkCall(call, target, prog.Functions[target])
+		}
+	}
+	g.addSummaries(prog, callees)
+	return g
+}
+
+func (g *PDG) add(inst *core.Instruction, on *core.Instruction, kind DepKind) {
+	for _, d := range g.deps[inst] {
+		if d.Inst == on && d.Kind == kind {
+			return
+		}
+	}
+	g.deps[inst] = append(g.deps[inst], Dep{on, kind})
+	g.users[on] = append(g.users[on], Dep{inst, kind})
+}
+
+// addDataDeps links the reads of fn to the definitions reaching them, and reads
+// of objects to the stores into them through any alias. The arguments of a call
+// of a program function are left to the summary edges.
+func (g *PDG) addDataDeps(name string, fn *core.FunctionIR, globals map[string][]*core.Instruction, callees map[*core.Instruction][]string) {
+	temps := make(map[string][]*core.Instruction)   // Temporary -> its definition
+	partial := make(map[string][]*core.Instruction) // Variable -> stores into its fields or through it
+	defined := make(map[string]bool)                // Names fn assigns, shadowing globals
+	vars := variables(fn)
+	for _, bb := range fn.Blocks {
+		if bb.Unreachable {
+			continue
+		}
+		for _, inst := range bb.Instructions {
+			if inst.Result != "" {
+				defined[pathRoot(inst.Result)] = true
+			}
+			switch {
+			case inst.Result == "":
+			case storedVar(inst) != "":
+			case inst.Op == core.OpStore:
+				partial[pathRoot(inst.Result)] = append(partial[pathRoot(inst.Result)], inst)
+			case !vars[inst.Result]:
+				temps[inst.Result] = append(temps[inst.Result], inst)
+			}
+		}
+	}
+
+	rd := NewReachingDefinitions(fn)
+	res := SolveDataflow(fn, rd)
+	for _, id := range fn.BlockIDs() {
+		bb := fn.Blocks[id]
+		if bb.Unreachable {
+			continue
+		}
+		reaching := res.Before[id]
+		defsOf := func(inst *core.Instruction, v string) []*core.Instruction {
+			defs := append([]*core.Instruction(nil), temps[v]...)
+			for _, def := range partial[v] {
+				if def != inst {
+					defs = append(defs, def)
+				}
+			}
+			for _, defID := range rd.defs[v] {
+				if reaching[defID] {
+					defs = append(defs, rd.inst[defID])
+				}
+			}
+			if !defined[v] {
+				defs = append(defs, globals[v]...)
+			}
+			return defs
+		}
+		for _, inst := range bb.Instructions {
+			resolved := callees[inst] != nil
+			for _, v := range dataReads(inst, resolved) {
+				for _, def := range defsOf(inst, v) {
+					g.add(inst, def, DepData)
+				}
+			}
+			if !resolved {
+				for _, op := range valueOperands(inst) {
+					for _, def := range g.heapStores(name, op) {
+						if def != inst {
+							g.add(inst, def, DepData)
+						}
+					}
+				}
+			}
+			if resolved {
+				g.args[inst] = make(map[int][]*core.Instruction)
+				for pos := -1; pos < len(inst.Operands)-1; pos++ {
+					if v := argVar(inst, pos); v != "" {
+						g.args[inst][pos] = defsOf(inst, v)
+					}
+				}
+			}
+			reaching = rd.Transfer(inst, reaching)
+		}
+	}
+}
+
+// heapStores lists the stores into the object a path of fn reaches, into its
+// fields, or into an object enclosing it
+func (g *PDG) heapStores(fn, p string) []*core.Instruction {
+	var stores []*core.Instruction
+	for _, key := range g.pt.aliasKeys(fn, p) {
+		stores = append(stores, g.heapBelow[key]...)
+		for k := key; ; {
+			stores = append(stores, g.heapAt[k]...)
+			j := strings.LastIndexByte(k, '.')
+			if j <= 0 {
+				break
+			}
+			k = k[:j]
+		}
+	}
+	return stores
+}
+
+// dataReads lists the names inst reads; a call of a program function only reads
+// its callee value, its arguments are linked through summary edges
+func dataReads(inst *core.Instruction, resolved bool) []string {
+	if !resolved {
+		return readVars(inst)
+	}
+	if len(inst.Operands) > 0 && inst.Operands[0] != "" && isIdentStart(inst.Operands[0][0]) && !strings.Contains(inst.Operands[0], ".") {
+		return []string{inst.Operands[0]}
+	}
+	return nil
+}
+
+// addControlDeps makes the instructions of each block depend on the branches
+// deciding whether it runs: B depends on the branch ending A when B
+// post-dominates a successor of A but does not strictly post-dominate A
+func (g *PDG) addControlDeps(fn *core.FunctionIR) {
+	succs := liveSuccessors(fn)
+	pdom := postDominators(fn, succs)
+	for _, id := range fn.BlockIDs() {
+		bb := fn.Blocks[id]
+		if bb.Unreachable || len(succs[id]) < 2 || len(bb.Instructions) == 0 {
+			continue
+		}
+		branch := bb.Instructions[len(bb.Instructions)-1]
+		for _, s := range succs[id] {
+			for b := range pdom[s] {
+				if b != id && pdom[id][b] {
+					continue
+				}
+				for _, inst := range fn.Blocks[b].Instructions {
+					g.add(inst, branch, DepControl)
+				}
+			}
+		}
+	}
+}
+
+// postDominators maps each reachable block to the blocks on every path from it to
+// an exit, itself included. Blocks that never reach an exit keep every block.
+func postDominators(fn *core.FunctionIR, succs map[string][]string) map[string]map[string]bool {
+	var ids []string
+	for _, id := range fn.BlockIDs() {
+		if !fn.Blocks[id].Unreachable {
+			ids = append(ids, id)
+		}
+	}
+	pdom := make(map[string]map[string]bool, len(ids))
+	for _, id := range ids {
+		if len(succs[id]) == 0 {
+			pdom[id] = map[string]bool{id: true}
+			continue
+		}
+		all := make(map[string]bool, len(ids))
+		for _, other := range ids {
+			all[other] = true
+		}
+		pdom[id] = all
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(ids) - 1; i >= 0; i-- {
+			id := ids[i]
+			if len(succs[id]) == 0 {
+				continue
+			}
+			var next map[string]bool
+			for _, s := range succs[id] {
+				if next == nil {
+					next = make(map[string]bool, len(pdom[s])+1)
+					for b := range pdom[s] {
+						next[b] = true
+					}
+					continue
+				}
+				for b := range next {
+					if !pdom[s][b] {
+						delete(next, b)
+					}
+				}
+			}
+			next[id] = true
+			if len(next) != len(pdom[id]) {
+				pdom[id] = next
+				changed = true
+			}
+		}
+	}
+	return pdom
+}
+
+// linkCall adds the edges between a call and one of its callees
+func (g *PDG) linkCall(call *core.Instruction, target string, fn *core.FunctionIR) {
+	if fn == nil {
+		return
+	}
+	for pos, defs := range g.args[call] {
+		if param := paramInst(fn, paramName(fn, pos)); param != nil {
+			for _, def := range defs {
+				g.add(param, def, DepParamIn)
+			}
+		}
+	}
+	if call.Result != "" {
+		for _, ret := range g.returns[target] {
+			g.add(call, ret, DepParamOut)
+		}
+	}
+	for _, inst := range g.insts[target] {
+		if !g.hasControlDep(inst) {
+			g.add(inst, call, DepCall)
+		}
+	}
+}
+
+// argVar is the variable passed at an argument position of a call (-1 for the receiver)
+func argVar(call *core.Instruction, pos int) string {
+	arg := call.Receiver
+	if pos >= 0 {
+		arg = call.Operands[pos+1]
+	}
+	if v := readVars(&core.Instruction{Op: core.OpLoad, Operands: []string{arg}}); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (g *PDG) hasControlDep(inst *core.Instruction) bool {
+	for _, d := range g.deps[inst] {
+		if d.Kind == DepControl {
+			return true
+		}
+	}
+	return false
+}
+
+// addSummaries adds summary edges until no callee gains a parameter reaching its
+// returns: a call depends on the definitions of the arguments whose parameters do
+func (g *PDG) addSummaries(prog *core.ProgramIR, callees map[*core.Instruction][]string) {
+	calls := make([]*core.Instruction, 0, len(callees))
+	for call := range callees {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].ID < calls[j].ID })
+
+	for changed := true; changed; {
+		changed = false
+		reaching := make(map[string]map[*core.Instruction]bool) // Function -> parameters reaching a return
+		for _, call := range calls {
+			for _, target := range callees[call] {
+				fn := prog.Functions[target]
+				if fn == nil {
+					continue
+				}
+				if reaching[target] == nil {
+					reaching[target] = g.paramsReachingReturns(target)
+				}
+				for pos, defs := range g.args[call] {
+					if !reaching[target][paramInst(fn, paramName(fn, pos))] {
+						continue
+					}
+					for _, def := range defs {
+						if !g.hasDep(call, def, DepSummary) {
+							g.add(call, def, DepSummary)
+							changed = true
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (g *PDG) hasDep(inst, on *core.Instruction, kind DepKind) bool {
+	for _, d := range g.deps[inst] {
+		if d.Inst == on && d.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// paramsReachingReturns walks back from the returns of fn inside fn
+func (g *PDG) paramsReachingReturns(fn string) map[*core.Instruction]bool {
+	seen := make(map[*core.Instruction]bool)
+	work := append([]*core.Instruction(nil), g.returns[fn]...)
+	for _, r := range work {
+		seen[r] = true
+	}
+	for len(work) > 0 {
+		inst := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, d := range g.deps[inst] {
+			if (d.Kind == DepData || d.Kind == DepControl || d.Kind == DepSummary) && !seen[d.Inst] {
+				seen[d.Inst] = true
+				work = append(work, d.Inst)
+			}
+		}
+	}
+	reached := make(map[*core.Instruction]bool)
+	for _, p := range g.params[fn] {
+		if seen[p] {
+			reached[p] = true
+		}
+	}
+	return reached
+}
+
+// Deps returns the dependences of an instruction
+func (g *PDG) Deps(inst *core.Instruction) []Dep { return g.deps[inst] }
+
+// InstructionsAt returns the reachable instructions generated for a source line
+func (g *PDG) InstructionsAt(line int) []*core.Instruction { return g.byLine[line] }
+
+// Slice is the set of instructions that may influence (backward) or be
+// influenced by (forward) a criterion
+type Slice struct {
+	Direction string       `json:"direction"`
+	Criterion []*SliceNode `json:"criterion"`
+	Nodes     []*SliceNode `json:"nodes"` // Sorted by line
+	Lines     []int        `json:"lines"` // Source lines of the nodes
+}
+
+type SliceNode struct {
+	ID       string `json:"id"`
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+	Code     string `json:"code"`
+}
+
+// Slice computes the slice of the criterion instructions in one direction
+func (g *PDG) Slice(criterion []*core.Instruction, dir Direction) *Slice {
+	// Backward: ascend to callers first (no param-out), then descend (no param-in, call).
+	// Forward: ascend through returns first (no param-in, call), then descend (no param-out).
+	edges, first, second := g.deps, DepParamOut, []DepKind{DepParamIn, DepCall}
+	if dir == Forward {
+		edges, first, second = g.users, DepParamIn, []DepKind{DepParamOut}
+	}
+	in := make(map[*core.Instruction]bool)
+	walk := func(start []*core.Instruction, skip ...DepKind) {
+		work := append([]*core.Instruction(nil), start...)
+		for _, inst := range work {
+			in[inst] = true
+		}
+		for len(work) > 0 {
+			inst := work[len(work)-1]
+			work = work[:len(work)-1]
+			for _, d := range edges[inst] {
+				if in[d.Inst] || containsKind(skip, d.Kind) || dir == Forward && d.Kind == DepCall {
+					continue
+				}
+				in[d.Inst] = true
+				work = append(work, d.Inst)
+			}
+		}
+	}
+	walk(criterion, first)
+	var reached []*core.Instruction
+	for inst := range in {
+		reached = append(reached, inst)
+	}
+	walk(reached, second...)
+
+	s := &Slice{Direction: dir.String()}
+	lines := make(map[int]bool)
+	for inst := range in {
+		s.Nodes = append(s.Nodes, g.sliceNode(inst))
+		if inst.Line > 0 {
+			lines[inst.Line] = true
+		}
+	}
+	sort.Slice(s.Nodes, func(i, j int) bool {
+		if s.Nodes[i].Line != s.Nodes[j].Line {
+			return s.Nodes[i].Line < s.Nodes[j].Line
+		}
+		return s.Nodes[i].ID < s.Nodes[j].ID
+	})
+	for _, inst := range criterion {
+		s.Criterion = append(s.Criterion, g.sliceNode(inst))
+	}
+	for line := range lines {
+		s.Lines = append(s.Lines, line)
+	}
+	sort.Ints(s.Lines)
+	return s
+}
+
+func (g *PDG) sliceNode(inst *core.Instruction) *SliceNode {
+	return &SliceNode{ID: inst.ID, Function: g.funcOf[inst], Line: inst.Line, Code: inst.Code}
+}
+
+func containsKind(kinds []DepKind, k DepKind) bool {
+	for _, kind := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+const sliceSource = `package main
+
+func helper(x string) string { return x + "!" }
+
+func main() {
+	a := "1"
+	b := "2"
+	c := helper(a)
+	println(c)
+	println(b)
+}
+`
+
+func TestSlice(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     int
+		dir      Direction
+		want     []int
+		excluded []int
+	}{
+		{"backward through a call", 9, Backward, []int{3, 6, 8, 9}, []int{7, 10}},
+		{"backward of an unrelated line", 10, Backward, []int{7, 10}, []int{6, 8, 9}},
+		{"forward into and out of a call", 6, Forward, []int{3, 6, 8, 9}, []int{7, 10}},
+	}
+	prog, _ := sourceIR(t, "slice.go", sliceSource)
+	MarkDeadCode(prog)
+	// Without points-to sets they are computed
+	pdg := BuildPDG(prog, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := pdg.Slice(pdg.InstructionsAt(tt.line), tt.dir)
+			for _, l := range tt.want {
+				if !slices.Contains(s.Lines, l) {
+					t.Errorf("line %d missing from %v", l, s.Lines)
+				}
+			}
+			for _, l := range tt.excluded {
+				if slices.Contains(s.Lines, l) {
+					t.Errorf("line %d in %v", l, s.Lines)
+				}
+			}
+		})
+	}
+}
+
+func TestSliceSkipsDeadBranches(t *testing.T) {
+	prog, _ := sourceIR(t, "dead.go", `package main
+
+func main() {
+	x := "a"
+	if false {
+		x = "b"
+	}
+	println(x)
+}
+`)
+	MarkDeadCode(prog)
+	pdg := BuildPDG(prog, AnalyzePointsTo(prog))
+	s := pdg.Slice(pdg.InstructionsAt(8), Backward)
+	if !slices.Contains(s.Lines, 4) || slices.Contains(s.Lines, 6) {
+		t.Errorf("backward slice of line 8: %v, want 4 and not the dead store on 6", s.Lines)
+	}
+}
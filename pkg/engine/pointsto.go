@@ -0,0 +1,498 @@
+package engine
+
+import (
+	"fmt"
+	"sast-demo/pkg/core"
+	"sort"
+	"strings"
+)
+
+// Andersen-style points-to analysis over the IR: flow-insensitive and
+// context-insensitive, solved as inclusion constraints to a fixed point.
+//
+// Abstract objects are allocation sites (composite literals, new, make, Java
+// `new`), the storage of variables whose address is taken or whose fields are
+// accessed, and functions used as values. Each object has field nodes, so that
+// `p := &s; p.Name = v` and `s.Name` meet at the same node. Values are named per
+// function; calls to functions of the program bind arguments to parameters and
+// returns to results, with dynamic and interface calls resolved from the
+// points-to sets of the function value or the receiver. The variables a closure
+// captures are those of the function enclosing it (see scope.go).
+
+// Object is an abstract memory location
+type Object struct {
+	ID   string            `json:"id"`             // "@o3"; never a valid identifier
+	Kind string            `json:"kind"`           // "alloc", "var" or "func"
+	Type string            `json:"type,omitempty"` // Allocated type, or the function name
+	Name string            `json:"name,omitempty"` // Variable whose storage this is
+	Func string            `json:"func,omitempty"` // Function of the allocation site or variable
+	Site *core.Instruction `json:"-"`
+}
+
+func (o *Object) String() string {
+	switch o.Kind {
+	case "var":
+		return fmt.Sprintf("&%s (%s)", o.Name, o.Func)
+	case "func":
+		return "func " + o.Type
+	}
+	return fmt.Sprintf("%s at L%d (%s)", o.Type, o.Site.Line, o.Func)
+}
+
+// PointsTo holds the solved points-to sets of a program
+type PointsTo struct {
+	prog    *core.ProgramIR
+	Objects []*Object
+
+	pts     map[string]map[int]bool // Node -> indexes into Objects
+	varLocs map[string]int          // Function-qualified variable -> its storage object
+	sites   map[*core.Instruction]int
+	funcs   map[string]int
+	scopes  *scopes
+	defs    map[string]*core.Instruction // Function-qualified value -> defining instruction
+	temps   map[string]bool              // Values defined by something other than a store or parameter
+	pointed map[int]bool                 // Objects some value points to, once solved
+
+	rules []func() bool
+}
+
+// AnalyzePointsTo computes the points-to sets of every value in prog
+func AnalyzePointsTo(prog *core.ProgramIR) *PointsTo {
+	pt := &PointsTo{
+		prog:    prog,
+		scopes:  newScopes(prog),
+		pts:     make(map[string]map[int]bool),
+		varLocs: make(map[string]int),
+		sites:   make(map[*core.Instruction]int),
+		funcs:   make(map[string]int),
+		defs:    make(map[string]*core.Instruction),
+		temps:   make(map[string]bool),
+	}
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		for _, id := range fn.BlockIDs() {
+			for _, inst := range fn.Blocks[id].Instructions {
+				if inst.Result == "" {
+					continue
+				}
+				pt.defs[pt.node(name, inst.Result)] = inst
+				if inst.Op != core.OpStore && inst.Op != core.OpParam {
+					pt.temps[inst.Result] = true
+				}
+			}
+		}
+	}
+	for _, name := range prog.FunctionNames() {
+		fn := prog.Functions[name]
+		for _, id := range fn.BlockIDs() {
+			if fn.Blocks[id].Unreachable {
+				continue
+			}
+			for _, inst := range fn.Blocks[id].Instructions {
+				pt.constrain(name, inst)
+			}
+		}
+	}
+
+	// Rules are monotone; rerun them until no set grows
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range pt.rules {
+			if rule() {
+				changed = true
+			}
+		}
+	}
+	pt.pointed = make(map[int]bool)
+	for _, set := range pt.pts {
+		for o := range set {
+			pt.pointed[o] = true
+		}
+	}
+	return pt
+}
+
+// node names a value of a function in the constraint graph
+func node(fn, v string) string {
+	return fn + "/" + v
+}
+
+// node names a value named in fn; a variable a closure shares with an enclosing
+// function is the enclosing function's
+func (pt *PointsTo) node(fn, v string) string {
+	if owner := pt.scopes.owner(fn, v); owner != "" {
+		fn = owner
+	}
+	return node(fn, v)
+}
+
+// fieldNode names a field path (".URL", "" for the whole object) of an object
+func (pt *PointsTo) fieldNode(o int, fields string) string {
+	obj := pt.Objects[o]
+	if fields == "" && obj.Kind == "var" {
+		// The content of a variable's storage is the variable itself
+		return pt.node(obj.Func, obj.Name)
+	}
+	return obj.ID + fields
+}
+
+func (pt *PointsTo) newObject(o *Object) int {
+	o.ID = fmt.Sprintf("@o%d", len(pt.Objects))
+	pt.Objects = append(pt.Objects, o)
+	return len(pt.Objects) - 1
+}
+
+func (pt *PointsTo) varLoc(fn, v string) int {
+	if owner := pt.scopes.owner(fn, v); owner != "" {
+		fn = owner
+	}
+	key := node(fn, v)
+	if o, ok := pt.varLocs[key]; ok {
+		return o
+	}
+	o := pt.newObject(&Object{Kind: "var", Name: v, Func: fn})
+	pt.varLocs[key] = o
+	return o
+}
+
+func (pt *PointsTo) funcObject(name string) int {
+	if o, ok := pt.funcs[name]; ok {
+		return o
+	}
+	o := pt.newObject(&Object{Kind: "func", Type: name})
+	pt.funcs[name] = o
+	return o
+}
+
+func (pt *PointsTo) add(n string, o int) bool {
+	set := pt.pts[n]
+	if set == nil {
+		set = make(map[int]bool)
+		pt.pts[n] = set
+	}
+	if set[o] {
+		return false
+	}
+	set[o] = true
+	return true
+}
+
+// copyInto adds pts(src) to pts(dst)
+func (pt *PointsTo) copyInto(dst, src string) bool {
+	changed := false
+	for o := range pt.pts[src] {
+		if pt.add(dst, o) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// sorted returns a node's objects in a fixed order, which also makes it safe to
+// iterate while the set grows
+func (pt *PointsTo) sorted(n string) []int {
+	objs := make([]int, 0, len(pt.pts[n]))
+	for o := range pt.pts[n] {
+		objs = append(objs, o)
+	}
+	sort.Ints(objs)
+	return objs
+}
+
+// splitPath separates an access path into a dereference marker, its root and its fields
+func splitPath(p string) (deref bool, root, fields string) {
+	if strings.HasPrefix(p, "*") {
+		deref, p = true, p[1:]
+	}
+	if i := strings.IndexByte(p, '.'); i >= 0 {
+		return deref, p[:i], p[i:]
+	}
+	return deref, p, ""
+}
+
+// bases are the objects an access path selects fields of: what a pointer or
+// reference root points to, and the root variable's own storage unless it is dereferenced
+func (pt *PointsTo) bases(fn, p string) []int {
+	deref, root, _ := splitPath(p)
+	objs := pt.sorted(pt.node(fn, root))
+	if !deref {
+		objs = append(objs, pt.varLoc(fn, root))
+	}
+	return objs
+}
+
+// pathNodes are the nodes an access path reads or writes
+func (pt *PointsTo) pathNodes(fn, p string) []string {
+	deref, root, fields := splitPath(p)
+	if !deref && fields == "" {
+		return []string{pt.node(fn, root)}
+	}
+	var nodes []string
+	for _, o := range pt.bases(fn, p) {
+		nodes = append(nodes, pt.fieldNode(o, fields))
+	}
+	return nodes
+}
+
+func (pt *PointsTo) constrain(fn string, inst *core.Instruction) {
+	ops := inst.Operands
+	res := pt.node(fn, inst.Result)
+	switch inst.Op {
+	case core.OpLoad:
+		if len(ops) == 0 {
+			return
+		}
+		// Functions used as values, function literals (handler.func1) included
+		if _, root, _ := splitPath(ops[0]); pt.prog.Functions[ops[0]] != nil && pt.defs[pt.node(fn, root)] == nil {
+			pt.add(res, pt.funcObject(ops[0]))
+			return
+		}
+		for _, p := range ops {
+			pt.rules = append(pt.rules, func() bool {
+				changed := false
+				for _, n := range pt.pathNodes(fn, p) {
+					if pt.copyInto(res, n) {
+						changed = true
+					}
+				}
+				// Loading from a computed container reads its elements
+				if _, root, fields := splitPath(p); fields == "" && pt.temps[root] {
+					for _, o := range pt.sorted(pt.node(fn, root)) {
+						if pt.copyInto(res, pt.fieldNode(o, "")) {
+							changed = true
+						}
+					}
+				}
+				return changed
+			})
+		}
+
+	case core.OpStore:
+		if len(ops) == 0 || inst.Result == "" {
+			return
+		}
+		src := pt.node(fn, ops[0])
+		pt.rules = append(pt.rules, func() bool {
+			changed := false
+			for _, n := range pt.pathNodes(fn, inst.Result) {
+				if pt.copyInto(n, src) {
+					changed = true
+				}
+			}
+			return changed
+		})
+
+	case core.OpPhi:
+		for _, op := range ops {
+			src := pt.node(fn, op)
+			pt.rules = append(pt.rules, func() bool { return pt.copyInto(res, src) })
+		}
+
+	case core.OpBinOp:
+		if len(ops) != 2 {
+			return
+		}
+		x := pt.node(fn, ops[1])
+		switch ops[0] {
+		case "&":
+			// &v takes the address of a variable; &T{} passes the literal's object on
+			if def := pt.defs[x]; def != nil && def.Op == core.OpLoad && len(def.Operands) > 0 {
+				if deref, root, fields := splitPath(def.Operands[0]); !deref && fields == "" && !pt.temps[root] {
+					pt.add(res, pt.varLoc(fn, root))
+					return
+				}
+			}
+			pt.rules = append(pt.rules, func() bool { return pt.copyInto(res, x) })
+		case "*":
+			pt.rules = append(pt.rules, func() bool {
+				changed := false
+				for _, o := range pt.sorted(x) {
+					if pt.copyInto(res, pt.fieldNode(o, "")) {
+						changed = true
+					}
+				}
+				return changed
+			})
+		}
+
+	case core.OpCall:
+		if len(ops) == 0 {
+			return
+		}
+		if typ, ok := pt.allocType(fn, inst); ok {
+			o, seen := pt.sites[inst]
+			if !seen {
+				o = pt.newObject(&Object{Kind: "alloc", Type: typ, Func: fn, Site: inst})
+				pt.sites[inst] = o
+			}
+			pt.add(res, o)
+			// Elements of literals and arrays are the content of the new object
+			if strings.HasSuffix(ops[0], "{}") || ops[0] == "new[]" {
+				for _, a := range ops[1:] {
+					src := pt.node(fn, a)
+					pt.rules = append(pt.rules, func() bool { return pt.copyInto(pt.fieldNode(o, ""), src) })
+				}
+			}
+			if !strings.HasPrefix(ops[0], "new ") {
+				return
+			}
+		}
+		pt.rules = append(pt.rules, func() bool {
+			changed := false
+			for _, target := range pt.Callees(fn, inst) {
+				if pt.bindCall(fn, inst, target) {
+					changed = true
+				}
+			}
+			return changed
+		})
+	}
+}
+
+// allocType reports whether a call allocates an object, and of which type
+func (pt *PointsTo) allocType(fn string, inst *core.Instruction) (string, bool) {
+	callee := inst.Operands[0]
+	switch {
+	case strings.HasSuffix(callee, "{}"):
+		return strings.TrimSuffix(callee, "{}"), true
+	case strings.HasPrefix(callee, "new "):
+		return strings.TrimPrefix(callee, "new "), true
+	case callee == "new[]":
+		return "[]", true
+	case callee == "new" || callee == "make":
+		// new(T): the type is loaded as the first argument
+		if len(inst.Operands) > 1 {
+			if def := pt.defs[pt.node(fn, inst.Operands[1])]; def != nil && def.Op == core.OpLoad && len(def.Operands) > 0 {
+				return def.Operands[0], true
+			}
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// bindCall flows arguments into the parameters of target and its returns into the call result
+func (pt *PointsTo) bindCall(fn string, inst *core.Instruction, target string) bool {
+	callee := pt.prog.Functions[target]
+	changed := false
+	for i, p := range callee.Params() {
+		if i+1 < len(inst.Operands) && pt.copyInto(pt.node(target, p), pt.node(fn, inst.Operands[i+1])) {
+			changed = true
+		}
+	}
+	if callee.Receiver != "" && inst.Receiver != "" && pt.copyInto(pt.node(target, callee.Receiver), pt.node(fn, inst.Receiver)) {
+		changed = true
+	}
+	if inst.Result == "" {
+		return changed
+	}
+	for _, id := range callee.BlockIDs() {
+		for _, ret := range callee.Blocks[id].Instructions {
+			if ret.Op == core.OpRet && len(ret.Operands) > 0 && pt.copyInto(pt.node(fn, inst.Result), pt.node(target, ret.Operands[0])) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Callees lists the functions of the program a call in fn may invoke: the function
+// it names, functions held by the called variable, and the methods of the types
+// the receiver may point to
+func (pt *PointsTo) Callees(fn string, call *core.Instruction) []string {
+	if call.Op != core.OpCall || len(call.Operands) == 0 {
+		return nil
+	}
+	callee := call.Operands[0]
+	seen := make(map[string]bool)
+	var targets []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			targets = append(targets, name)
+		}
+	}
+
+	for _, name := range pt.prog.FunctionNames() {
+		if matchesFunction(name, callee) {
+			add(name)
+		}
+	}
+	for _, o := range pt.sorted(pt.node(fn, callee)) {
+		if obj := pt.Objects[o]; obj.Kind == "func" {
+			add(obj.Type)
+		}
+	}
+	if call.Receiver != "" {
+		method := callee[strings.LastIndexByte(callee, '.')+1:]
+		for _, o := range pt.sorted(pt.node(fn, call.Receiver)) {
+			obj := pt.Objects[o]
+			if obj.Kind != "alloc" || obj.Type == "" {
+				continue
+			}
+			for _, name := range pt.prog.FunctionNames() {
+				if matchesFunction(name, obj.Type+"."+method) {
+					add(name)
+				}
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// matchesFunction reports whether a call of callee names the function: exactly,
+// or as the tail of a package-qualified name (Demo.run for com.acme.Demo.run),
+// overloads (Demo.run#2) included
+func matchesFunction(fnName, callee string) bool {
+	if i := strings.LastIndexByte(fnName, '#'); i >= 0 {
+		fnName = fnName[:i]
+	}
+	if fnName == callee {
+		return true
+	}
+	return strings.Contains(callee, ".") && strings.HasSuffix(fnName, "."+callee)
+}
+
+// PointsToSet returns the objects a value of fn may point to
+func (pt *PointsTo) PointsToSet(fn, v string) []*Object {
+	var objs []*Object
+	for _, o := range pt.sorted(pt.node(fn, v)) {
+		objs = append(objs, pt.Objects[o])
+	}
+	return objs
+}
+
+// MayAlias reports whether two values may point to the same object
+func (pt *PointsTo) MayAlias(fn1, v1, fn2, v2 string) bool {
+	for o := range pt.pts[pt.node(fn1, v1)] {
+		if pt.pts[pt.node(fn2, v2)][o] {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasKeys names the memory an access path refers to independently of the
+// variable used to reach it: `p.Name` with p pointing to s's storage and `s.Name`
+// share a key. A whole variable only has a key when its address is taken.
+func (pt *PointsTo) aliasKeys(fn, p string) []string {
+	deref, root, fields := splitPath(p)
+	if root == "" || (pt.temps[root] && !deref && fields == "") {
+		return nil
+	}
+	var keys []string
+	if !deref && fields == "" {
+		if o, ok := pt.varLocs[pt.node(fn, root)]; ok && pt.pointed[o] {
+			keys = append(keys, pt.Objects[o].ID)
+		}
+		return keys
+	}
+	for _, o := range pt.sorted(pt.node(fn, root)) {
+		keys = append(keys, pt.Objects[o].ID+fields)
+	}
+	if !deref {
+		keys = append(keys, pt.Objects[pt.varLoc(fn, root)].ID+fields)
+	}
+	return keys
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"strings"
+	"testing"
+)
+
+const aliasSource = `package main
+
+import (
+	"net/http"
+)
+
+type User struct{ Name string }
+
+type Fetcher interface{ Fetch(u string) }
+
+type Direct struct{}
+
+func (d *Direct) Fetch(u string) { http.Get(u) }
+
+type Logged struct{}
+
+func (l *Logged) Fetch(u string) {}
+
+func alias(r *http.Request) {
+	var s User
+	p := &s
+	q := &s
+	other := &User{}
+	p.Name = r.URL.Query().Get("name")
+	http.Get(s.Name)
+	http.Get(other.Name)
+	_ = q
+}
+
+func dispatch(r *http.Request) {
+	var f Fetcher = &Direct{}
+	f.Fetch(r.URL.Query().Get("u"))
+	var g Fetcher = &Logged{}
+	g.Fetch(r.URL.Query().Get("u"))
+}
+`
+
+func TestPointsTo(t *testing.T) {
+	prog, _ := sourceIR(t, "main.go", aliasSource)
+	pt := AnalyzePointsTo(prog)
+
+	if objs := pt.PointsToSet("alias", "p"); len(objs) != 1 || objs[0].Kind != "var" || objs[0].Name != "s" {
+		t.Errorf("p points to %v, want &s", objs)
+	}
+	if objs := pt.PointsToSet("alias", "other"); len(objs) != 1 || objs[0].Kind != "alloc" || objs[0].Site.Line != 23 {
+		t.Errorf("other points to %v, want the allocation at line 23", objs)
+	}
+	if !pt.MayAlias("alias", "p", "alias", "q") {
+		t.Error("p and q, both &s, do not alias")
+	}
+	if pt.MayAlias("alias", "p", "alias", "other") {
+		t.Error("p aliases a separate allocation")
+	}
+	// Values of another function are other variables, whatever their names
+	if pt.MayAlias("alias", "p", "dispatch", "p") {
+		t.Error("p of alias aliases an undefined p of dispatch")
+	}
+
+	// Interface calls resolve to the methods of the types the receiver points to
+	callees := make(map[int][]string)
+	for _, bb := range prog.Functions["dispatch"].Blocks {
+		for _, inst := range bb.Instructions {
+			if inst.Op == core.OpCall && strings.HasSuffix(inst.Operands[0], ".Fetch") {
+				callees[inst.Line] = pt.Callees("dispatch", inst)
+			}
+		}
+	}
+	want := map[int][]string{32: {"Direct.Fetch"}, 34: {"Logged.Fetch"}}
+	if !reflect.DeepEqual(callees, want) {
+		t.Errorf("callees %v, want %v", callees, want)
+	}
+}
+
+// A store through a pointer taints loads through the variable it points to,
+// and an interface call only reaches the sinks of the implementations it may run
+func TestAliasFindings(t *testing.T) {
+	if got, want := findings(scan(t, "main.go", aliasSource)), []string{"ssrf@24->25", "ssrf@32->13"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"sast-demo/pkg/core"
+	"strings"
+)
+
+// Variable scopes of closures.
+//
+// The Go frontend lowers a function literal to a function of its own named after
+// the function enclosing it (`handler.func1`, `handler.func1.func2`). Its free
+// variables keep their names: `ch` in handler.func1 is handler's `ch` unless the
+// literal declares it as a parameter. A variable belongs to the innermost function
+// on the chain that has it as a parameter, else to the outermost that assigns it,
+// else to no function (a package-level variable). Values of functions outside the
+// chain never share it, whatever their names.
+
+// scopes resolves the function that owns a variable named in another one
+type scopes struct {
+	params    map[string]map[string]bool // Function -> its parameters
+	assigns   map[string]map[string]bool // Function -> whole variables it assigns
+	receivers map[string]string          // Method -> the name its receiver is bound to
+	owners    map[[2]string]string       // (function, variable) -> owner, as resolved so far
+}
+
+func newScopes(prog *core.ProgramIR) *scopes {
+	s := &scopes{
+		params:    make(map[string]map[string]bool),
+		assigns:   make(map[string]map[string]bool),
+		receivers: make(map[string]string),
+		owners:    make(map[[2]string]string),
+	}
+	for name, fn := range prog.Functions {
+		s.receivers[name] = fn.Receiver
+		s.params[name] = make(map[string]bool)
+		s.assigns[name] = make(map[string]bool)
+		for _, bb := range fn.Blocks {
+			for _, inst := range bb.Instructions {
+				switch {
+				case inst.Op == core.OpParam:
+					s.params[name][inst.Result] = true
+				case inst.Result != "" && !strings.ContainsAny(inst.Result, ".*"):
+					s.assigns[name][inst.Result] = true
+				}
+			}
+		}
+	}
+	return s
+}
+
+// owner returns the function whose variable the root of access path p is when
+// named in fn, "" for a package-level variable
+func (s *scopes) owner(fn, p string) string {
+	v := pathRoot(p)
+	key := [2]string{fn, v}
+	if owner, ok := s.owners[key]; ok {
+		return owner
+	}
+	owner := ""
+	for f := fn; f != ""; f = enclosingFunc(f) {
+		if s.params[f][v] {
+			owner = f
+			break
+		}
+		if s.assigns[f][v] {
+			owner = f
+		}
+	}
+	s.owners[key] = owner
+	return owner
+}
+
+// enclosingFunc is the function a function literal is nested in (handler for
+// handler.func1), "" for a declared function
+func enclosingFunc(name string) string {
+	i := strings.LastIndex(name, ".func")
+	if i <= 0 {
+		return ""
+	}
+	n := name[i+len(".func"):]
+	if n == "" || strings.Trim(n, "0123456789") != "" {
+		return ""
+	}
+	return name[:i]
+}
+
+// outermostFunc is the declared function a function literal is nested in, or
+// name itself for a declared function
+func outermostFunc(name string) string {
+	for outer := enclosingFunc(name); outer != ""; outer = enclosingFunc(outer) {
+		name = outer
+	}
+	return name
+}
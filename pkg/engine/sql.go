@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"sast-demo/pkg/core"
+	"slices"
+	"strings"
+)
+
+// SQL contexts.
+//
+// How dangerous a tainted value in a query is depends on where it lands. Inside a
+// quoted literal it has to break out of the quotes; in a numeric position (`id = `,
+// `LIMIT `) anything it holds is SQL; as a table or column name, or in ORDER BY, it
+// cannot be bound as a parameter and needs an allowlist; bound to a `?` or `$1`
+// placeholder it is data the driver never parses. For rules with QueryLanguage
+// QuerySQL the query rebuilt by the string model (strings.go) is run through a
+// small tokenizer that follows quotes and clause keywords up to each tainted value,
+// and the finding takes the severity and confidence of its most dangerous one.
+
+// Where a tainted value lies in a query (core.Vulnerability.QueryContext)
+const (
+	SQLQuoted      = "quoted"      // Inside a quoted string literal
+	SQLNumeric     = "numeric"     // An unquoted value: after an operator, in a list, LIMIT
+	SQLIdentifier  = "identifier"  // A table or column name
+	SQLOrderBy     = "order-by"    // A column or direction in ORDER BY or GROUP BY
+	SQLFragment    = "fragment"    // A whole condition, clause or statement
+	SQLPlaceholder = "placeholder" // An argument bound to a placeholder of the query
+)
+
+// sqlAssessment is the severity and confidence of a finding by where its taint lies
+type sqlAssessment struct {
+	severity   string
+	confidence string
+}
+
+var sqlAssessments = map[string]sqlAssessment{
+	SQLFragment:    {"HIGH", "high"},
+	SQLNumeric:     {"HIGH", "high"},
+	SQLQuoted:      {"HIGH", "high"},
+	SQLIdentifier:  {"MEDIUM", "medium"},
+	SQLOrderBy:     {"MEDIUM", "medium"},
+	SQLPlaceholder: {"LOW", "low"},
+}
+
+// boundValue is the assessment of a value printed as a number (%d), whatever its context
+var boundValue = sqlAssessment{"LOW", "low"}
+
+var severityRank = map[string]int{"LOW": 1, "MEDIUM": 2, "HIGH": 3, "CRITICAL": 4}
+var confidenceRank = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+// sqlClauses are the keywords that start a clause; "BY" completes ORDER, GROUP and PARTITION
+var sqlClauses = map[string]bool{
+	"SELECT": true, "FROM": true, "JOIN": true, "WHERE": true, "ON": true, "USING": true,
+	"HAVING": true, "SET": true, "VALUES": true, "INTO": true, "UPDATE": true, "TABLE": true,
+	"LIMIT": true, "OFFSET": true, "FETCH": true,
+}
+
+// sqlIdentifierClauses are the clauses whose bare words name tables or columns
+var sqlIdentifierClauses = map[string]bool{
+	"SELECT": true, "FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true, "USING": true,
+}
+
+// sqlOperators are the tokens after which a value is expected
+var sqlOperators = map[string]bool{
+	"=": true, "<": true, ">": true, "!": true, "(": true, ",": true, "+": true, "-": true,
+	"*": true, "/": true, "%": true, "|": true, "LIKE": true, "ILIKE": true, "IN": true,
+	"IS": true, "BETWEEN": true, "NOT": true,
+}
+
+// sqlScanner follows a query's text, keeping what decides the context of the
+// next value
+type sqlScanner struct {
+	quote        byte   // The open quote, 0 outside quotes
+	escaped      bool   // The previous character was a backslash inside quotes
+	word         []byte // The word being read
+	prev         string // The last token outside quotes, upper-cased
+	clause       string // The last clause keyword ("WHERE", "ORDER BY" ...)
+	placeholders int    // ? and $n placeholders seen outside quotes
+}
+
+func (sc *sqlScanner) text(t string) {
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		if sc.quote != 0 {
+			switch {
+			case sc.escaped:
+				sc.escaped = false
+			case c == '\\':
+				sc.escaped = true
+			case c == sc.quote && c != '[' || sc.quote == '[' && c == ']':
+				// A doubled quote closes and reopens the literal
+				sc.quote = 0
+				sc.prev = "LITERAL"
+			}
+			continue
+		}
+		switch {
+		case c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9':
+			sc.word = append(sc.word, c)
+		case c == '-' && i+1 < len(t) && t[i+1] == '-':
+			// A comment runs to the end of the line
+			sc.endWord()
+			if j := strings.IndexByte(t[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(t)
+			}
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			sc.endWord()
+			sc.quote = c
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			sc.endWord()
+		default:
+			sc.endWord()
+			if c == '?' {
+				sc.placeholders++
+			}
+			sc.prev = string(c)
+		}
+	}
+}
+
+func (sc *sqlScanner) endWord() {
+	if len(sc.word) == 0 {
+		return
+	}
+	w := strings.ToUpper(string(sc.word))
+	sc.word = sc.word[:0]
+	switch {
+	case w == "BY" && (sc.prev == "ORDER" || sc.prev == "GROUP" || sc.prev == "PARTITION"):
+		sc.clause = sc.prev + " BY"
+	case sqlClauses[w]:
+		sc.clause = w
+	case len(w) > 1 && w[0] == '$' && strings.Trim(w[1:], "0123456789") == "":
+		sc.placeholders++
+	}
+	sc.prev = w
+}
+
+// value returns the context of a value inserted at the current position and
+// moves past it
+func (sc *sqlScanner) value() string {
+	if sc.quote != 0 {
+		if sc.quote == '`' || sc.quote == '[' {
+			return SQLIdentifier
+		}
+		return SQLQuoted
+	}
+	// A value glued to a word continues it: `tbl_` + suffix is still a name
+	glued := len(sc.word) > 0
+	sc.endWord()
+	ctx := SQLFragment
+	switch {
+	case sc.prev == ".":
+		ctx = SQLIdentifier
+	case sc.clause == "ORDER BY" || sc.clause == "GROUP BY" || sc.clause == "PARTITION BY":
+		ctx = SQLOrderBy
+	case sc.clause == "LIMIT" || sc.clause == "OFFSET" || sc.clause == "FETCH":
+		ctx = SQLNumeric
+	case sqlIdentifierClauses[sc.clause] && (glued || sc.prev == sc.clause || sc.prev == ","):
+		ctx = SQLIdentifier
+	case sqlOperators[sc.prev]:
+		ctx = SQLNumeric
+	}
+	sc.prev = "VALUE"
+	return ctx
+}
+
+// sqlContexts returns the context of each tainted value of a query, and how
+// many placeholders the query has
+func sqlContexts(parts []core.StringPart) (map[int]string, int) {
+	var sc sqlScanner
+	ctxs := make(map[int]string)
+	for i, p := range parts {
+		if !p.Value {
+			sc.text(p.Text)
+			continue
+		}
+		if ctx := sc.value(); p.Tainted {
+			ctxs[i] = ctx
+		}
+	}
+	return ctxs, sc.placeholders
+}
+
+// describeSQL sets the query context, severity and confidence of a finding of
+// a rule with QuerySQL queries. The taint reaches either the query itself, or an
+// argument following a query with placeholders, bound to one of them.
+func (s *stringModel) describeSQL(v *core.Vulnerability, path []*core.Instruction) {
+	ctx, a := "", sqlAssessment{}
+	if parts := v.StringParts; parts != nil {
+		ctxs, _ := sqlContexts(parts)
+		for i, c := range ctxs {
+			next := sqlAssessments[c]
+			if !printsText(parts[i].Verb) && parts[i].Verb != "" {
+				next = boundValue
+			}
+			if ctx == "" || worse(next, a) || next == a && c < ctx {
+				ctx, a = c, next
+			}
+		}
+	} else if s.boundArgument(path) {
+		ctx, a = SQLPlaceholder, sqlAssessments[SQLPlaceholder]
+	}
+	if ctx == "" {
+		return
+	}
+	v.QueryContext = ctx
+	v.Confidence = a.confidence
+	// The rule's severity is the most a finding is given
+	if severityRank[a.severity] < severityRank[v.Severity] {
+		v.Severity = a.severity
+	}
+}
+
+// worse reports whether assessment a is more severe than b
+func worse(a, b sqlAssessment) bool {
+	if severityRank[a.severity] != severityRank[b.severity] {
+		return severityRank[a.severity] > severityRank[b.severity]
+	}
+	return confidenceRank[a.confidence] > confidenceRank[b.confidence]
+}
+
+// boundArgument reports whether a taint path enters its sink call through an
+// argument following a query that has placeholders, as in
+// `jdbcTemplate.query("... WHERE id = ?", id)`
+func (s *stringModel) boundArgument(path []*core.Instruction) bool {
+	if len(path) < 2 {
+		return false
+	}
+	sink, prev := path[len(path)-1], path[len(path)-2]
+	if sink.Op != core.OpCall || prev.Result == "" || len(sink.Operands) < 2 {
+		return false
+	}
+	fn := s.instToFunc[sink.ID]
+	args := sink.Operands[1:]
+	at := slices.Index(args, prev.Result)
+	for _, arg := range args[:max(at, 0)] {
+		parts := s.parts(fn, arg, nil, 0)
+		if slices.ContainsFunc(parts, func(p core.StringPart) bool { return p.Tainted }) {
+			return false
+		}
+		if _, n := sqlContexts(parts); n > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+func TestSQLContexts(t *testing.T) {
+	text := func(s string) core.StringPart { return core.StringPart{Text: s} }
+	tainted := core.StringPart{Value: true, Tainted: true}
+	tests := []struct {
+		parts        []core.StringPart
+		want         string
+		placeholders int
+	}{
+		{[]core.StringPart{text("SELECT * FROM t WHERE name = '"), tainted, text("'")}, SQLQuoted, 0},
+		{[]core.StringPart{text("SELECT * FROM t WHERE id = "), tainted}, SQLNumeric, 0},
+		{[]core.StringPart{text("SELECT * FROM t LIMIT "), tainted}, SQLNumeric, 0},
+		{[]core.StringPart{text("SELECT * FROM "), tainted, text(" WHERE id = ?")}, SQLIdentifier, 1},
+		{[]core.StringPart{text("SELECT * FROM t ORDER BY "), tainted}, SQLOrderBy, 0},
+		{[]core.StringPart{text("SELECT * FROM t WHERE "), tainted}, SQLFragment, 0},
+		// Quotes and placeholders inside literals do not count
+		{[]core.StringPart{text(`SELECT * FROM t WHERE a = 'it''s ?' AND b = $1 AND c = "`), tainted, text(`"`)}, SQLQuoted, 1},
+	}
+	for _, tt := range tests {
+		ctxs, n := sqlContexts(tt.parts)
+		if got := ctxs[1]; got != tt.want || n != tt.placeholders {
+			t.Errorf("%s: context %q with %d placeholders, want %q with %d", core.RenderString(tt.parts), got, n, tt.want, tt.placeholders)
+		}
+	}
+}
+
+const sqlSource = `package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+func handler(db *sql.DB, r *http.Request) {
+	v := r.URL.Query().Get("v")
+	db.Query("SELECT * FROM t WHERE name = '" + v + "'")
+	db.Query("SELECT * FROM t ORDER BY " + v)
+	db.Query(fmt.Sprintf("SELECT * FROM t WHERE id = %d", v))
+	db.Query("SELECT * FROM " + v + " WHERE id = 1")
+}
+`
+
+// SQL findings take the severity and confidence of where the taint lies
+func TestQueryContext(t *testing.T) {
+	vulns := scan(t, "main.go", sqlSource)
+	type assessment struct{ context, severity, confidence string }
+	want := map[int]assessment{
+		11: {SQLQuoted, "HIGH", "high"},
+		12: {SQLOrderBy, "MEDIUM", "medium"},
+		13: {SQLNumeric, "LOW", "low"}, // Printed with %d
+		14: {SQLIdentifier, "MEDIUM", "medium"},
+	}
+	got := make(map[int]assessment)
+	for _, v := range vulns {
+		got[v.Sink.Line] = assessment{v.QueryContext, v.Severity, v.Confidence}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("assessments %v, want %v", got, want)
+	}
+}
+
+const boundSource = `import javax.servlet.http.HttpServletRequest;
+import org.springframework.jdbc.core.JdbcTemplate;
+
+public class Users {
+    public void find(JdbcTemplate jdbcTemplate, HttpServletRequest request) {
+        String id = request.getParameter("id");
+        jdbcTemplate.queryForList("SELECT name FROM users WHERE id = ?", id);
+    }
+}
+`
+
+// A value bound to a placeholder is reported as such, at low severity
+func TestBoundArgument(t *testing.T) {
+	vulns := scan(t, "Users.java", boundSource)
+	if len(vulns) != 1 || vulns[0].QueryContext != SQLPlaceholder || vulns[0].Severity != "LOW" {
+		t.Fatalf("findings %v", vulns)
+	}
+}
@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"regexp"
+	"sast-demo/pkg/core"
+	"sort"
+	"strings"
+)
+
+// Second-order flows.
+//
+// A value written to a database, cache or file is not a sink by itself, but it
+// comes back from every read of the same table, key or file, often in a handler
+// the attacker never calls directly. Writes reached by the taint of a source are
+// matched with the reads of their store, and each read is solved as a source of
+// its own; a sink it reaches is reported with the path through the write and the
+// read. Keys are resolved by constant propagation; a write or read whose key is
+// unknown matches every access of its store kind.
+
+var (
+	sqlWriteTable = regexp.MustCompile("(?i)\\b(?:insert\\s+(?:ignore\\s+)?into|replace\\s+into|merge\\s+into|update)\\s+[`\"\\[]?([\\w.]+)")
+	sqlReadTable  = regexp.MustCompile("(?i)\\b(?:from|join)\\s+[`\"\\[]?([\\w.]+)")
+)
+
+// storeCall is a StoreCall with its callee compiled
+type storeCall struct {
+	kind   string
+	callee *regexp.Regexp
+	spec   StoreCall
+}
+
+// storeAccess is a write or read resolved to its store
+type storeAccess struct {
+	kind string
+	keys []string // Tables, cache keys or files; nil when unknown
+}
+
+func (a *storeAccess) matches(b *storeAccess) bool {
+	if a.kind != b.kind {
+		return false
+	}
+	if a.keys == nil || b.keys == nil {
+		return true
+	}
+	for _, k := range a.keys {
+		for _, l := range b.keys {
+			if k == l {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *storeAccess) key() string { return strings.Join(a.keys, ",") }
+
+// storeModel finds the writes of taint into stores and the reads giving it back
+type storeModel struct {
+	e          *Engine
+	prog       *core.ProgramIR
+	instToFunc map[string]string
+	writes     []storeCall
+	reads      []storeCall
+	writeSinks *sinkMatcher
+	readInsts  []*core.Instruction
+	consts     map[string]map[string]string // Function -> constant strings
+}
+
+func (e *Engine) newStoreModel(prog *core.ProgramIR, flows *taintFlows, allInsts []*core.Instruction, instToFunc map[string]string) *storeModel {
+	s := &storeModel{
+		e:          e,
+		prog:       prog,
+		instToFunc: instToFunc,
+		consts:     make(map[string]map[string]string),
+	}
+	var writeSpecs []SinkSpec
+	for _, st := range e.Config.Stores {
+		for _, w := range st.Writes {
+			if r, err := regexp.Compile(w.Callee); err == nil {
+				s.writes = append(s.writes, storeCall{kind: st.Kind, callee: r, spec: w})
+				writeSpecs = append(writeSpecs, SinkSpec{Callee: w.Callee, Args: w.Args})
+			}
+		}
+		for _, rd := range st.Reads {
+			if r, err := regexp.Compile(rd.Callee); err == nil {
+				s.reads = append(s.reads, storeCall{kind: st.Kind, callee: r, spec: rd})
+			}
+		}
+	}
+	s.writeSinks = e.newSinkMatcher(flows, Rule{CallSinks: writeSpecs})
+	for _, inst := range allInsts {
+		if s.access(inst, false) != nil {
+			s.readInsts = append(s.readInsts, inst)
+		}
+	}
+	return s
+}
+
+// access resolves a call writing to (or reading from) a store, or returns nil
+func (s *storeModel) access(inst *core.Instruction, write bool) *storeAccess {
+	if inst.Op != core.OpCall || len(inst.Operands) == 0 {
+		return nil
+	}
+	calls, tables := s.reads, sqlReadTable
+	if write {
+		calls, tables = s.writes, sqlWriteTable
+	}
+	for _, c := range calls {
+		if !c.callee.MatchString(inst.Operands[0]) {
+			continue
+		}
+		// A call without the key argument is some other function of the same name
+		if c.spec.Key+1 >= len(inst.Operands) {
+			continue
+		}
+		a := &storeAccess{kind: c.kind}
+		if c.spec.Key < 0 {
+			return a
+		}
+		str, ok := s.constString(inst, inst.Operands[c.spec.Key+1])
+		if !ok {
+			return a
+		}
+		if c.kind != "sql" {
+			a.keys = []string{str}
+			return a
+		}
+		for _, m := range tables.FindAllStringSubmatch(str, -1) {
+			a.keys = append(a.keys, strings.ToLower(m[1]))
+		}
+		sort.Strings(a.keys)
+		return a
+	}
+	return nil
+}
+
+// constString is the string an operand of inst always holds
+func (s *storeModel) constString(inst *core.Instruction, op string) (string, bool) {
+	fn := s.instToFunc[inst.ID]
+	strs, ok := s.consts[fn]
+	if !ok {
+		if s.prog.Functions[fn] != nil {
+			strs = constStrings(s.prog, fn)
+		}
+		s.consts[fn] = strs
+	}
+	if str, ok := strs[op]; ok {
+		return str, true
+	}
+	if c := parseLiteral(op); c.state == latticeConst {
+		str, ok := c.val.(string)
+		return str, ok
+	}
+	return "", false
+}
+
+// storedFlow is a path from a source through a store to a sink
+type storedFlow struct {
+	kind, key   string
+	write, read *core.Instruction
+	sink        *core.Instruction
+	path        []*core.Instruction
+}
+
+// secondOrder follows the writes of a taint solution into stores, and the reads
+// of the same keys to the sinks of m. solve gives the taint solution of a read.
+func (s *storeModel) secondOrder(sol *Solution, solve func(*core.Instruction) *Solution, m *sinkMatcher, accept func([]*core.Instruction) bool) []storedFlow {
+	var flows []storedFlow
+	reported := make(map[*core.Instruction]bool)
+	all := func([]*core.Instruction) bool { return true }
+	for _, w := range s.writeSinks.findSinks(sol, 1, all) {
+		wa := s.access(w.sink, true)
+		for _, read := range s.readInsts {
+			ra := s.access(read, false)
+			if !wa.matches(ra) {
+				continue
+			}
+			key := wa.key()
+			if key == "" {
+				key = ra.key()
+			}
+			for _, sp := range m.findSinks(solve(read), 1, accept) {
+				if reported[sp.sink] {
+					continue
+				}
+				reported[sp.sink] = true
+				flows = append(flows, storedFlow{
+					kind:  wa.kind,
+					key:   key,
+					write: w.sink,
+					read:  read,
+					sink:  sp.sink,
+					path:  concatPath(w.paths[0], sp.paths[0]),
+				})
+			}
+		}
+	}
+	return flows
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+const storedSource = `package main
+
+import (
+	"database/sql"
+	"net/http"
+	"os/exec"
+)
+
+var cache Cache
+
+type Cache interface {
+	Set(key, value string)
+	Get(key string) string
+}
+
+func save(r *http.Request) {
+	cache.Set("motd", r.URL.Query().Get("motd"))
+}
+
+func show() {
+	exec.Command(cache.Get("motd")).Run()
+	exec.Command(cache.Get("banner")).Run()
+}
+
+func record(db *sql.DB, r *http.Request) {
+	db.Exec("INSERT INTO jobs (cmd) VALUES (?)", r.URL.Query().Get("cmd"))
+}
+
+func worker(db *sql.DB) {
+	var cmd string
+	db.QueryRow("SELECT cmd FROM jobs LIMIT 1").Scan(&cmd)
+	exec.Command(cmd).Run()
+	var name string
+	db.QueryRow("SELECT name FROM users LIMIT 1").Scan(&name)
+	exec.Command(name).Run()
+}
+`
+
+// A write of taint into a store reaches the sinks of the reads of the same key
+func TestStoredFlows(t *testing.T) {
+	prog, path := sourceIR(t, "main.go", storedSource)
+	e := NewEngine(DefaultRules())
+	vulns := e.AnalyzeIR(prog, path)
+
+	var stored []core.Vulnerability
+	for _, v := range vulns {
+		if v.Stored != nil {
+			stored = append(stored, v)
+		}
+	}
+	if got, want := findings(stored), []string{"rce@17->21", "rce@26->32"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second-order findings %v, want %v", got, want)
+	}
+	if e.Stats.StoredFlows != len(stored) {
+		t.Errorf("Stats.StoredFlows = %d, want %d", e.Stats.StoredFlows, len(stored))
+	}
+	want := map[string][2]int{"cache": {17, 21}, "sql": {26, 31}} // Write and read lines
+	for _, v := range stored {
+		w, ok := want[v.Stored.Kind]
+		if !ok || v.Stored.Write.Line != w[0] || v.Stored.Read.Line != w[1] {
+			t.Errorf("stored flow %s %q: write at %d, read at %d", v.Stored.Kind, v.Stored.Key, v.Stored.Write.Line, v.Stored.Read.Line)
+		}
+	}
+	keys := map[string]string{}
+	for _, v := range stored {
+		keys[v.Stored.Kind] = v.Stored.Key
+	}
+	if !reflect.DeepEqual(keys, map[string]string{"cache": "motd", "sql": "jobs"}) {
+		t.Errorf("store keys %v", keys)
+	}
+}
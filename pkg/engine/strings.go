@@ -0,0 +1,326 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sast-demo/pkg/core"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// String building.
+//
+// A taint path only says that the value reaching a sink depends on the source,
+// not how. Strings are mostly built from constant text and a few values, by
+// concatenation or by a format function, and the verb a value is printed with
+// matters: `fmt.Sprintf("... WHERE id=%d", id)` can only insert a number. The
+// string reaching a sink is rebuilt from the definitions of its value: constants
+// are text, `+` joins its operands, the format of a Config.Formats call is parsed
+// and each verb takes the argument it prints, and a variable stored once stands
+// for its value. Anything else is a value, tainted when it is on the path. A
+// finding whose tainted values are all printed as numbers is reported as LOW.
+
+// maxStringDepth bounds how many definitions a string is rebuilt through
+const maxStringDepth = 16
+
+// formatPiece is a piece of a parsed format: text, or a verb printing an argument
+type formatPiece struct {
+	text string
+	verb string // "%d"; "" for text
+	arg  int    // 0-based index among the values following the format
+}
+
+// parseFormat splits a printf-style format into text and verbs. Arguments are
+// numbered in order; Go's %[n]d and Java's %n$d pick one explicitly, and a *
+// width or precision takes one.
+func parseFormat(f string) []formatPiece {
+	var pieces []formatPiece
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			pieces = append(pieces, formatPiece{text: text.String()})
+			text.Reset()
+		}
+	}
+	next := 0
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			text.WriteByte(f[i])
+			continue
+		}
+		i++
+		if i >= len(f) || f[i] == '%' {
+			text.WriteByte('%')
+			continue
+		}
+		arg := -1
+		if j := i + strings.IndexFunc(f[i:], func(r rune) bool { return r < '0' || r > '9' }); j > i && f[j] == '$' {
+			n, _ := strconv.Atoi(f[i:j])
+			arg, i = n-1, j+1
+		}
+	flags:
+		for ; i < len(f); i++ {
+			switch c := f[i]; {
+			case c == '[':
+				end := strings.IndexByte(f[i:], ']')
+				if end < 0 {
+					break flags
+				}
+				if n, err := strconv.Atoi(f[i+1 : i+end]); err == nil {
+					next = n - 1
+				}
+				i += end
+			case c == '*':
+				next++
+			case c >= '0' && c <= '9' || strings.IndexByte("+-# ,(.", c) >= 0:
+			default:
+				break flags
+			}
+		}
+		if i >= len(f) {
+			break
+		}
+		if f[i] == 'n' {
+			// Java's line separator
+			text.WriteByte('\n')
+			continue
+		}
+		if arg < 0 {
+			arg = next
+			next++
+		}
+		flush()
+		pieces = append(pieces, formatPiece{verb: "%" + string(f[i]), arg: arg})
+	}
+	flush()
+	return pieces
+}
+
+// printsText reports whether a verb prints the text of a value (%s, %v, %q), as
+// opposed to a number, a boolean, a character or an encoding of it
+func printsText(verb string) bool {
+	return verb == "%s" || verb == "%v" || verb == "%q" || verb == "%S"
+}
+
+// formatFunc is a FormatSpec with its callee compiled
+type formatFunc struct {
+	callee *regexp.Regexp
+	spec   FormatSpec
+}
+
+// stringModel rebuilds the strings reaching sinks
+type stringModel struct {
+	prog       *core.ProgramIR
+	formats    []formatFunc
+	instToFunc map[string]string
+	defs       map[string]map[string][]*core.Instruction // Function -> value -> its definitions
+	consts     map[string]map[string]string              // Function -> constant strings
+}
+
+func (e *Engine) newStringModel(prog *core.ProgramIR, instToFunc map[string]string) *stringModel {
+	s := &stringModel{
+		prog:       prog,
+		instToFunc: instToFunc,
+		defs:       make(map[string]map[string][]*core.Instruction),
+		consts:     make(map[string]map[string]string),
+	}
+	for _, f := range e.Config.Formats {
+		if r, err := regexp.Compile(f.Callee); err == nil {
+			s.formats = append(s.formats, formatFunc{callee: r, spec: f})
+		}
+	}
+	return s
+}
+
+func (s *stringModel) definitions(fn string) map[string][]*core.Instruction {
+	defs, ok := s.defs[fn]
+	if ok {
+		return defs
+	}
+	defs = make(map[string][]*core.Instruction)
+	if f := s.prog.Functions[fn]; f != nil {
+		for _, bb := range f.Blocks {
+			for _, inst := range bb.Instructions {
+				if inst.Result != "" {
+					defs[inst.Result] = append(defs[inst.Result], inst)
+				}
+			}
+		}
+		s.consts[fn] = constStrings(s.prog, fn)
+	}
+	s.defs[fn] = defs
+	return defs
+}
+
+// sinkString rebuilds the string a taint path carries into its sink, or returns
+// nil when nothing is known about it beyond being tainted
+func (s *stringModel) sinkString(path []*core.Instruction) []core.StringPart {
+	if len(path) < 2 {
+		return nil
+	}
+	sink, prev := path[len(path)-1], path[len(path)-2]
+	fn := s.instToFunc[sink.ID]
+	if prev.Result == "" || s.instToFunc[prev.ID] != fn || !slices.Contains(valueOperands(sink), prev.Result) {
+		return nil
+	}
+	onPath := make(map[*core.Instruction]bool, len(path))
+	for _, inst := range path {
+		onPath[inst] = true
+	}
+	parts := s.parts(fn, prev.Result, onPath, 0)
+	for _, p := range parts {
+		if !p.Value || p.Verb != "" {
+			return parts
+		}
+	}
+	return nil
+}
+
+// parts rebuilds the string held by value v of function fn
+func (s *stringModel) parts(fn, v string, onPath map[*core.Instruction]bool, depth int) []core.StringPart {
+	defs := s.definitions(fn)[v]
+	if str, ok := s.consts[fn][v]; ok {
+		return []core.StringPart{{Text: str}}
+	}
+	if c := parseLiteral(v); c.state == latticeConst {
+		return []core.StringPart{{Text: fmt.Sprint(c.val)}}
+	}
+	tainted := false
+	for _, d := range defs {
+		tainted = tainted || onPath[d]
+	}
+	value := []core.StringPart{{Value: true, Tainted: tainted}}
+	if len(defs) != 1 || depth >= maxStringDepth {
+		return value
+	}
+
+	var parts []core.StringPart
+	d, ops := defs[0], defs[0].Operands
+	switch {
+	case d.Op == core.OpConst && len(ops) == 1:
+		c := parseLiteral(ops[0])
+		if c.state != latticeConst {
+			return value
+		}
+		parts = []core.StringPart{{Text: fmt.Sprint(c.val)}}
+	case (d.Op == core.OpStore || d.Op == core.OpLoad) && len(ops) == 1 && isPlainValue(ops[0]):
+		// A copy: the variable stored once, or loaded
+		parts = s.parts(fn, ops[0], onPath, depth+1)
+	case d.Op == core.OpBinOp && len(ops) == 3 && ops[1] == "+":
+		parts = append(s.parts(fn, ops[0], onPath, depth+1), s.parts(fn, ops[2], onPath, depth+1)...)
+	case d.Op == core.OpCall:
+		var ok bool
+		if parts, ok = s.formatted(fn, d, onPath, depth); !ok {
+			return value
+		}
+	default:
+		return value
+	}
+	// The value is on the path, but none of its parts is: the taint came in some
+	// other way, so where it lies in the string is not known
+	if tainted && !slices.ContainsFunc(parts, func(p core.StringPart) bool { return p.Tainted }) {
+		return value
+	}
+	return joinText(parts)
+}
+
+// formatted rebuilds the result of a call of a format function with a constant format
+func (s *stringModel) formatted(fn string, call *core.Instruction, onPath map[*core.Instruction]bool, depth int) ([]core.StringPart, bool) {
+	args := call.Operands[1:]
+	for _, f := range s.formats {
+		if !f.callee.MatchString(call.Operands[0]) || f.spec.Format >= len(args) {
+			continue
+		}
+		format, ok := s.consts[fn][args[f.spec.Format]]
+		if !ok {
+			return nil, false
+		}
+		values := args[f.spec.Format+1:]
+		var parts []core.StringPart
+		for _, p := range parseFormat(format) {
+			if p.verb == "" {
+				parts = append(parts, core.StringPart{Text: p.text})
+				continue
+			}
+			if p.arg < 0 || p.arg >= len(values) {
+				parts = append(parts, core.StringPart{Value: true, Verb: p.verb})
+				continue
+			}
+			inner := s.parts(fn, values[p.arg], onPath, depth+1)
+			if p.verb == "%d" && len(inner) == 1 && !inner[0].Value {
+				if _, err := strconv.Atoi(inner[0].Text); err == nil {
+					// A constant number prints as itself
+					parts = append(parts, inner[0])
+					continue
+				}
+			}
+			if printsText(p.verb) {
+				// The value's text is inserted as it is
+				for i := range inner {
+					if inner[i].Value && inner[i].Verb == "" {
+						inner[i].Verb = p.verb
+					}
+				}
+				parts = append(parts, inner...)
+				continue
+			}
+			parts = append(parts, core.StringPart{
+				Value:   true,
+				Verb:    p.verb,
+				Tainted: slices.ContainsFunc(inner, func(p core.StringPart) bool { return p.Tainted }),
+			})
+		}
+		return parts, true
+	}
+	return nil, false
+}
+
+// isPlainValue reports whether an operand names a whole variable or temporary
+func isPlainValue(op string) bool {
+	return op != "" && isIdentStart(op[0]) && !strings.ContainsAny(op, ".*")
+}
+
+// joinText merges adjacent text parts
+func joinText(parts []core.StringPart) []core.StringPart {
+	var out []core.StringPart
+	for _, p := range parts {
+		if n := len(out); n > 0 && !p.Value && !out[n-1].Value {
+			out[n-1].Text += p.Text
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// numericOnly reports whether every tainted value of a string is printed as a
+// number (or another non-text verb), so the taint cannot insert arbitrary text
+func numericOnly(parts []core.StringPart) bool {
+	tainted := false
+	for _, p := range parts {
+		if !p.Tainted {
+			continue
+		}
+		if p.Verb == "" || printsText(p.Verb) {
+			return false
+		}
+		tainted = true
+	}
+	return tainted
+}
+
+// describeString records the string a finding's path carries into its sink, and
+// lowers the finding when the taint can only be printed as a number. Queries of
+// rules with a QueryLanguage are also told where the taint lies in them.
+func (s *stringModel) describeString(v *core.Vulnerability, rule Rule, path []*core.Instruction) {
+	if parts := s.sinkString(path); parts != nil {
+		v.StringParts = parts
+		if numericOnly(parts) {
+			v.Severity = "LOW"
+		}
+	}
+	if rule.QueryLanguage == QuerySQL {
+		s.describeSQL(v, path)
+	}
+}
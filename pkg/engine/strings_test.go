@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	text := func(s string) formatPiece { return formatPiece{text: s} }
+	verb := func(v string, arg int) formatPiece { return formatPiece{verb: v, arg: arg} }
+	tests := []struct {
+		format string
+		want   []formatPiece
+	}{
+		{"id=%d", []formatPiece{text("id="), verb("%d", 0)}},
+		{"%s and %q", []formatPiece{verb("%s", 0), text(" and "), verb("%q", 1)}},
+		{"100%% %v", []formatPiece{text("100% "), verb("%v", 0)}},
+		{"%-10s|%08.3f", []formatPiece{verb("%s", 0), text("|"), verb("%f", 1)}},
+		{"%*d", []formatPiece{verb("%d", 1)}}, // The width is argument 0
+		{"%[2]s %[1]s %s", []formatPiece{verb("%s", 1), text(" "), verb("%s", 0), text(" "), verb("%s", 1)}},
+		{"%2$s %1$s%n", []formatPiece{verb("%s", 1), text(" "), verb("%s", 0), text("\n")}}, // Java
+		{"trailing %", []formatPiece{text("trailing %")}},
+	}
+	for _, tt := range tests {
+		if got := parseFormat(tt.format); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFormat(%q) = %+v, want %+v", tt.format, got, tt.want)
+		}
+	}
+}
+
+const stringSource = `package main
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+)
+
+func handler(r *http.Request) {
+	n := r.URL.Query().Get("n")
+	exec.Command("sh", "-c", "head -n "+n+" log.txt").Run()
+	exec.Command("sh", "-c", fmt.Sprintf("head -n %d %s", n, "log.txt")).Run()
+	exec.Command("sh", "-c", fmt.Sprintf("echo %[2]s %[1]q", n, "x")).Run()
+}
+`
+
+// The string reaching a sink is rebuilt, and taint printed only as a number lowers the finding
+func TestSinkStrings(t *testing.T) {
+	vulns := scan(t, "main.go", stringSource, func(c *Config) {
+		for i := range c.Rules {
+			if c.Rules[i].ID == "rce" {
+				c.Rules[i].CallSinks = []SinkSpec{{Callee: "^exec\\.Command$", Args: []int{2}}}
+			}
+		}
+	})
+	want := map[int][2]string{ // Sink line -> rendered string, severity
+		11: {"head -n {tainted ?} log.txt", "CRITICAL"},
+		12: {"head -n {tainted %d} log.txt", "LOW"},
+		13: {"echo x {tainted %q}", "CRITICAL"},
+	}
+	if len(vulns) != len(want) {
+		t.Fatalf("findings %v", findings(vulns))
+	}
+	for _, v := range vulns {
+		got := [2]string{core.RenderString(v.StringParts), v.Severity}
+		if got != want[v.Sink.Line] {
+			t.Errorf("line %d: %q %s, want %q %s", v.Sink.Line, got[0], got[1], want[v.Sink.Line][0], want[v.Sink.Line][1])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+const summarySource = `package main
+
+import (
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+func wrap(s string) string { return strings.TrimSpace(s) }
+
+func input(r *http.Request) string { return r.URL.Query().Get("cmd") }
+
+func run(cmd string) { exec.Command(cmd).Run() }
+
+func constant(s string) string { return "ls" }
+
+func viaConstant(s string) string { return constant(s) }
+
+func handler(r *http.Request) {
+	cmd := r.URL.Query().Get("cmd")
+	exec.Command(wrap(cmd)).Run()
+	exec.Command(wrap("ls")).Run()
+	exec.Command(wrap(cmd + " -l")).Run()
+	exec.Command(input(r)).Run()
+	run(cmd)
+	run("ls")
+	exec.Command(viaConstant(cmd)).Run()
+}
+`
+
+func TestSummaries(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		// param-to-return (21, 23), source-to-return (11->24), param-to-sink (20->13);
+		// the constant calls of wrap (22) and run (26) are not merged with the tainted ones
+		{3, []string{"rce@11->24", "rce@20->13", "rce@20->21", "rce@20->23"}},
+		// One call level: viaConstant's call of constant is not solved, so its
+		// result is taken to carry the taint of its argument
+		{1, []string{"rce@11->24", "rce@20->13", "rce@20->21", "rce@20->23", "rce@20->27"}},
+	}
+	for _, tt := range tests {
+		prog, path := sourceIR(t, "main.go", summarySource)
+		cfg := DefaultRules()
+		cfg.ContextDepth = tt.depth
+		e := NewEngine(cfg)
+		if got := findings(e.AnalyzeIR(prog, path)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("depth %d: findings %v, want %v", tt.depth, got, tt.want)
+		}
+		// wrap entered with the same fact from lines 21 and 23 is solved once
+		if e.Stats.Summaries == 0 || e.Stats.SummariesReused == 0 {
+			t.Errorf("depth %d: %d summaries, %d reused", tt.depth, e.Stats.Summaries, e.Stats.SummariesReused)
+		}
+	}
+}
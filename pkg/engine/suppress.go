@@ -0,0 +1,34 @@
+package engine
+
+import "sast-demo/pkg/core"
+
+// ApplySuppressions moves findings covered by an inline suppression out of the active list.
+// A suppression matches when it names the finding's rule and sits on its source or sink line.
+// Suppressions without a reason are ignored so that every accepted finding is justified.
+// Findings the engine already suppressed (guarded sinks) stay suppressed.
+func ApplySuppressions(vulns []core.Vulnerability, sups []core.Suppression) (active, suppressed []core.Vulnerability) {
+	for _, v := range vulns {
+		if v.Suppression != nil {
+			suppressed = append(suppressed, v)
+		} else if s := findSuppression(v, sups); s != nil {
+			v.Suppression = &core.SuppressionInfo{Kind: "inline", Reason: s.Reason, Line: s.CommentLine}
+			suppressed = append(suppressed, v)
+		} else {
+			active = append(active, v)
+		}
+	}
+	return active, suppressed
+}
+
+func findSuppression(v core.Vulnerability, sups []core.Suppression) *core.Suppression {
+	for i := range sups {
+		s := &sups[i]
+		if s.Reason == "" || !s.Covers(v.RuleID) {
+			continue
+		}
+		if (v.Sink != nil && v.Sink.Line == s.Line) || (v.Source != nil && v.Source.Line == s.Line) {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"reflect"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+func TestApplySuppressions(t *testing.T) {
+	finding := func(rule string, source, sink int) core.Vulnerability {
+		return core.Vulnerability{RuleID: rule, Source: &core.Node{Line: source}, Sink: &core.Node{Line: sink}}
+	}
+	guarded := finding("rce", 1, 2)
+	guarded.Suppression = &core.SuppressionInfo{Kind: "guard", Reason: "validated", Line: 1}
+	vulns := []core.Vulnerability{
+		finding("rce", 10, 12),  // Suppressed at its sink
+		finding("sqli", 20, 22), // Suppressed at its source
+		finding("xss", 30, 32),  // Suppression names another rule
+		finding("rce", 40, 42),  // Suppression without a reason
+		finding("rce", 50, 52),  // Nothing on its lines
+		guarded,
+	}
+	sups := []core.Suppression{
+		{Line: 12, CommentLine: 11, Rules: []string{"rce"}, Reason: "fixed command"},
+		{Line: 20, CommentLine: 20, Rules: []string{"*"}, Reason: "internal endpoint"},
+		{Line: 32, CommentLine: 32, Rules: []string{"rce", "sqli"}, Reason: "not xss"},
+		{Line: 42, CommentLine: 42, Rules: []string{"rce"}},
+	}
+	active, suppressed := ApplySuppressions(vulns, sups)
+
+	var lines []int
+	for _, v := range active {
+		lines = append(lines, v.Source.Line)
+	}
+	if want := []int{30, 40, 50}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("active findings from lines %v, want %v", lines, want)
+	}
+	want := []core.SuppressionInfo{
+		{Kind: "inline", Reason: "fixed command", Line: 11},
+		{Kind: "inline", Reason: "internal endpoint", Line: 20},
+		{Kind: "guard", Reason: "validated", Line: 1},
+	}
+	if len(suppressed) != len(want) {
+		t.Fatalf("%d suppressed findings, want %d", len(suppressed), len(want))
+	}
+	for i, v := range suppressed {
+		if *v.Suppression != want[i] {
+			t.Errorf("suppression %d: %+v, want %+v", i, *v.Suppression, want[i])
+		}
+	}
+}
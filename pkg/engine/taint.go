@@ -0,0 +1,437 @@
+package engine
+
+import (
+	"regexp"
+	"sast-demo/pkg/core"
+	"strings"
+)
+
+// Taint as an IFDS problem.
+//
+// A fact is a tainted access path. An instruction that reads a tainted path
+// taints what it defines (see useIndex.steps); assigning a plain variable kills
+// the facts rooted at it, so `x = input; x = "safe"; sink(x)` is clean. Taint
+// written to a package-level variable or to an object other functions reach
+// through pointers also jumps straight to their reads there, independently of
+// the order calls happen in. Library calls that decode into pointer arguments
+// (`json.Unmarshal(body, &v)`) taint the variables passed by address as well
+// as their result, and container methods (`list.add(v)`) their receiver.
+
+// taintFlows are the flow functions of taint over a useIndex
+type taintFlows struct {
+	uses      *useIndex
+	prog      *core.ProgramIR
+	memo      map[string]*factSteps
+	outParams []outParam
+	temps     map[string]*core.Instruction // Temporary -> the load, & or receiver-returning call defining it
+}
+
+// outParam is an OutParamSpec with its callee compiled
+type outParam struct {
+	callee *regexp.Regexp
+	spec   OutParamSpec
+}
+
+// factSteps are the instructions reached from one fact of one function: by
+// instruction inside the function, and the reads elsewhere it jumps to
+type factSteps struct {
+	local  map[*core.Instruction][]taintStep
+	remote []taintStep
+}
+
+func newTaintFlows(uses *useIndex, prog *core.ProgramIR, outParams []OutParamSpec) *taintFlows {
+	t := &taintFlows{uses: uses, prog: prog, memo: make(map[string]*factSteps), temps: make(map[string]*core.Instruction)}
+	for _, s := range outParams {
+		if r, err := regexp.Compile(s.Callee); err == nil {
+			t.outParams = append(t.outParams, outParam{callee: r, spec: s})
+		}
+	}
+	for _, fn := range prog.Functions {
+		for _, bb := range fn.Blocks {
+			for _, inst := range bb.Instructions {
+				if inst.Op == core.OpLoad || inst.Op == core.OpBinOp || t.returnsReceiver(inst) {
+					t.temps[inst.Result] = inst
+				}
+			}
+		}
+	}
+	return t
+}
+
+func (t *taintFlows) steps(fn, fact string) *factSteps {
+	key := fn + "|" + fact
+	if fs, ok := t.memo[key]; ok {
+		return fs
+	}
+	fs := &factSteps{local: make(map[*core.Instruction][]taintStep)}
+	for _, step := range t.uses.steps(fn, fact) {
+		if t.uses.funcOf[step.inst.ID] == fn {
+			fs.local[step.inst] = append(fs.local[step.inst], step)
+		} else {
+			fs.remote = append(fs.remote, step)
+		}
+	}
+	t.memo[key] = fs
+	return fs
+}
+
+// stepsAt lists how inst, an instruction of fn, reads fact
+func (t *taintFlows) stepsAt(fn string, inst *core.Instruction, fact string) []taintStep {
+	return t.steps(fn, fact).local[inst]
+}
+
+// Normal taints what inst defines from fact, and jumps to the reads in other
+// functions of both: a fact may hold only at the end of a function, as after
+// storing into a global
+func (t *taintFlows) Normal(fn string, inst *core.Instruction, fact string) []Flow {
+	var flows []Flow
+	gen := func(fact string) {
+		flows = append(flows, Flow{Fact: fact, Transfer: true})
+		for _, remote := range t.steps(fn, fact).remote {
+			flows = append(flows, Flow{Fact: remote.key, Transfer: true, At: remote.inst})
+		}
+	}
+	for _, step := range t.stepsAt(fn, inst, fact) {
+		if step.fact != "" {
+			gen(step.fact)
+		}
+		for _, out := range t.outFacts(inst, step.value) {
+			gen(out)
+		}
+	}
+	for _, remote := range t.steps(fn, fact).remote {
+		flows = append(flows, Flow{Fact: remote.key, At: remote.inst})
+	}
+	if !kills(inst, fact) {
+		flows = append(flows, Flow{Fact: fact})
+	}
+	return flows
+}
+
+func (t *taintFlows) Call(call *core.Instruction, callee, fact string) []string {
+	f := t.prog.Functions[callee]
+	var facts []string
+	for _, step := range t.stepsAt(t.uses.funcOf[call.ID], call, fact) {
+		rest := ""
+		if strings.HasPrefix(fact, step.value) {
+			rest = fact[len(step.value):]
+		}
+		for _, pos := range argPositions(call, step.value) {
+			if name := paramName(f, pos); name != "" {
+				facts = append(facts, truncatePath(name+rest, t.uses.depth))
+			}
+		}
+	}
+	return facts
+}
+
+func (t *taintFlows) Return(call *core.Instruction, callee string, exit *core.Instruction, fact string) []string {
+	if exit.Op != core.OpRet || call.Result == "" {
+		return nil
+	}
+	var facts []string
+	for _, step := range t.stepsAt(callee, exit, fact) {
+		rest := ""
+		if strings.HasPrefix(fact, step.value) {
+			rest = fact[len(step.value):]
+		}
+		facts = append(facts, truncatePath(call.Result+rest, t.uses.depth))
+	}
+	return facts
+}
+
+// CallToReturn lets facts the call does not overwrite through; a call that
+// could not be followed into its callees taints its result from any tainted
+// argument, as for library functions
+func (t *taintFlows) CallToReturn(fn string, call *core.Instruction, fact string, entered bool) []Flow {
+	var flows []Flow
+	if !entered {
+		flows = t.Normal(fn, call, fact)
+	} else if !kills(call, fact) {
+		flows = append(flows, Flow{Fact: fact})
+	}
+	return flows
+}
+
+// outFacts lists the paths a library call taints through its pointer arguments
+// when value carries taint into it
+func (t *taintFlows) outFacts(call *core.Instruction, value string) []string {
+	if call.Op != core.OpCall || len(call.Operands) == 0 {
+		return nil
+	}
+	var facts []string
+	for _, op := range t.outParams {
+		if !op.callee.MatchString(call.Operands[0]) {
+			continue
+		}
+		from := argPositions(call, value)
+		if len(op.spec.From) > 0 {
+			if !anyPosition(from, op.spec.From) {
+				continue
+			}
+			from = op.spec.From
+		}
+		for i, arg := range call.Operands[1:] {
+			if len(op.spec.To) > 0 && !anyPosition([]int{i}, op.spec.To) || len(op.spec.To) == 0 && anyPosition([]int{i}, from) {
+				continue
+			}
+			facts = append(facts, t.pointees(arg)...)
+		}
+		// Containers filled through a method, list.add(v)
+		if call.Receiver != "" && anyPosition([]int{-1}, op.spec.To) {
+			facts = append(facts, t.pointees(call.Receiver)...)
+		}
+	}
+	return facts
+}
+
+// returnsReceiver reports whether inst calls a builder method returning its receiver
+func (t *taintFlows) returnsReceiver(inst *core.Instruction) bool {
+	if inst.Op != core.OpCall || inst.Receiver == "" || inst.Result == "" || len(inst.Operands) == 0 {
+		return false
+	}
+	for _, op := range t.outParams {
+		if op.spec.ReturnsReceiver && op.callee.MatchString(inst.Operands[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// pointees lists the paths written through an argument: x for `&x`, for a
+// pointer, slice or map variable p both p and what it points to, and for the
+// result of a builder call what its receiver is
+func (t *taintFlows) pointees(arg string) []string {
+	def := t.temps[arg]
+	if def == nil || len(def.Operands) == 0 {
+		return nil
+	}
+	if def.Op == core.OpCall {
+		return t.pointees(def.Receiver)
+	}
+	if def.Op == core.OpBinOp && len(def.Operands) == 2 && def.Operands[0] == "&" {
+		if load := t.temps[def.Operands[1]]; load != nil && load.Op == core.OpLoad && len(load.Operands) > 0 {
+			return []string{truncatePath(load.Operands[0], t.uses.depth)}
+		}
+		return nil
+	}
+	if def.Op != core.OpLoad || strings.ContainsAny(def.Operands[0], `"'`) {
+		return nil
+	}
+	p := truncatePath(def.Operands[0], t.uses.depth)
+	return []string{p, "*" + p}
+}
+
+func anyPosition(positions, of []int) bool {
+	for _, p := range positions {
+		for _, q := range of {
+			if p == q {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// kills reports whether inst overwrites the variable fact is rooted at. Only
+// plain variables are overwritten for sure; a store without operands (an unknown
+// value, possibly from a loop that never runs) and parameters kill nothing.
+func kills(inst *core.Instruction, fact string) bool {
+	r := inst.Result
+	if r == "" || strings.ContainsAny(r, ".*") || inst.Op == core.OpParam || (inst.Op == core.OpStore && len(inst.Operands) == 0) {
+		return false
+	}
+	return fact == r || strings.HasPrefix(fact, r+".")
+}
+
+// argPositions lists the argument indexes of call that pass value, -1 for the receiver
+func argPositions(call *core.Instruction, value string) []int {
+	var positions []int
+	if call.Receiver == value {
+		positions = append(positions, -1)
+	}
+	for i, arg := range call.Operands[1:] {
+		if arg == value {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// paramName is the parameter at an argument position of fn (-1 for the receiver), "" if none
+func paramName(fn *core.FunctionIR, pos int) string {
+	if fn == nil {
+		return ""
+	}
+	if pos < 0 {
+		return fn.Receiver
+	}
+	if params := fn.Params(); pos < len(params) {
+		return params[pos]
+	}
+	return ""
+}
+
+func paramInst(fn *core.FunctionIR, name string) *core.Instruction {
+	if entry := fn.Blocks[fn.Entry]; entry != nil {
+		for _, inst := range entry.Instructions {
+			if inst.Op == core.OpParam && inst.Result == name {
+				return inst
+			}
+		}
+	}
+	return nil
+}
+
+// taintSeeds start a solve at a source: its result is tainted after it
+func taintSeeds(icfg *ICFG, source *core.Instruction, depth int) []Seed {
+	if source.Result == "" {
+		return nil
+	}
+	var seeds []Seed
+	for _, succ := range icfg.Succs(source) {
+		seeds = append(seeds, Seed{Node: Node{succ, truncatePath(source.Result, depth)}, Via: []*core.Instruction{source}})
+	}
+	return seeds
+}
+
+// sinkMatcher finds the sinks of one rule in taint solutions
+type sinkMatcher struct {
+	e       *Engine
+	flows   *taintFlows
+	regexes []*regexp.Regexp
+	calls   []callSink
+	callee  map[*Solution][][]*core.Instruction
+	labeled map[string]*sinkMatcher // Source labels -> matcher of the call sinks accepting them
+}
+
+func (e *Engine) newSinkMatcher(flows *taintFlows, rule Rule) *sinkMatcher {
+	return &sinkMatcher{
+		e:       e,
+		flows:   flows,
+		regexes: e.compileRegexes(rule.Sinks),
+		calls:   e.compileCallSinks(rule.CallSinks),
+		callee:  make(map[*Solution][][]*core.Instruction),
+		labeled: make(map[string]*sinkMatcher),
+	}
+}
+
+// isSink reports whether the instruction of a node reads its fact into a sink
+func (m *sinkMatcher) isSink(n Node) bool {
+	steps := m.flows.stepsAt(m.flows.uses.funcOf[n.Inst.ID], n.Inst, n.Fact)
+	if len(steps) == 0 {
+		return false
+	}
+	if m.e.matchesAny(n.Inst.Code, m.regexes) {
+		return true
+	}
+	for _, step := range steps {
+		// Positional sinks are checked per value: the same call may also
+		// read the fact through an argument that is not a sink position
+		if m.e.hitsCallSink(n.Inst, step.value, m.calls) {
+			return true
+		}
+	}
+	return false
+}
+
+// calleePaths lists, for a callee solve, one path per sink it reaches, from the
+// parameter it was entered through; sinks of the functions it calls in turn are included
+func (m *sinkMatcher) calleePaths(sub *Solution) [][]*core.Instruction {
+	if paths, ok := m.callee[sub]; ok {
+		return paths
+	}
+	m.callee[sub] = nil // Cut cycles through recursive summaries
+	var head []*core.Instruction
+	if p := paramInst(m.flows.prog.Functions[sub.Func], pathRoot(sub.EntryFact)); p != nil {
+		head = []*core.Instruction{p}
+	}
+	var paths [][]*core.Instruction
+	sinks := make(map[*core.Instruction]bool)
+	add := func(path []*core.Instruction) {
+		if sink := path[len(path)-1]; !sinks[sink] {
+			sinks[sink] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, n := range sub.Nodes {
+		if m.isSink(n) {
+			for _, p := range sub.Paths(n, 1) {
+				add(concatPath(head, p, []*core.Instruction{n.Inst}))
+			}
+		}
+	}
+	for _, ce := range sub.Calls {
+		for _, inner := range m.calleePaths(ce.Callee) {
+			for _, p := range sub.Paths(ce.Node, 1) {
+				add(concatPath(head, p, []*core.Instruction{ce.Node.Inst}, inner))
+			}
+		}
+	}
+	m.callee[sub] = paths
+	return paths
+}
+
+func concatPath(parts ...[]*core.Instruction) []*core.Instruction {
+	var n int
+	for _, p := range parts {
+		n += len(p)
+	}
+	path := make([]*core.Instruction, 0, n)
+	for _, p := range parts {
+		path = append(path, p...)
+	}
+	return path
+}
+
+// sinkPaths collects the paths from one source to one sink, shortest first
+type sinkPaths struct {
+	sink  *core.Instruction
+	paths [][]*core.Instruction
+}
+
+// findSinks rebuilds the paths of a solve that end in a sink of the rule: at most
+// k accepted paths per sink, shortest first, including sinks inside solved callees
+func (m *sinkMatcher) findSinks(sol *Solution, k int, accept func([]*core.Instruction) bool) []*sinkPaths {
+	var found []*sinkPaths
+	bySink := make(map[*core.Instruction]*sinkPaths)
+	seen := make(map[string]bool)
+	record := func(path []*core.Instruction) {
+		sink := path[len(path)-1]
+		sp := bySink[sink]
+		if sp != nil && len(sp.paths) >= k {
+			return
+		}
+		key := pathKey(path)
+		if seen[key] || !accept(path) {
+			return
+		}
+		seen[key] = true
+		if sp == nil {
+			sp = &sinkPaths{sink: sink}
+			bySink[sink] = sp
+			found = append(found, sp)
+		}
+		sp.paths = append(sp.paths, path)
+	}
+
+	limit := 2*k + 2
+	for _, n := range sol.Nodes {
+		if !m.isSink(n) {
+			continue
+		}
+		for _, p := range sol.Paths(n, limit) {
+			if !onPath(p, n.Inst) {
+				record(concatPath(p, []*core.Instruction{n.Inst}))
+			}
+		}
+	}
+	for _, ce := range sol.Calls {
+		for _, inner := range m.calleePaths(ce.Callee) {
+			for _, p := range sol.Paths(ce.Node, limit) {
+				record(concatPath(p, []*core.Instruction{ce.Node.Inst}, inner))
+			}
+		}
+	}
+	return found
+}
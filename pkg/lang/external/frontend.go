@@ -0,0 +1,239 @@
+// Package external runs language frontends as separate processes.
+//
+// The scanner launches the configured executable once per file, writes a single
+// Request document to its stdin and reads a single Response document from its stdout.
+// The response is validated against Schema (schema.json) and then checked for
+// consistency (block references, entry blocks) before the engine sees it.
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sast-demo/pkg/core"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion identifies the request/response format. Frontends must echo it back.
+const ProtocolVersion = "sast-frontend/v1"
+
+const defaultTimeout = 60 * time.Second
+
+// Config describes one external frontend executable
+type Config struct {
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"` // e.g. [".py"]
+	Command    string   `json:"command"`    // Relative paths of files next to the config file are resolved against it
+	Args       []string `json:"args"`
+	Timeout    int      `json:"timeout_seconds"` // 0 means the default (60s)
+}
+
+// FileConfig is the on-disk format of a frontends configuration file
+type FileConfig struct {
+	Frontends []Config `json:"frontends"`
+}
+
+// Request is sent to the frontend on stdin
+type Request struct {
+	Protocol string `json:"protocol"`
+	File     string `json:"file"`     // Absolute path, for reference only
+	Content  string `json:"content"`  // Full file contents
+	Encoding string `json:"encoding"` // Always "utf-8" in v1
+}
+
+// Response is read from the frontend's stdout
+type Response struct {
+	Protocol string          `json:"protocol"`
+	IR       *core.ProgramIR `json:"ir"`
+	AST      *core.ASTNode   `json:"ast"`
+	Logs     []string        `json:"logs"`
+	Error    string          `json:"error"`
+}
+
+// LoadConfig reads a frontends configuration file
+func LoadConfig(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fc FileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i := range fc.Frontends {
+		c := &fc.Frontends[i]
+		if c.Command == "" || len(c.Extensions) == 0 {
+			return nil, fmt.Errorf("%s: frontend #%d needs a command and at least one extension", path, i)
+		}
+		// Scripts shipped with the config are found wherever the scanner runs
+		c.Command = resolveRelative(dir, c.Command)
+		for j, a := range c.Args {
+			c.Args[j] = resolveRelative(dir, a)
+		}
+	}
+	return fc.Frontends, nil
+}
+
+// resolveRelative makes a relative path naming a file in dir absolute; anything
+// else (a command on PATH, a flag) is returned as it is
+func resolveRelative(dir, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	candidate := filepath.Join(dir, p)
+	if info, err := os.Stat(candidate); err != nil || info.IsDir() {
+		return p
+	}
+	if abs, err := filepath.Abs(candidate); err == nil {
+		return abs
+	}
+	return candidate
+}
+
+// Find returns the frontend registered for a file extension, if any
+func Find(cfgs []Config, ext string) *Config {
+	ext = strings.ToLower(ext)
+	for i := range cfgs {
+		for _, e := range cfgs[i].Extensions {
+			if strings.ToLower(e) == ext {
+				return &cfgs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Frontend runs one configured executable
+type Frontend struct {
+	cfg Config
+}
+
+func NewFrontend(cfg Config) *Frontend {
+	return &Frontend{cfg: cfg}
+}
+
+// Run sends the file to the frontend process and returns its validated response
+func (f *Frontend) Run(filePath string) (*Response, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := json.Marshal(Request{
+		Protocol: ProtocolVersion,
+		File:     absPath,
+		Content:  string(content),
+		Encoding: "utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultTimeout
+	if f.cfg.Timeout > 0 {
+		timeout = time.Duration(f.cfg.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, f.cfg.Command, f.cfg.Args...)
+	cmd.Stdin = bytes.NewReader(req)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("frontend %s timed out after %s", f.name(), timeout)
+		}
+		return nil, fmt.Errorf("frontend %s failed: %v: %s", f.name(), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return DecodeResponse(stdout.Bytes())
+}
+
+func (f *Frontend) name() string {
+	if f.cfg.Name != "" {
+		return f.cfg.Name
+	}
+	return f.cfg.Command
+}
+
+// DecodeResponse validates a raw response against the schema and the IR invariants the engine relies on
+func DecodeResponse(data []byte) (*Response, error) {
+	if err := ValidateResponse(data); err != nil {
+		return nil, err
+	}
+
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("frontend reported: %s", resp.Error)
+	}
+	if resp.IR == nil {
+		return nil, fmt.Errorf("response has neither ir nor error")
+	}
+	if err := checkProgram(resp.IR); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// checkProgram verifies cross references that a JSON Schema cannot express
+func checkProgram(prog *core.ProgramIR) error {
+	seen := make(map[string]string)
+	for key, fn := range prog.Functions {
+		if fn == nil {
+			return fmt.Errorf("function %q is null", key)
+		}
+		if fn.Name != key {
+			return fmt.Errorf("function key %q does not match name %q", key, fn.Name)
+		}
+		if fn.Blocks[fn.Entry] == nil {
+			return fmt.Errorf("function %s: entry block %q does not exist", key, fn.Entry)
+		}
+		for id, bb := range fn.Blocks {
+			if bb == nil || bb.ID != id {
+				return fmt.Errorf("function %s: block key %q does not match its id", key, id)
+			}
+			for _, succ := range bb.Successors {
+				if fn.Blocks[succ] == nil {
+					return fmt.Errorf("function %s: block %s has unknown successor %q", key, id, succ)
+				}
+			}
+			for _, pred := range bb.Predecessors {
+				if fn.Blocks[pred] == nil {
+					return fmt.Errorf("function %s: block %s has unknown predecessor %q", key, id, pred)
+				}
+			}
+			for _, inst := range bb.Instructions {
+				if prev, dup := seen[inst.ID]; dup {
+					return fmt.Errorf("instruction id %q used in both %s and %s/%s", inst.ID, prev, key, id)
+				}
+				seen[inst.ID] = key + "/" + id
+				// BRANCH operands are [condition, then, else]; both targets must be successors
+				if inst.Op == core.OpBranch && len(inst.Operands) == 3 {
+					for _, target := range inst.Operands[1:] {
+						if !slices.Contains(bb.Successors, target) {
+							return fmt.Errorf("function %s: branch %s in block %s targets %q, which is not a successor", key, inst.ID, id, target)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
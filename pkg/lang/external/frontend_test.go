@@ -0,0 +1,88 @@
+package external
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// response wraps the blocks of a function f in a response document
+func response(blocks string) string {
+	return `{"protocol": "sast-frontend/v1", "ir": {"functions": {"f": {"name": "f", "entry": "B0", "blocks": {` + blocks + `}}}}}`
+}
+
+const branchBlocks = `
+	"B0": {"id": "B0", "successors": ["B1", "B2"], "instructions": [
+		{"id": "i1", "op": "LOAD", "result": "t1", "operands": ["x"], "line": 1, "code": "x"},
+		{"id": "i2", "op": "BRANCH", "operands": ["t1", "B1", "B2"], "line": 1, "code": "if x"}]},
+	"B1": {"id": "B1", "predecessors": ["B0"], "instructions": []},
+	"B2": {"id": "B2", "predecessors": ["B0"], "instructions": []}`
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  string // Part of the expected error, "" for none
+	}{
+		{"valid", response(branchBlocks), ""},
+		{"not json", `{"protocol": `, "not valid JSON"},
+		{"wrong protocol", `{"protocol": "v0"}`, "is not one of"},
+		{"unknown property", `{"protocol": "sast-frontend/v1", "extra": 1}`, `unexpected property "extra"`},
+		{"unknown op", response(`"B0": {"id": "B0", "instructions": [{"id": "i1", "op": "GOTO", "line": 1, "code": ""}]}`), "$.ir.functions.f.blocks.B0.instructions[0].op"},
+		{"negative line", response(`"B0": {"id": "B0", "instructions": [{"id": "i1", "op": "RET", "line": -1, "code": ""}]}`), "below minimum"},
+		{"reported error", `{"protocol": "sast-frontend/v1", "error": "syntax error"}`, "frontend reported: syntax error"},
+		{"neither ir nor error", `{"protocol": "sast-frontend/v1"}`, "neither ir nor error"},
+		{"missing entry", response(`"B1": {"id": "B1", "instructions": []}`), `entry block "B0" does not exist`},
+		{"mismatched block id", response(`"B0": {"id": "B1", "instructions": []}`), "does not match its id"},
+		{"unknown successor", response(`"B0": {"id": "B0", "successors": ["B9"], "instructions": []}`), `unknown successor "B9"`},
+		{"branch to unknown block", response(strings.Replace(branchBlocks, `["t1", "B1", "B2"]`, `["true", "0", "0"]`, 1)), `targets "0"`},
+		{"branch to non-successor", response(strings.Replace(branchBlocks, `"successors": ["B1", "B2"]`, `"successors": ["B1"]`, 1)), `targets "B2"`},
+		{"duplicate instruction id", response(strings.Replace(branchBlocks, `"id": "i2"`, `"id": "i1"`, 1)), `instruction id "i1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := DecodeResponse([]byte(tt.doc))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err == "" && resp.IR.Functions["f"] == nil:
+				t.Errorf("function f missing from %+v", resp.IR)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "frontend.py")
+	if err := os.WriteFile(script, []byte("print()"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "frontends.json")
+	config := `{"frontends": [{"name": "py", "extensions": [".PY"], "command": "python3", "args": ["frontend.py", "-v"]}]}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfgs, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files next to the config resolve against it; commands on PATH and flags stay as they are
+	if c := cfgs[0]; c.Command != "python3" || c.Args[0] != script || c.Args[1] != "-v" {
+		t.Errorf("loaded %+v", c)
+	}
+	if Find(cfgs, ".py") == nil || Find(cfgs, ".go") != nil {
+		t.Errorf("extension lookup is wrong for %v", cfgs[0].Extensions)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"frontends": [{"name": "py", "extensions": []}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("a frontend without a command or extensions was accepted")
+	}
+}
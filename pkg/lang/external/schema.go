@@ -0,0 +1,196 @@
+package external
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema is the JSON Schema (draft-07 subset) every frontend response must satisfy
+//
+//go:embed schema.json
+var Schema []byte
+
+// schemaNode is the subset of JSON Schema keywords used by schema.json
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 interface{}            `json:"type"` // string or []string
+	Required             []string               `json:"required"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"` // bool or schema
+	Items                *schemaNode            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	MinLength            *int                   `json:"minLength"`
+	Minimum              *float64               `json:"minimum"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+}
+
+type validator struct {
+	root   *schemaNode
+	errors []string
+}
+
+// ValidateResponse checks a raw response document against Schema, returning every violation
+func ValidateResponse(doc []byte) error {
+	var root schemaNode
+	if err := json.Unmarshal(Schema, &root); err != nil {
+		return fmt.Errorf("invalid embedded schema: %v", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(doc, &value); err != nil {
+		return fmt.Errorf("response is not valid JSON: %v", err)
+	}
+
+	v := &validator{root: &root}
+	v.validate(&root, value, "$")
+	if len(v.errors) > 0 {
+		if len(v.errors) > 10 {
+			v.errors = append(v.errors[:10], fmt.Sprintf("... and %d more", len(v.errors)-10))
+		}
+		return fmt.Errorf("response does not match schema: %s", strings.Join(v.errors, "; "))
+	}
+	return nil
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) resolve(s *schemaNode) *schemaNode {
+	for s != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		s = v.root.Definitions[name]
+	}
+	return s
+}
+
+func (v *validator) validate(s *schemaNode, value interface{}, path string) {
+	s = v.resolve(s)
+	if s == nil {
+		return
+	}
+
+	if s.Type != nil && !matchesType(s.Type, value) {
+		v.fail(path, "expected %v, got %s", s.Type, jsonType(value))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "value %v is not one of %v", value, s.Enum)
+		}
+	}
+
+	switch x := value.(type) {
+	case string:
+		if s.MinLength != nil && len(x) < *s.MinLength {
+			v.fail(path, "string shorter than %d", *s.MinLength)
+		}
+	case float64:
+		if s.Minimum != nil && x < *s.Minimum {
+			v.fail(path, "value %v below minimum %v", x, *s.Minimum)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range x {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case map[string]interface{}:
+		for _, req := range s.Required {
+			if _, ok := x[req]; !ok {
+				v.fail(path, "missing required property %q", req)
+			}
+		}
+
+		// Visit keys in a stable order so error messages are reproducible
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		additional := v.additional(s)
+		for _, k := range keys {
+			childPath := path + "." + k
+			if prop, ok := s.Properties[k]; ok {
+				v.validate(prop, x[k], childPath)
+			} else if additional == nil {
+				v.fail(path, "unexpected property %q", k)
+			} else if additional != allowAny {
+				v.validate(additional, x[k], childPath)
+			}
+		}
+	}
+}
+
+// allowAny marks an omitted or `true` additionalProperties
+var allowAny = &schemaNode{}
+
+func (v *validator) additional(s *schemaNode) *schemaNode {
+	raw := strings.TrimSpace(string(s.AdditionalProperties))
+	switch raw {
+	case "", "true":
+		return allowAny
+	case "false":
+		return nil
+	}
+	var sub schemaNode
+	if err := json.Unmarshal(s.AdditionalProperties, &sub); err != nil {
+		return allowAny
+	}
+	return &sub
+}
+
+func matchesType(t interface{}, value interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return typeMatches(tt, value)
+	case []interface{}:
+		for _, alt := range tt {
+			if name, ok := alt.(string); ok && typeMatches(name, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeMatches(name string, value interface{}) bool {
+	actual := jsonType(value)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+	return name == actual
+}
+
+func jsonType(value interface{}) string {
+	switch x := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if x == float64(int64(x)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
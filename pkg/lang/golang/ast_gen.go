@@ -123,6 +123,10 @@ func (g *ASTGenerator) visit(node ast.Node, parent *core.ASTNode, idPrefix strin
 }
 
 func isASTNode(t reflect.Type) bool {
+	// Nodes are pointers or interfaces; other go/ast types such as ChanDir are plain values
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface {
+		return false
+	}
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
@@ -5,7 +5,10 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"path"
 	"sast-demo/pkg/core"
+	"strconv"
+	"strings"
 )
 
 type IRGenerator struct {
@@ -17,12 +20,18 @@ type IRGenerator struct {
 	currentBlock *core.BasicBlock
 	blockCount   int
 	instCount    int
+
+	// Import names in the current file; selectors on them are package functions, not methods
+	imports map[string]bool
+	// Function literals lowered so far per enclosing function, for naming them
+	funcLits map[string]int
 }
 
 func NewIRGenerator() *IRGenerator {
 	return &IRGenerator{
-		fset: token.NewFileSet(),
-		prog: core.NewProgramIR(),
+		fset:     token.NewFileSet(),
+		prog:     core.NewProgramIR(),
+		funcLits: make(map[string]int),
 	}
 }
 
@@ -32,6 +41,15 @@ func (g *IRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 		return nil, err
 	}
 
+	g.imports = make(map[string]bool)
+	for _, imp := range node.Imports {
+		if imp.Name != nil {
+			g.imports[imp.Name.Name] = true
+		} else if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+			g.imports[path.Base(p)] = true
+		}
+	}
+
 	for _, decl := range node.Decls {
 		if fn, ok := decl.(*ast.FuncDecl); ok {
 			g.processFunction(fn)
@@ -54,6 +72,18 @@ func (g *IRGenerator) processFunction(fn *ast.FuncDecl) {
 	g.currentFunc.Entry = entryBlock.ID
 	g.currentBlock = entryBlock
 
+	// Methods are named Type.Method, so that methods of different types do not
+	// collide; the receiver is the first parameter
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		recv := fn.Recv.List[0]
+		g.currentFunc.Name = receiverType(recv.Type) + "." + funcName
+		funcName = g.currentFunc.Name
+		if len(recv.Names) > 0 && recv.Names[0].Name != "_" {
+			g.currentFunc.Receiver = recv.Names[0].Name
+			g.emit(core.OpParam, recv.Names[0].Name, nil, recv.Names[0].Pos())
+		}
+	}
+
 	// Process params
 	for _, field := range fn.Type.Params.List {
 		for _, name := range field.Names {
@@ -67,6 +97,23 @@ func (g *IRGenerator) processFunction(fn *ast.FuncDecl) {
 	g.prog.Functions[funcName] = g.currentFunc
 }
 
+// receiverType names the type of a method receiver, without pointer or type parameters
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.ParenExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "expr"
+}
+
 func (g *IRGenerator) processBlockStmt(block *ast.BlockStmt) {
 	for _, stmt := range block.List {
 		g.processStmt(stmt)
@@ -85,10 +132,47 @@ func (g *IRGenerator) processStmt(stmt ast.Stmt) {
 			// Generate code for RHS
 			rhsRes := g.processExpr(rhs)
 
-			// Store to LHS
-			if ident, ok := lhs.(*ast.Ident); ok {
+			// Store to LHS: a variable or a field path such as cfg.URL
+			if name := g.lvalue(lhs); name != "" {
+				if s.Tok != token.ASSIGN && s.Tok != token.DEFINE {
+					// x += y is x = x + y
+					cur := g.processExpr(lhs)
+					res := g.tempVar()
+					g.emit(core.OpBinOp, res, []string{cur, strings.TrimSuffix(s.Tok.String(), "="), rhsRes}, s.Pos())
+					rhsRes = res
+				}
 				// x = ...
-				g.emit(core.OpStore, ident.Name, []string{rhsRes}, s.Pos())
+				g.emit(core.OpStore, name, []string{rhsRes}, s.Pos())
+			}
+		}
+	case *ast.IncDecStmt:
+		if name := g.lvalue(s.X); name != "" {
+			cur := g.processExpr(s.X)
+			one := g.tempVar()
+			g.emit(core.OpConst, one, []string{"1"}, s.Pos())
+			op := "+"
+			if s.Tok == token.DEC {
+				op = "-"
+			}
+			res := g.tempVar()
+			g.emit(core.OpBinOp, res, []string{cur, op, one}, s.Pos())
+			g.emit(core.OpStore, name, []string{res}, s.Pos())
+		}
+	case *ast.DeclStmt:
+		// var x = ... and const x = ... inside functions
+		if gd, ok := s.Decl.(*ast.GenDecl); ok {
+			for _, spec := range gd.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for i, name := range vs.Names {
+					var ops []string
+					if i < len(vs.Values) {
+						ops = []string{g.processExpr(vs.Values[i])}
+					}
+					g.emit(core.OpStore, name.Name, ops, name.Pos())
+				}
 			}
 		}
 	case *ast.ExprStmt:
@@ -103,11 +187,61 @@ func (g *IRGenerator) processStmt(stmt ast.Stmt) {
 		g.processIf(s)
 	case *ast.BlockStmt:
 		g.processBlockStmt(s)
+	case *ast.LabeledStmt:
+		g.processStmt(s.Stmt)
+	case *ast.GoStmt:
+		// The goroutine body is a callee like any other: when it runs does not
+		// matter to a flow-insensitive view of its arguments
+		g.processExpr(s.Call)
+	case *ast.DeferStmt:
+		g.processExpr(s.Call)
+	case *ast.SendStmt:
+		// ch <- v stores v into the elements of ch
+		v := g.processExpr(s.Value)
+		g.emit(core.OpStore, g.chanPath(s.Chan), []string{v}, s.Pos())
+	case *ast.SelectStmt:
+		g.processSelect(s)
+	case *ast.ForStmt:
+		g.processFor(s)
+	case *ast.RangeStmt:
+		g.processRange(s)
+	default:
+		g.unknownStores(s)
 	}
 }
 
+// unknownStores records that the variables assigned by a statement the generator does
+// not lower (loops, switches, ...) take unknown values, so no analysis treats them as
+// constants. A store without operands stands for an unknown value.
+func (g *IRGenerator) unknownStores(stmt ast.Stmt) {
+	seen := make(map[string]bool)
+	store := func(e ast.Expr) {
+		if name := g.lvalue(e); name != "" && !seen[name] {
+			seen[name] = true
+			g.emit(core.OpStore, name, nil, e.Pos())
+		}
+	}
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				store(lhs)
+			}
+		case *ast.IncDecStmt:
+			store(n.X)
+		case *ast.RangeStmt:
+			store(n.Key)
+			store(n.Value)
+		}
+		return true
+	})
+}
+
 func (g *IRGenerator) processIf(s *ast.IfStmt) {
-	// 1. Condition
+	// 1. Init statement and condition
+	if s.Init != nil {
+		g.processStmt(s.Init)
+	}
 	condRes := g.processExpr(s.Cond)
 
 	// 2. Blocks
@@ -125,9 +259,11 @@ func (g *IRGenerator) processIf(s *ast.IfStmt) {
 	// Process Then
 	g.currentBlock = thenBlock
 	g.processBlockStmt(s.Body)
-	// Jump to merge
-	g.emit(core.OpJump, "", []string{mergeBlock.ID}, s.Body.End())
-	g.linkBlocks(g.currentBlock, mergeBlock)
+	// Jump to merge, unless the branch already returned
+	if !terminated(g.currentBlock) {
+		g.emit(core.OpJump, "", []string{mergeBlock.ID}, s.Body.End())
+		g.linkBlocks(g.currentBlock, mergeBlock)
+	}
 
 	// Process Else
 	g.currentBlock = elseBlock
@@ -136,14 +272,170 @@ func (g *IRGenerator) processIf(s *ast.IfStmt) {
 		g.processStmt(s.Else)
 		elseEndPos = s.Else.Pos()
 	}
-	// Jump to merge
-	g.emit(core.OpJump, "", []string{mergeBlock.ID}, elseEndPos)
-	g.linkBlocks(g.currentBlock, mergeBlock)
+	if !terminated(g.currentBlock) {
+		g.emit(core.OpJump, "", []string{mergeBlock.ID}, elseEndPos)
+		g.linkBlocks(g.currentBlock, mergeBlock)
+	}
 
 	// Continue in merge block
 	g.currentBlock = mergeBlock
 }
 
+// processSelect lowers a select as a chain of branches on no condition, one per
+// case: which case runs is decided at run time. A receive assigned by a case is
+// lowered with the case body.
+func (g *IRGenerator) processSelect(s *ast.SelectStmt) {
+	mergeBlock := g.newBlock()
+	clauses := s.Body.List
+	for i, stmt := range clauses {
+		cc := stmt.(*ast.CommClause)
+		if i < len(clauses)-1 {
+			caseBlock := g.newBlock()
+			nextBlock := g.newBlock()
+			g.emit(core.OpBranch, "", []string{"", caseBlock.ID, nextBlock.ID}, cc.Pos())
+			g.linkBlocks(g.currentBlock, caseBlock)
+			g.linkBlocks(g.currentBlock, nextBlock)
+			g.currentBlock = caseBlock
+			g.processCommClause(cc, mergeBlock)
+			g.currentBlock = nextBlock
+			continue
+		}
+		g.processCommClause(cc, mergeBlock)
+	}
+	if len(clauses) == 0 {
+		// select {} blocks forever
+		return
+	}
+	g.currentBlock = mergeBlock
+}
+
+func (g *IRGenerator) processCommClause(cc *ast.CommClause, mergeBlock *core.BasicBlock) {
+	if cc.Comm != nil {
+		g.processStmt(cc.Comm)
+	}
+	for _, stmt := range cc.Body {
+		g.processStmt(stmt)
+	}
+	if !terminated(g.currentBlock) {
+		g.emit(core.OpJump, "", []string{mergeBlock.ID}, cc.End())
+		g.linkBlocks(g.currentBlock, mergeBlock)
+	}
+}
+
+// processFor lowers a loop as a header testing the condition, and the body
+// followed by the post statement, which jumps back to the header
+func (g *IRGenerator) processFor(s *ast.ForStmt) {
+	if s.Init != nil {
+		g.processStmt(s.Init)
+	}
+	g.loop(s.Pos(), func() string {
+		return g.processExpr(s.Cond)
+	}, s.Body, s.Post)
+}
+
+// processRange lowers `for k, v := range x` as a loop whose key and value are
+// read from x: the container itself, or the elements of a channel. Which one x
+// is is not known without types, so both are read. x is evaluated once, before
+// the loop.
+func (g *IRGenerator) processRange(s *ast.RangeStmt) {
+	x := g.processExpr(s.X)
+	// A channel named by a path is received from by that path, so that sends on
+	// the same variable or field meet the receive
+	recvPath := x + "." + core.ChanField
+	if name := g.fieldPath(s.X); name != "" {
+		recvPath = name + "." + core.ChanField
+	}
+	g.loop(s.Pos(), func() string {
+		elem := g.tempVar()
+		g.emit(core.OpLoad, elem, []string{x}, s.Pos())
+		recv := g.tempVar()
+		g.emit(core.OpLoad, recv, []string{recvPath}, s.Pos())
+		res := g.tempVar()
+		g.emit(core.OpPhi, res, []string{elem, recv}, s.Pos())
+		for _, e := range []ast.Expr{s.Key, s.Value} {
+			if name := g.lvalue(e); name != "" && name != "_" {
+				g.emit(core.OpStore, name, []string{res}, e.Pos())
+			}
+		}
+		return ""
+	}, s.Body, nil)
+}
+
+// loop emits a header computed by header, which returns the loop condition and
+// leaves to the body or the exit block, and the body followed by post, which
+// goes back to the header
+func (g *IRGenerator) loop(pos token.Pos, header func() string, body *ast.BlockStmt, post ast.Stmt) {
+	headerBlock := g.newBlock()
+	bodyBlock := g.newBlock()
+	exitBlock := g.newBlock()
+
+	g.emit(core.OpJump, "", []string{headerBlock.ID}, pos)
+	g.linkBlocks(g.currentBlock, headerBlock)
+
+	g.currentBlock = headerBlock
+	cond := header()
+	g.emit(core.OpBranch, "", []string{cond, bodyBlock.ID, exitBlock.ID}, pos)
+	g.linkBlocks(g.currentBlock, bodyBlock)
+	g.linkBlocks(g.currentBlock, exitBlock)
+
+	g.currentBlock = bodyBlock
+	g.processBlockStmt(body)
+	if !terminated(g.currentBlock) {
+		if post != nil {
+			g.processStmt(post)
+		}
+		g.emit(core.OpJump, "", []string{headerBlock.ID}, body.End())
+		g.linkBlocks(g.currentBlock, headerBlock)
+	}
+	g.currentBlock = exitBlock
+}
+
+// chanPath is the access path of the elements of a channel
+func (g *IRGenerator) chanPath(ch ast.Expr) string {
+	if name := g.fieldPath(ch); name != "" {
+		return name + "." + core.ChanField
+	}
+	return g.processExpr(ch) + "." + core.ChanField
+}
+
+// receive loads an element of a channel
+func (g *IRGenerator) receive(ch ast.Expr, pos token.Pos) string {
+	path := g.chanPath(ch)
+	res := g.tempVar()
+	g.emit(core.OpLoad, res, []string{path}, pos)
+	return res
+}
+
+// processFuncLit lowers a function literal as a function of its own, named as
+// the Go runtime does (handler.func1), and returns the name. Variables it captures
+// keep their names; the engine resolves them to the enclosing function by the name
+// of the literal.
+func (g *IRGenerator) processFuncLit(lit *ast.FuncLit) string {
+	outer := g.currentFunc.Name
+	g.funcLits[outer]++
+	name := fmt.Sprintf("%s.func%d", outer, g.funcLits[outer])
+
+	savedFunc, savedBlock, savedCount := g.currentFunc, g.currentBlock, g.blockCount
+	g.currentFunc = &core.FunctionIR{
+		Name:   name,
+		Blocks: make(map[string]*core.BasicBlock),
+	}
+	g.blockCount = 0
+	entryBlock := g.newBlock()
+	g.currentFunc.Entry = entryBlock.ID
+	g.currentBlock = entryBlock
+	for _, field := range lit.Type.Params.List {
+		for _, n := range field.Names {
+			g.emit(core.OpParam, n.Name, nil, n.Pos())
+		}
+	}
+	g.processBlockStmt(lit.Body)
+	g.prog.Functions[name] = g.currentFunc
+
+	g.currentFunc, g.currentBlock, g.blockCount = savedFunc, savedBlock, savedCount
+	return name
+}
+
 func (g *IRGenerator) processExpr(expr ast.Expr) string {
 	if expr == nil {
 		return ""
@@ -161,10 +453,17 @@ func (g *IRGenerator) processExpr(expr ast.Expr) string {
 		return res
 	case *ast.CallExpr:
 		funName := "unknown"
+		receiver := ""
 		if id, ok := e.Fun.(*ast.Ident); ok {
 			funName = id.Name
+		} else if lit, ok := e.Fun.(*ast.FuncLit); ok {
+			funName = g.processFuncLit(lit)
 		} else if sel, ok := e.Fun.(*ast.SelectorExpr); ok {
 			funName = g.resolveFlatName(sel)
+			// Method call: evaluate the receiver so taint on the object reaches the call
+			if id, ok := sel.X.(*ast.Ident); !ok || !g.imports[id.Name] {
+				receiver = g.processExpr(sel.X)
+			}
 		}
 
 		var args []string
@@ -175,6 +474,7 @@ func (g *IRGenerator) processExpr(expr ast.Expr) string {
 		res := g.tempVar()
 		ops := append([]string{funName}, args...)
 		g.emit(core.OpCall, res, ops, e.Pos())
+		g.currentBlock.Instructions[len(g.currentBlock.Instructions)-1].Receiver = receiver
 		return res
 	case *ast.BinaryExpr:
 		left := g.processExpr(e.X)
@@ -182,27 +482,195 @@ func (g *IRGenerator) processExpr(expr ast.Expr) string {
 		res := g.tempVar()
 		g.emit(core.OpBinOp, res, []string{left, e.Op.String(), right}, e.Pos())
 		return res
+	case *ast.UnaryExpr:
+		if e.Op == token.ARROW {
+			return g.receive(e.X, e.Pos())
+		}
+		x := g.processExpr(e.X)
+		res := g.tempVar()
+		g.emit(core.OpBinOp, res, []string{e.Op.String(), x}, e.Pos())
+		return res
+	case *ast.ParenExpr:
+		return g.processExpr(e.X)
+	case *ast.StarExpr:
+		// *p loads through the pointer variable; other dereferences are unary operations
+		if name := g.fieldPath(e); name != "" {
+			res := g.tempVar()
+			g.emit(core.OpLoad, res, []string{name}, e.Pos())
+			return res
+		}
+		x := g.processExpr(e.X)
+		res := g.tempVar()
+		g.emit(core.OpBinOp, res, []string{"*", x}, e.Pos())
+		return res
 	case *ast.SelectorExpr:
-		// Handle field access like r.URL.Query
-		// If it's part of a call, it's handled in CallExpr.
-		// If it's a standalone access (like struct field), we resolve it.
-		// However, complex selectors might need to be processed if they involve function calls.
-		// For now, keep simple resolution for identifiers.
-		name := g.resolveFlatName(e)
+		// Field access: a.b.c loads the access path as one name; fields of
+		// computed values (f().URL) load the path below the value's temporary
+		name := g.fieldPath(e)
+		if name == "" {
+			if x := g.processExpr(e.X); x != "" {
+				name = x + "." + e.Sel.Name
+			} else {
+				name = g.resolveFlatName(e)
+			}
+		}
+		res := g.tempVar()
+		g.emit(core.OpLoad, res, []string{name}, e.Pos())
+		return res
+	case *ast.CompositeLit:
+		return g.processCompositeLit(e)
+	case *ast.FuncLit:
+		// A function value: loading the function's name
+		name := g.processFuncLit(e)
 		res := g.tempVar()
 		g.emit(core.OpLoad, res, []string{name}, e.Pos())
 		return res
 	case *ast.IndexExpr:
-		// Handle array access like os.Args[1]
-		// recursively process the container expression
-		container := g.processExpr(e.X)
+		// Element access like os.Args[1] loads the element path (os.Args.[].[1]);
+		// elements of computed values load the path below the value's temporary
+		if elemKey(e.Index) == "" {
+			g.processExpr(e.Index)
+		}
+		name := g.fieldPath(e)
+		if name == "" {
+			name = g.processExpr(e.X) + elemSuffix(e.Index)
+		}
+		res := g.tempVar()
+		g.emit(core.OpLoad, res, []string{name}, e.Pos())
+		return res
+	case *ast.SliceExpr:
+		// s[i:j] shifts the indexes of the elements, so the result is tainted as a whole
+		x := g.processExpr(e.X)
+		for _, idx := range []ast.Expr{e.Low, e.High, e.Max} {
+			g.processExpr(idx)
+		}
 		res := g.tempVar()
-		g.emit(core.OpLoad, res, []string{container}, e.Pos())
+		g.emit(core.OpBinOp, res, []string{"[:]", x}, e.Pos())
 		return res
 	}
 	return ""
 }
 
+// elemKey is the constant index or key of an element access, "" if it is not one
+func elemKey(index ast.Expr) string {
+	lit, ok := index.(*ast.BasicLit)
+	if !ok {
+		return ""
+	}
+	switch lit.Kind {
+	case token.INT:
+		if n, err := strconv.ParseInt(lit.Value, 0, 64); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+	case token.STRING, token.CHAR:
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			return s
+		}
+	}
+	return ""
+}
+
+// elemSuffix is the path of an element below its container
+func elemSuffix(index ast.Expr) string {
+	return core.ElemPath(elemKey(index))
+}
+
+// processCompositeLit builds a literal as a call of T{} on its elements. Struct
+// fields set by name are stored into the fields of the result instead, so that
+// Config{URL: u} taints only its URL field, and so are the elements of slice,
+// array and map literals: []string{"ls", dir} taints only element 1.
+func (g *IRGenerator) processCompositeLit(e *ast.CompositeLit) string {
+	_, isMap := e.Type.(*ast.MapType)
+	_, isArray := e.Type.(*ast.ArrayType)
+	type field struct {
+		name  string
+		value string
+		pos   token.Pos
+	}
+	var fields []field
+	var args []string
+	index := 0
+	for _, elt := range e.Elts {
+		var key ast.Expr
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if id, ok := kv.Key.(*ast.Ident); ok && !isMap && !isArray {
+				fields = append(fields, field{id.Name, g.processExpr(kv.Value), kv.Pos()})
+				continue
+			}
+			key = kv.Key
+			if elemKey(key) == "" {
+				g.processExpr(key)
+			}
+			elt = kv.Value
+		}
+		if !isMap && !isArray {
+			args = append(args, g.processExpr(elt))
+			continue
+		}
+		// Elements without a key follow the previous index
+		k := elemKey(key)
+		if key == nil {
+			k = strconv.Itoa(index)
+		} else if n, err := strconv.Atoi(k); err == nil && isArray {
+			index = n
+		}
+		index++
+		fields = append(fields, field{strings.TrimPrefix(core.ElemPath(k), "."), g.processExpr(elt), elt.Pos()})
+	}
+	res := g.tempVar()
+	g.emit(core.OpCall, res, append([]string{g.typeName(e.Type) + "{}"}, args...), e.Pos())
+	for _, f := range fields {
+		g.emit(core.OpStore, res+"."+f.name, []string{f.value}, f.pos)
+	}
+	return res
+}
+
+func (g *IRGenerator) typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case nil:
+		return ""
+	case *ast.ArrayType:
+		return "[]" + g.typeName(t.Elt)
+	case *ast.MapType:
+		return "map[" + g.typeName(t.Key) + "]" + g.typeName(t.Value)
+	}
+	return g.resolveFlatName(expr)
+}
+
+// fieldPath is the access path of a chain of field selections on a variable
+// (cfg.URL.Host), or "" when the chain starts at a computed value. A pointer
+// variable may be dereferenced first: *p and (*p).Name are written *p and *p.Name.
+func (g *IRGenerator) fieldPath(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.ParenExpr:
+		return g.fieldPath(e.X)
+	case *ast.StarExpr:
+		if id, ok := e.X.(*ast.Ident); ok {
+			return "*" + id.Name
+		}
+	case *ast.SelectorExpr:
+		if base := g.fieldPath(e.X); base != "" {
+			return base + "." + e.Sel.Name
+		}
+	case *ast.IndexExpr:
+		if base := g.fieldPath(e.X); base != "" {
+			return base + elemSuffix(e.Index)
+		}
+	}
+	return ""
+}
+
+// lvalue is the variable or field path an assignment stores to, "" for
+// targets the IR does not model (elements, dereferences, the blank identifier)
+func (g *IRGenerator) lvalue(expr ast.Expr) string {
+	if id, ok := expr.(*ast.Ident); ok && id.Name == "_" {
+		return ""
+	}
+	return g.fieldPath(expr)
+}
+
 func (g *IRGenerator) resolveFlatName(expr ast.Expr) string {
 	switch e := expr.(type) {
 	case *ast.Ident:
@@ -231,6 +699,12 @@ func (g *IRGenerator) newBlock() *core.BasicBlock {
 	return bb
 }
 
+// terminated reports whether bb ends in a return, so control never falls through it
+func terminated(bb *core.BasicBlock) bool {
+	n := len(bb.Instructions)
+	return n > 0 && bb.Instructions[n-1].Op == core.OpRet
+}
+
 func (g *IRGenerator) linkBlocks(from, to *core.BasicBlock) {
 	from.Successors = append(from.Successors, to.ID)
 	to.Predecessors = append(to.Predecessors, from.ID)
@@ -257,6 +731,9 @@ func (g *IRGenerator) tempVar() string {
 func (g *IRGenerator) formatCode(op core.OpCode, res string, ops []string) string {
 	switch op {
 	case core.OpStore:
+		if len(ops) == 0 {
+			return fmt.Sprintf("%s = ?", res)
+		}
 		return fmt.Sprintf("%s = %s", res, ops[0])
 	case core.OpLoad:
 		return fmt.Sprintf("%s = load %s", res, ops[0])
@@ -264,6 +741,11 @@ func (g *IRGenerator) formatCode(op core.OpCode, res string, ops []string) strin
 		return fmt.Sprintf("%s = call %s(%v)", res, ops[0], ops[1:])
 	case core.OpConst:
 		return fmt.Sprintf("%s = const %s", res, ops[0])
+	case core.OpBinOp:
+		if len(ops) == 2 { // Unary
+			return fmt.Sprintf("%s = %s%s", res, ops[0], ops[1])
+		}
+		return fmt.Sprintf("%s = %s %v", res, op, ops)
 	case core.OpBranch:
 		return fmt.Sprintf("if %s goto %s else %s", ops[0], ops[1], ops[2])
 	case core.OpJump:
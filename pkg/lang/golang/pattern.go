@@ -0,0 +1,343 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"reflect"
+	"regexp"
+	"sast-demo/pkg/core"
+	"strconv"
+	"strings"
+)
+
+// Pattern syntax is Go source with two extensions:
+//
+//	$X   matches any expression; repeated uses must match the same text
+//	...  matches any sequence of arguments, elements or statements
+//
+// Both are rewritten to plain identifiers so the pattern parses with go/parser.
+const (
+	metavarPrefix = "__sast_mv_"
+	ellipsisIdent = "__sast_ellipsis"
+)
+
+var metavarRegex = regexp.MustCompile(`\$([A-Z][A-Z0-9_]*)`)
+
+// PatternMatcher matches structural patterns against one parsed Go file
+type PatternMatcher struct {
+	fset *token.FileSet
+	file *ast.File
+	src  []byte
+}
+
+func NewPatternMatcher(filePath string) (*PatternMatcher, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &PatternMatcher{fset: fset, file: file, src: src}, nil
+}
+
+func (m *PatternMatcher) Language() string { return "go" }
+
+// Match returns every expression (or statement sequence) in the file matching pattern
+func (m *PatternMatcher) Match(pattern string) ([]core.PatternMatch, error) {
+	src := metavarRegex.ReplaceAllString(pattern, metavarPrefix+"$1")
+	src = strings.ReplaceAll(src, "...", ellipsisIdent)
+
+	if expr, err := parser.ParseExpr(src); err == nil {
+		return m.matchExpr(expr), nil
+	}
+
+	// Not an expression: try a statement list inside a dummy function
+	f, err := parser.ParseFile(token.NewFileSet(), "pattern.go", "package p\nfunc _() {\n"+src+"\n}", 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Go pattern %q: %v", pattern, err)
+	}
+	body := f.Decls[0].(*ast.FuncDecl).Body.List
+	if len(body) == 0 {
+		return nil, fmt.Errorf("empty Go pattern")
+	}
+	return m.matchStmts(body), nil
+}
+
+func (m *PatternMatcher) matchExpr(pattern ast.Expr) []core.PatternMatch {
+	var matches []core.PatternMatch
+	m.walk(func(n ast.Node, fn string) {
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return
+		}
+		b := map[string]string{}
+		if m.matchNode(pattern, expr, b) {
+			matches = append(matches, m.newMatch(expr, expr.End(), fn, b))
+		}
+	})
+	return matches
+}
+
+// matchStmts finds the pattern statements as a subsequence of some block
+func (m *PatternMatcher) matchStmts(pattern []ast.Stmt) []core.PatternMatch {
+	ps := make([]ast.Node, len(pattern))
+	for i, s := range pattern {
+		ps[i] = s
+	}
+	var matches []core.PatternMatch
+	m.walk(func(n ast.Node, fn string) {
+		var list []ast.Stmt
+		switch x := n.(type) {
+		case *ast.BlockStmt:
+			list = x.List
+		case *ast.CaseClause:
+			list = x.Body
+		case *ast.CommClause:
+			list = x.Body
+		default:
+			return
+		}
+		for start := range list {
+			for end := start + 1; end <= len(list); end++ {
+				ns := make([]ast.Node, end-start)
+				for i, s := range list[start:end] {
+					ns[i] = s
+				}
+				b := map[string]string{}
+				if m.matchList(ps, ns, b) {
+					matches = append(matches, m.newMatch(list[start], list[end-1].End(), fn, b))
+					break
+				}
+			}
+		}
+	})
+	return matches
+}
+
+// walk visits every node together with the name of its enclosing function
+func (m *PatternMatcher) walk(visit func(n ast.Node, fn string)) {
+	for _, decl := range m.file.Decls {
+		fn := ""
+		if fd, ok := decl.(*ast.FuncDecl); ok {
+			fn = fd.Name.Name
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if n != nil {
+				visit(n, fn)
+			}
+			return true
+		})
+	}
+}
+
+func (m *PatternMatcher) newMatch(start ast.Node, end token.Pos, fn string, b map[string]string) core.PatternMatch {
+	code := m.text(start)
+	if _, ok := start.(ast.Stmt); ok && end > start.End() {
+		// Statement sequences: report the source range rather than a single statement
+		code = m.rangeText(start.Pos(), end)
+	}
+	bindings := make(map[string]string, len(b))
+	for k, v := range b {
+		bindings["$"+strings.TrimPrefix(k, metavarPrefix)] = v
+	}
+	return core.PatternMatch{
+		Line:     m.fset.Position(start.Pos()).Line,
+		Code:     code,
+		Function: fn,
+		Bindings: bindings,
+	}
+}
+
+func (m *PatternMatcher) text(n ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, m.fset, n); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func (m *PatternMatcher) rangeText(from, to token.Pos) string {
+	start, end := m.fset.Position(from).Offset, m.fset.Position(to).Offset
+	if start < 0 || end > len(m.src) || start > end {
+		return ""
+	}
+	return string(m.src[start:end])
+}
+
+// --- Structural comparison ---
+
+func isEllipsis(n ast.Node) bool {
+	switch x := n.(type) {
+	case *ast.Ident:
+		return x.Name == ellipsisIdent
+	case *ast.ExprStmt:
+		return isEllipsis(x.X)
+	}
+	return false
+}
+
+func metavarName(n ast.Node) string {
+	if id, ok := n.(*ast.Ident); ok && strings.HasPrefix(id.Name, metavarPrefix) {
+		return id.Name
+	}
+	return ""
+}
+
+func isNilNode(n ast.Node) bool {
+	return n == nil || reflect.ValueOf(n).IsNil()
+}
+
+// matchNode reports whether node n has the shape of pattern p, recording metavariable bindings in b
+func (m *PatternMatcher) matchNode(p, n ast.Node, b map[string]string) bool {
+	if isNilNode(p) {
+		return isNilNode(n)
+	}
+	if isNilNode(n) {
+		return false
+	}
+	if isEllipsis(p) {
+		return true
+	}
+	if mv := metavarName(p); mv != "" {
+		if _, ok := n.(ast.Expr); !ok {
+			return false
+		}
+		text := m.text(n)
+		if prev, bound := b[mv]; bound {
+			return prev == text
+		}
+		b[mv] = text
+		return true
+	}
+	// A bare expression pattern also matches the same expression used as a statement
+	if ps, ok := p.(*ast.ExprStmt); ok {
+		if ns, ok := n.(*ast.ExprStmt); ok {
+			return m.matchNode(ps.X, ns.X, b)
+		}
+		return false
+	}
+	// Parentheses are not significant
+	if pe, ok := n.(*ast.ParenExpr); ok {
+		if _, ok := p.(*ast.ParenExpr); !ok {
+			return m.matchNode(p, pe.X, b)
+		}
+	}
+
+	if reflect.TypeOf(p) != reflect.TypeOf(n) {
+		return false
+	}
+	switch pp := p.(type) {
+	case *ast.Ident:
+		return pp.Name == n.(*ast.Ident).Name
+	case *ast.BasicLit:
+		nn := n.(*ast.BasicLit)
+		if pp.Kind != nn.Kind {
+			return false
+		}
+		// "sh" and `sh` are the same string
+		pv, err1 := strconv.Unquote(pp.Value)
+		nv, err2 := strconv.Unquote(nn.Value)
+		if err1 == nil && err2 == nil {
+			return pv == nv
+		}
+		return pp.Value == nn.Value
+	}
+	return m.matchFields(reflect.ValueOf(p).Elem(), reflect.ValueOf(n).Elem(), b)
+}
+
+var (
+	posType     = reflect.TypeOf(token.NoPos)
+	objectType  = reflect.TypeOf((*ast.Object)(nil))
+	scopeType   = reflect.TypeOf((*ast.Scope)(nil))
+	commentType = reflect.TypeOf((*ast.CommentGroup)(nil))
+	nodeType    = reflect.TypeOf((*ast.Node)(nil)).Elem()
+)
+
+// matchFields compares two AST structs of the same type field by field
+func (m *PatternMatcher) matchFields(p, n reflect.Value, b map[string]string) bool {
+	for i := 0; i < p.NumField(); i++ {
+		pf, nf := p.Field(i), n.Field(i)
+		switch t := pf.Type(); {
+		case t == posType || t == objectType || t == scopeType || t == commentType:
+			continue
+		case t.Kind() == reflect.Slice && t.Elem().Implements(nodeType):
+			if !m.matchList(toNodes(pf), toNodes(nf), b) {
+				return false
+			}
+		case t.Implements(nodeType):
+			var pn, nn ast.Node
+			if !pf.IsNil() {
+				pn = pf.Interface().(ast.Node)
+			}
+			if !nf.IsNil() {
+				nn = nf.Interface().(ast.Node)
+			}
+			if !m.matchNode(pn, nn, b) {
+				return false
+			}
+		case t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface || t.Kind() == reflect.Slice || t.Kind() == reflect.Map:
+			continue
+		default:
+			if pf.Interface() != nf.Interface() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func toNodes(v reflect.Value) []ast.Node {
+	nodes := make([]ast.Node, v.Len())
+	for i := range nodes {
+		nodes[i] = v.Index(i).Interface().(ast.Node)
+	}
+	return nodes
+}
+
+// matchList matches a pattern list against a node list, letting `...` absorb any run of nodes
+func (m *PatternMatcher) matchList(ps, ns []ast.Node, b map[string]string) bool {
+	if len(ps) == 0 {
+		return len(ns) == 0
+	}
+	if isEllipsis(ps[0]) {
+		for k := 0; k <= len(ns); k++ {
+			try := copyBindings(b)
+			if m.matchList(ps[1:], ns[k:], try) {
+				commitBindings(b, try)
+				return true
+			}
+		}
+		return false
+	}
+	if len(ns) == 0 {
+		return false
+	}
+	try := copyBindings(b)
+	if m.matchNode(ps[0], ns[0], try) && m.matchList(ps[1:], ns[1:], try) {
+		commitBindings(b, try)
+		return true
+	}
+	return false
+}
+
+func copyBindings(b map[string]string) map[string]string {
+	c := make(map[string]string, len(b))
+	for k, v := range b {
+		c[k] = v
+	}
+	return c
+}
+
+func commitBindings(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
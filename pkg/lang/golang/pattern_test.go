@@ -0,0 +1,96 @@
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const patternSource = `package main
+
+import (
+	"crypto/tls"
+	"os/exec"
+)
+
+func run(cmd string) {
+	exec.Command("sh", "-c", cmd).Run()
+	exec.Command("ls", "-l").Run()
+	cfg := &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}
+	_ = cfg
+}
+
+func copyTwice(a, b []byte) {
+	copy(a, a)
+	copy(a, b)
+}
+
+func locked(mu interface{ Lock(); Unlock() }) {
+	mu.Lock()
+	println("critical")
+	mu.Unlock()
+}
+`
+
+func matcher(t *testing.T) *PatternMatcher {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte(patternSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewPatternMatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMatch(t *testing.T) {
+	m := matcher(t)
+	tests := []struct {
+		pattern string
+		lines   []int
+		binding string // "$X=text" bound by the first match, if any
+	}{
+		{`exec.Command("sh", "-c", ...)`, []int{9}, ""},
+		{`exec.Command(...)`, []int{9, 10}, ""},
+		{`exec.Command("sh", "-c", $CMD)`, []int{9}, "$CMD=cmd"},
+		{`&tls.Config{..., InsecureSkipVerify: true, ...}`, []int{11}, ""},
+		// A metavariable used twice matches the same text twice
+		{`copy($X, $X)`, []int{16}, "$X=a"},
+		{"mu.Lock()\n...\nmu.Unlock()", []int{21}, ""},
+		{`exec.Command("bash", ...)`, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matches, err := m.Match(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var lines []int
+			for _, match := range matches {
+				lines = append(lines, match.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("matched lines %v, want %v", lines, tt.lines)
+			}
+			if tt.binding != "" {
+				name, text, _ := strings.Cut(tt.binding, "=")
+				if got := matches[0].Bindings[name]; got != text {
+					t.Errorf("%s = %q, want %q", name, got, text)
+				}
+			}
+			if len(matches) > 0 && matches[0].Function == "" {
+				t.Errorf("no enclosing function for %+v", matches[0])
+			}
+		})
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	if _, err := matcher(t).Match(`exec.Command(`); err == nil {
+		t.Error("an unbalanced pattern was accepted")
+	}
+}
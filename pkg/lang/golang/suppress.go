@@ -0,0 +1,39 @@
+package golang
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"sast-demo/pkg/core"
+	"strings"
+)
+
+// CollectSuppressions returns the `// sast:ignore` comments of a Go file
+func CollectSuppressions(filePath string) ([]core.Suppression, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var sups []core.Suppression
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, "//") {
+				continue
+			}
+			pos := fset.Position(c.Pos())
+			// Code before the comment on the same line makes it a trailing comment
+			lineStart := pos.Offset - (pos.Column - 1)
+			trailing := strings.TrimSpace(string(src[lineStart:pos.Offset])) != ""
+			if s, ok := core.ParseSuppression(strings.TrimPrefix(c.Text, "//"), pos.Line, trailing); ok {
+				sups = append(sups, s)
+			}
+		}
+	}
+	return sups, nil
+}
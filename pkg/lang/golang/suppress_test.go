@@ -0,0 +1,44 @@
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+const suppressSource = `package main
+
+import "os/exec"
+
+func run(cmd string) {
+	exec.Command(cmd).Run() // sast:ignore rce reason: cmd is a fixed allow-list entry
+	// sast:ignore rce,sqli reason: checked by the caller
+	exec.Command(cmd).Run()
+	/* sast:ignore rce reason: block comments are not suppressions */
+	exec.Command(cmd).Run()
+	exec.Command("// sast:ignore rce reason: inside a string").Run()
+	exec.Command(cmd).Run() // sast:ignore *
+	exec.Command(cmd).Run() // sast:ignored rce reason: misspelled
+}
+`
+
+func TestCollectSuppressions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte(suppressSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sups, err := CollectSuppressions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []core.Suppression{
+		{Line: 6, CommentLine: 6, Rules: []string{"rce"}, Reason: "cmd is a fixed allow-list entry"},
+		{Line: 8, CommentLine: 7, Rules: []string{"rce", "sqli"}, Reason: "checked by the caller"},
+		{Line: 12, CommentLine: 12, Rules: []string{"*"}},
+	}
+	if !reflect.DeepEqual(sups, want) {
+		t.Errorf("suppressions\n%+v\nwant\n%+v", sups, want)
+	}
+}
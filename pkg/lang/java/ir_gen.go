@@ -15,14 +15,17 @@ type JavaIRGenerator struct {
 	currentFn  *core.FunctionIR
 	currBlock  *core.BasicBlock
 	blockCount int
+	instCount  int
 	// Stack for handling control flow: stores merge blocks or loop headers
 	ctrlStack []controlContext
 }
 
 type controlContext struct {
-	type_      string // "if", "loop"
+	type_      string // "if", "loop", or "block" for other braces (methods, try, switch...)
 	mergeBlock *core.BasicBlock
-	loopHeader *core.BasicBlock // for loops
+	loopHeader *core.BasicBlock  // for loops
+	branch     *core.Instruction // for ifs: the branch, retargeted when an else follows
+	condBlock  *core.BasicBlock  // block ending in branch
 }
 
 func NewJavaIRGenerator() *JavaIRGenerator {
@@ -47,14 +50,14 @@ func (g *JavaIRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 		Blocks: make(map[string]*core.BasicBlock),
 	}
 	g.program.Functions["main"] = g.currentFn
-	g.newBlock() // Entry block
+	g.currentFn.Entry = g.newBlock().ID
 
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
 
 	// Regex patterns
 	// if (condition) {
-	ifRegex := regexp.MustCompile(`^\s*if\s*\((.*)\)\s*\{?`)
+	ifRegex := regexp.MustCompile(`^\s*if\s*\(`)
 	// } else {
 	elseRegex := regexp.MustCompile(`^\s*\}\s*else\s*\{?`)
 	// while (condition) {
@@ -64,13 +67,6 @@ func (g *JavaIRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 	// }
 	closeRegex := regexp.MustCompile(`^\s*\}\s*$`)
 
-	// Assignments: type var = val; or var = val;
-	assignRegex := regexp.MustCompile(`^\s*(?:[a-zA-Z0-9_<>\[\]]+\s+)?([a-zA-Z0-9_]+)\s*=\s*(.+);`)
-	// Method calls: obj.method(args); or method(args);
-	callRegex := regexp.MustCompile(`^\s*([a-zA-Z0-9_.]+\s*\(.*\));`)
-	// Return
-	returnRegex := regexp.MustCompile(`^\s*return\s*(.*);`)
-
 	for scanner.Scan() {
 		lineNum++
 		line := strings.TrimSpace(scanner.Text())
@@ -79,18 +75,35 @@ func (g *JavaIRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 		}
 
 		// 1. Control Flow: IF
-		if matches := ifRegex.FindStringSubmatch(line); matches != nil {
-			cond := matches[1]
+		if ifRegex.MatchString(line) {
+			cond, body := splitCondition(line)
 
 			// Create blocks
 			thenBlock := g.createBlock()
 			mergeBlock := g.createBlock()
 
-			// Jump from current to then/merge
-			g.emit(core.OpBranch, cond, []string{thenBlock.ID, mergeBlock.ID}, lineNum)
+			// Branch from current to then/merge; an else block replaces merge later
+			condBlock := g.currBlock
+			br := g.branch(cond, thenBlock, mergeBlock, lineNum)
+
+			// if (c) stmt; has its whole body on this line and opens no context
+			if toks := tokenize(body, lineNum); len(toks) > 1 || (len(toks) == 1 && toks[0].text != "{") {
+				g.currBlock = thenBlock
+				if toks[0].text == "{" && toks[len(toks)-1].text == "}" {
+					toks = toks[1 : len(toks)-1]
+				}
+				for _, rs := range splitStatements(toks) {
+					g.lowerStatement(parseStatement(rs))
+				}
+				if !terminated(g.currBlock) {
+					g.emit(core.OpJump, "", []string{mergeBlock.ID}, lineNum)
+				}
+				g.currBlock = mergeBlock
+				continue
+			}
 
 			// Push context
-			g.pushCtrl(controlContext{type_: "if", mergeBlock: mergeBlock})
+			g.pushCtrl(controlContext{type_: "if", mergeBlock: mergeBlock, branch: br, condBlock: condBlock})
 
 			// Switch to then
 			g.currBlock = thenBlock
@@ -103,10 +116,13 @@ func (g *JavaIRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 				ctx := g.peekCtrl()
 				if ctx.type_ == "if" {
 					// End of THEN block, jump to MERGE
-					g.emit(core.OpJump, "", []string{ctx.mergeBlock.ID}, lineNum)
+					if !terminated(g.currBlock) {
+						g.emit(core.OpJump, "", []string{ctx.mergeBlock.ID}, lineNum)
+					}
 
-					// Create ELSE block
+					// Create ELSE block as the false target of the branch
 					elseBlock := g.createBlock()
+					g.retarget(ctx.condBlock, ctx.branch, ctx.mergeBlock, elseBlock)
 					g.currBlock = elseBlock
 					continue
 				}
@@ -116,11 +132,17 @@ func (g *JavaIRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 		// 3. Control Flow: WHILE/FOR (Loops)
 		if matches := whileRegex.FindStringSubmatch(line); matches != nil {
 			cond := matches[1]
-			g.handleLoop(cond, lineNum)
+			g.handleLoop(cond, lineNum, "")
 			continue
 		}
 		if matches := forRegex.FindStringSubmatch(line); matches != nil {
-			g.handleLoop("loop_cond", lineNum) // Simplified condition for for-loop
+			// for (init; cond; update); enhanced for loops have no condition
+			cond, update := "", ""
+			if parts := strings.Split(matches[1], ";"); len(parts) == 3 {
+				g.lowerClause(parts[0], lineNum)
+				cond, update = parts[1], parts[2]
+			}
+			g.handleLoop(cond, lineNum, update)
 			continue
 		}
 
@@ -128,38 +150,37 @@ func (g *JavaIRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 		if closeRegex.MatchString(line) {
 			if len(g.ctrlStack) > 0 {
 				ctx := g.popCtrl()
-				// Jump to merge block
-				g.emit(core.OpJump, "", []string{ctx.mergeBlock.ID}, lineNum)
+				if ctx.type_ == "block" {
+					continue
+				}
+				// Jump to merge block, unless the block already returned or threw
+				if !terminated(g.currBlock) {
+					g.emit(core.OpJump, "", []string{ctx.mergeBlock.ID}, lineNum)
+				}
 				g.currBlock = ctx.mergeBlock
 			}
 			continue
 		}
 
-		// 5. Instructions
-		if matches := assignRegex.FindStringSubmatch(line); matches != nil {
-			// var = val
-			lhs := matches[1]
-			rhs := matches[2]
-
-			// Check if RHS is a call
-			if strings.Contains(rhs, "(") && strings.Contains(rhs, ")") {
-				// Simplified call handling
-				g.emit(core.OpCall, rhs, nil, lineNum, lhs)
-			} else {
-				g.emit(core.OpStore, rhs, nil, lineNum, lhs)
+		// Other opening braces: methods, classes, try, switch... Their closing brace
+		// must not end an enclosing if or loop. `} catch (...) {` closes and opens one.
+		if toks := tokenize(line, lineNum); len(toks) > 0 && toks[len(toks)-1].text == "{" {
+			if toks[0].text == "}" {
+				continue
 			}
+			if rs := splitStatements(toks); len(rs) > 0 && parseStatement(rs[len(rs)-1]).Kind == StmtMethod {
+				g.startMethod(lineNum)
+			}
+			g.pushCtrl(controlContext{type_: "block"})
 			continue
 		}
 
-		if matches := callRegex.FindStringSubmatch(line); matches != nil {
-			call := matches[1]
-			g.emit(core.OpCall, call, nil, lineNum)
-			continue
-		}
-
-		if matches := returnRegex.FindStringSubmatch(line); matches != nil {
-			val := matches[1]
-			g.emit(core.OpRet, val, nil, lineNum)
+		// 5. Instructions: parse the statements on this line and lower their expressions
+		// Checking tokens rather than text lets trailing comments follow the `;`
+		if toks := tokenize(line, lineNum); len(toks) > 0 && toks[len(toks)-1].text == ";" {
+			for _, rs := range splitStatements(toks) {
+				g.lowerStatement(parseStatement(rs))
+			}
 			continue
 		}
 	}
@@ -167,7 +188,7 @@ func (g *JavaIRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
 	return g.program, nil
 }
 
-func (g *JavaIRGenerator) handleLoop(cond string, lineNum int) {
+func (g *JavaIRGenerator) handleLoop(cond string, lineNum int, update string) {
 	headerBlock := g.createBlock()
 	bodyBlock := g.createBlock()
 	exitBlock := g.createBlock()
@@ -175,9 +196,11 @@ func (g *JavaIRGenerator) handleLoop(cond string, lineNum int) {
 	// Current -> Header
 	g.emit(core.OpJump, "", []string{headerBlock.ID}, lineNum)
 
-	// Header -> Body or Exit
+	// Header -> Body or Exit. The for-update runs in the header so that the
+	// variables it changes are not mistaken for constants.
 	g.currBlock = headerBlock
-	g.emit(core.OpBranch, cond, []string{bodyBlock.ID, exitBlock.ID}, lineNum)
+	g.lowerClause(update, lineNum)
+	g.branch(cond, bodyBlock, exitBlock, lineNum)
 
 	// Stack: loop
 	g.pushCtrl(controlContext{type_: "loop", mergeBlock: exitBlock, loopHeader: headerBlock})
@@ -186,6 +209,106 @@ func (g *JavaIRGenerator) handleLoop(cond string, lineNum int) {
 	g.currBlock = bodyBlock
 }
 
+// splitCondition splits `if (cond) rest` at the parenthesis closing the condition,
+// skipping parentheses inside string and char literals
+func splitCondition(line string) (cond, rest string) {
+	start := strings.Index(line, "(")
+	if start < 0 {
+		return "", ""
+	}
+	depth := 0
+	var quote byte
+	for i := start; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return line[start+1 : i], strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return line[start+1:], ""
+}
+
+// lowerClause lowers the init or update clause of a for loop
+func (g *JavaIRGenerator) lowerClause(clause string, lineNum int) {
+	for _, rs := range splitStatements(tokenize(clause+";", lineNum)) {
+		g.lowerStatement(parseStatement(rs))
+	}
+}
+
+// startMethod begins a method body in a new block. All methods share one IR
+// function, so besides falling through from the previous method the body is also
+// entered from the function entry: code made dead in one method never hides the next.
+func (g *JavaIRGenerator) startMethod(lineNum int) {
+	start := g.createBlock()
+	if !terminated(g.currBlock) {
+		g.emit(core.OpJump, "", []string{start.ID}, lineNum)
+	}
+	if entry := g.currentFn.Blocks[g.currentFn.Entry]; entry != g.currBlock || terminated(g.currBlock) {
+		g.link(entry, start)
+	}
+	g.currBlock = start
+}
+
+// branch lowers a condition and ends the current block with a two-way branch.
+// Like the other frontends, Operands are the condition value and the true and false targets.
+func (g *JavaIRGenerator) branch(cond string, then, els *core.BasicBlock, lineNum int) *core.Instruction {
+	c := ""
+	if e := parseExpr(tokenize(cond, lineNum)); e != nil {
+		c = g.lowerExpr(e, lineNum)
+	}
+	code := fmt.Sprintf("if (%s) goto %s else %s", strings.TrimSpace(cond), then.ID, els.ID)
+	g.emitInst(core.OpBranch, "", []string{c, then.ID, els.ID}, lineNum, code)
+	g.link(g.currBlock, then)
+	g.link(g.currBlock, els)
+	return g.currBlock.Instructions[len(g.currBlock.Instructions)-1]
+}
+
+// retarget moves the false edge of br in from old to to
+func (g *JavaIRGenerator) retarget(from *core.BasicBlock, br *core.Instruction, old, to *core.BasicBlock) {
+	if from == nil || br == nil {
+		return
+	}
+	br.Operands[2] = to.ID
+	br.Code = strings.Replace(br.Code, "else "+old.ID, "else "+to.ID, 1)
+	for i, s := range from.Successors {
+		if s == old.ID {
+			from.Successors[i] = to.ID
+			break
+		}
+	}
+	for i, p := range old.Predecessors {
+		if p == from.ID {
+			old.Predecessors = append(old.Predecessors[:i], old.Predecessors[i+1:]...)
+			break
+		}
+	}
+	to.Predecessors = append(to.Predecessors, from.ID)
+}
+
+func (g *JavaIRGenerator) link(from, to *core.BasicBlock) {
+	from.Successors = append(from.Successors, to.ID)
+	to.Predecessors = append(to.Predecessors, from.ID)
+}
+
+// terminated reports whether bb ends in a return or throw, so control never falls through it
+func terminated(bb *core.BasicBlock) bool {
+	n := len(bb.Instructions)
+	return n > 0 && bb.Instructions[n-1].Op == core.OpRet
+}
+
 func (g *JavaIRGenerator) newBlock() *core.BasicBlock {
 	bb := g.createBlock()
 	g.currBlock = bb
@@ -239,13 +362,14 @@ func (g *JavaIRGenerator) emit(op core.OpCode, code string, successors []string,
 	}
 
 	inst := &core.Instruction{
-		ID:       fmt.Sprintf("i%d", len(g.currBlock.Instructions)),
+		ID:       fmt.Sprintf("i%d", g.instCount),
 		Op:       op,
 		Code:     code,
 		Operands: operands,
 		Line:     line,
 		Result:   res,
 	}
+	g.instCount++
 	g.currBlock.Instructions = append(g.currBlock.Instructions, inst)
 
 	// Link blocks
@@ -0,0 +1,272 @@
+package java
+
+import (
+	"fmt"
+	"sast-demo/pkg/core"
+	"strconv"
+	"strings"
+)
+
+// Lowering of parsed Java statements into three-address IR.
+// Calls follow the IR convention: Operands[0] is the callee and Operands[1:]
+// are the argument values in source order, with the receiver kept separately.
+
+func (g *JavaIRGenerator) lowerStatement(st *Stmt) {
+	switch st.Kind {
+	case StmtDecl:
+		if st.Expr != nil {
+			v := g.lowerExpr(st.Expr, st.Line)
+			g.emitInst(core.OpStore, st.Name, []string{v}, st.Line, "")
+		}
+	case StmtExpr:
+		g.lowerExpr(st.Expr, st.Line)
+	case StmtThrow:
+		// A throw leaves the method like a return as far as the CFG is concerned
+		v := g.lowerExpr(st.Expr, st.Line)
+		g.emitInst(core.OpRet, "", []string{v}, st.Line, "throw "+v)
+	case StmtReturn:
+		var ops []string
+		if st.Expr != nil {
+			ops = []string{g.lowerExpr(st.Expr, st.Line)}
+		}
+		g.emitInst(core.OpRet, "", ops, st.Line, "")
+	}
+}
+
+// lowerExpr emits the instructions computing e and returns the value holding its result
+func (g *JavaIRGenerator) lowerExpr(e *Expr, line int) string {
+	if e == nil {
+		return ""
+	}
+	if e.Line > 0 {
+		line = e.Line
+	}
+
+	switch e.Kind {
+	case ExprLiteral, ExprLambda:
+		res := g.tempVar()
+		g.emitInst(core.OpConst, res, []string{e.String()}, line, "")
+		return res
+
+	case ExprName:
+		res := g.tempVar()
+		g.emitInst(core.OpLoad, res, []string{e.Value}, line, "")
+		return res
+
+	case ExprField:
+		// Qualified names (a.b.c) load as one variable, like the Go frontend's selectors
+		if name := flatName(e); name != "" {
+			res := g.tempVar()
+			g.emitInst(core.OpLoad, res, []string{name}, line, "")
+			return res
+		}
+		// Fields of computed values load the access path below the value's temporary
+		obj := g.lowerExpr(e.X, line)
+		res := g.tempVar()
+		g.emitInst(core.OpLoad, res, []string{obj + "." + e.Value}, line, "")
+		return res
+
+	case ExprCall, ExprNew:
+		callee := e.Value
+		receiver := ""
+		switch {
+		case e.Kind == ExprNew:
+			callee = "new " + e.Value
+		case e.X != nil:
+			callee = e.X.String() + "." + e.Value
+			if !isTypeName(e.X) {
+				receiver = g.lowerExpr(e.X, line)
+			}
+		}
+		if e.Kind == ExprNew && strings.HasSuffix(e.Value, "]") {
+			return g.lowerArray(callee, e.Args, line)
+		}
+		ops := []string{callee}
+		for _, a := range e.Args {
+			ops = append(ops, g.lowerExpr(a, line))
+		}
+		res := g.tempVar()
+		g.emitInst(core.OpCall, res, ops, line, "")
+		g.currBlock.Instructions[len(g.currBlock.Instructions)-1].Receiver = receiver
+		return res
+
+	case ExprArray:
+		return g.lowerArray("new[]", e.Args, line)
+
+	case ExprBinary:
+		left := g.lowerExpr(e.X, line)
+		right := g.lowerExpr(e.Y, line)
+		res := g.tempVar()
+		g.emitInst(core.OpBinOp, res, []string{left, e.Value, right}, line, "")
+		return res
+
+	case ExprUnary:
+		x := g.lowerExpr(e.X, line)
+		if target := assignTarget(e.X); target != "" && (e.Value == "++" || e.Value == "--") {
+			// i++ is i = i + 1; the postfix form (Y set) evaluates to the old value
+			one := g.tempVar()
+			g.emitInst(core.OpConst, one, []string{"1"}, line, "")
+			sum := g.tempVar()
+			g.emitInst(core.OpBinOp, sum, []string{x, e.Value[:1], one}, line, "")
+			g.emitInst(core.OpStore, target, []string{sum}, line, "")
+			if e.Y != nil {
+				return x
+			}
+			return sum
+		}
+		res := g.tempVar()
+		g.emitInst(core.OpBinOp, res, []string{e.Value, x}, line, fmt.Sprintf("%s = %s %s", res, e.Value, x))
+		return res
+
+	case ExprAssign:
+		v := g.lowerExpr(e.Y, line)
+		if e.Value != "=" {
+			cur := g.lowerExpr(e.X, line)
+			res := g.tempVar()
+			op := strings.TrimSuffix(e.Value, "=")
+			g.emitInst(core.OpBinOp, res, []string{cur, op, v}, line, "")
+			v = res
+		}
+		if target := assignTarget(e.X); target != "" {
+			g.emitInst(core.OpStore, target, []string{v}, line, "")
+		}
+		return v
+
+	case ExprIndex:
+		// arr[0] loads the element path arr.[].[0], like the Go frontend's index expressions
+		if elemKey(e.Y) == "" {
+			g.lowerExpr(e.Y, line)
+		}
+		name := assignTarget(e)
+		if name == "" {
+			name = g.lowerExpr(e.X, line) + core.ElemPath(elemKey(e.Y))
+		}
+		res := g.tempVar()
+		g.emitInst(core.OpLoad, res, []string{name}, line, "")
+		return res
+
+	case ExprCond:
+		g.lowerExpr(e.Args[0], line)
+		a := g.lowerExpr(e.X, line)
+		b := g.lowerExpr(e.Y, line)
+		res := g.tempVar()
+		g.emitInst(core.OpPhi, res, []string{a, b}, line, "")
+		return res
+
+	case ExprCast:
+		x := g.lowerExpr(e.X, line)
+		res := g.tempVar()
+		g.emitInst(core.OpLoad, res, []string{x}, line, fmt.Sprintf("%s = (%s) %s", res, e.Value, x))
+		return res
+	}
+	return ""
+}
+
+// lowerArray allocates an array and stores the initializer values into its
+// elements, so that {"ls", dir} taints only element 1
+func (g *JavaIRGenerator) lowerArray(callee string, elems []*Expr, line int) string {
+	var vals []string
+	for _, a := range elems {
+		vals = append(vals, g.lowerExpr(a, line))
+	}
+	res := g.tempVar()
+	g.emitInst(core.OpCall, res, []string{callee}, line, "")
+	for i, v := range vals {
+		g.emitInst(core.OpStore, res+core.ElemPath(strconv.Itoa(i)), []string{v}, line, "")
+	}
+	return res
+}
+
+// flatName renders a chain of names and field accesses (a.b.c), or "" for anything else
+func flatName(e *Expr) string {
+	switch e.Kind {
+	case ExprName:
+		return e.Value
+	case ExprField:
+		if base := flatName(e.X); base != "" {
+			return base + "." + e.Value
+		}
+	}
+	return ""
+}
+
+// isTypeName guesses whether a call qualifier is a class (Paths.get) rather than an object,
+// following the Java naming convention that types are capitalized
+func isTypeName(e *Expr) bool {
+	name := flatName(e)
+	if name == "" {
+		return false
+	}
+	last := name[strings.LastIndex(name, ".")+1:]
+	return last != "" && last[0] >= 'A' && last[0] <= 'Z' && strings.ToUpper(last) != last
+}
+
+// assignTarget is the access path updated by an assignment: a variable, a field
+// or an element (arr.[].[0])
+func assignTarget(e *Expr) string {
+	if e != nil && e.Kind == ExprIndex {
+		if base := assignTarget(e.X); base != "" {
+			return base + core.ElemPath(elemKey(e.Y))
+		}
+		return ""
+	}
+	if e == nil {
+		return ""
+	}
+	return flatName(e)
+}
+
+// elemKey is the constant index or key of an element access, "" if it is not one
+func elemKey(e *Expr) string {
+	if e == nil || e.Kind != ExprLiteral {
+		return ""
+	}
+	if s, err := strconv.Unquote(e.Value); err == nil {
+		return s
+	}
+	if n, err := strconv.ParseInt(e.Value, 0, 64); err == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	return ""
+}
+
+func (g *JavaIRGenerator) tempVar() string {
+	return fmt.Sprintf("t%d", g.instCount)
+}
+
+// emitInst appends an instruction with explicit operands; code defaults to the standard rendering
+func (g *JavaIRGenerator) emitInst(op core.OpCode, result string, operands []string, line int, code string) {
+	if code == "" {
+		code = formatCode(op, result, operands)
+	}
+	g.currBlock.Instructions = append(g.currBlock.Instructions, &core.Instruction{
+		ID:       fmt.Sprintf("i%d", g.instCount),
+		Op:       op,
+		Result:   result,
+		Operands: operands,
+		Line:     line,
+		Code:     code,
+	})
+	g.instCount++
+}
+
+func formatCode(op core.OpCode, res string, ops []string) string {
+	switch op {
+	case core.OpStore:
+		return fmt.Sprintf("%s = %s", res, ops[0])
+	case core.OpLoad:
+		return fmt.Sprintf("%s = load %s", res, ops[0])
+	case core.OpCall:
+		return fmt.Sprintf("%s = call %s(%v)", res, ops[0], ops[1:])
+	case core.OpConst:
+		return fmt.Sprintf("%s = const %s", res, ops[0])
+	case core.OpBinOp:
+		return fmt.Sprintf("%s = %s %s %s", res, ops[0], ops[1], ops[2])
+	case core.OpPhi:
+		return fmt.Sprintf("%s = phi(%s)", res, strings.Join(ops, ", "))
+	case core.OpRet:
+		return fmt.Sprintf("return %s", strings.Join(ops, ""))
+	default:
+		return fmt.Sprintf("%s = %s %v", res, op, ops)
+	}
+}
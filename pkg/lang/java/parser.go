@@ -0,0 +1,883 @@
+package java
+
+import (
+	"strings"
+)
+
+// --- Tokens ---
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokNumber
+	tokString // "..." (and text blocks)
+	tokChar   // '.'
+	tokOp     // Operators and punctuation
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// Multi-character operators, longest first
+var javaOperators = []string{
+	">>>=", "<<=", ">>=", ">>>", "...", "::", "->", "++", "--", "&&", "||",
+	"==", "!=", "<=", ">=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<",
+}
+
+// tokenize splits Java source into tokens, dropping comments and whitespace.
+// `>>` is emitted as two `>` tokens so that nested generics close correctly.
+func tokenize(src string, line int) []token {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				end = len(src) - i - 3
+			}
+			text := src[i:min(i+end+6, len(src))]
+			toks = append(toks, token{tokString, text, line})
+			line += strings.Count(text, "\n")
+			i += len(text)
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(src))
+			kind := tokString
+			if c == '\'' {
+				kind = tokChar
+			}
+			toks = append(toks, token{kind, src[i:j], line})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && isIdentPart(src[j]) {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], line})
+			i = j
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9'):
+			j := i
+			for j < len(src) && (isIdentPart(src[j]) || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokNumber, src[i:j], line})
+			i = j
+		default:
+			op := string(c)
+			for _, o := range javaOperators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			toks = append(toks, token{tokOp, op, line})
+			i += len(op)
+		}
+	}
+	return toks
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// --- Statements ---
+
+// rawStatement is a run of tokens terminated by ';', '{' or '}' outside of parentheses
+type rawStatement struct {
+	tokens []token
+	term   string
+	line   int
+}
+
+// splitStatements breaks a token stream into statements and block delimiters.
+// Braces inside parentheses (lambda bodies, anonymous classes in arguments) and
+// array initializers stay part of the enclosing statement.
+func splitStatements(toks []token) []rawStatement {
+	var stmts []rawStatement
+	var cur []token
+	depth := 0 // () and []
+	exprBraces := 0
+
+	flush := func(term string, line int) {
+		if len(cur) > 0 {
+			line = cur[0].line
+		}
+		stmts = append(stmts, rawStatement{tokens: cur, term: term, line: line})
+		cur = nil
+	}
+
+	for i, t := range toks {
+		if t.kind == tokOp {
+			switch t.text {
+			case "(", "[":
+				depth++
+			case ")", "]":
+				depth--
+			case "{":
+				prev := ""
+				if i > 0 {
+					prev = toks[i-1].text
+				}
+				if depth > 0 || exprBraces > 0 || prev == "=" || prev == "]" || prev == "," || prev == "->" {
+					exprBraces++
+					cur = append(cur, t)
+					continue
+				}
+				flush("{", t.line)
+				continue
+			case "}":
+				if exprBraces > 0 {
+					exprBraces--
+					cur = append(cur, t)
+					continue
+				}
+				flush("", t.line)
+				stmts = append(stmts, rawStatement{term: "}", line: t.line})
+				continue
+			case ";":
+				if depth == 0 && exprBraces == 0 {
+					flush(";", t.line)
+					continue
+				}
+			}
+		}
+		cur = append(cur, t)
+	}
+	if len(cur) > 0 {
+		flush("", cur[len(cur)-1].line)
+	}
+
+	// Drop the empty pieces created by back-to-back delimiters
+	var out []rawStatement
+	for _, s := range stmts {
+		if len(s.tokens) > 0 || s.term == "}" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ParseSource splits Java source into statements and parses each of them.
+// Block structure is kept as StmtClose entries matching the statements that open a block.
+func ParseSource(src string) []*Stmt {
+	var stmts []*Stmt
+	for _, rs := range splitStatements(tokenize(src, 1)) {
+		stmts = append(stmts, parseStatement(rs))
+	}
+	return stmts
+}
+
+// StmtKind classifies a parsed statement
+type StmtKind string
+
+const (
+	StmtExpr   StmtKind = "expr"   // foo(x);
+	StmtDecl   StmtKind = "decl"   // String x = foo();
+	StmtReturn StmtKind = "return" // return x;
+	StmtThrow  StmtKind = "throw"  // throw e;
+	StmtIf     StmtKind = "if"     // if (cond) {
+	StmtLoop   StmtKind = "loop"   // while (cond) { / for (...) {
+	StmtSwitch StmtKind = "switch" // switch (x) {
+	StmtElse   StmtKind = "else"   // else {
+	StmtClass  StmtKind = "class"  // class Foo {
+	StmtMethod StmtKind = "method" // public void foo(String a) {
+	StmtClose  StmtKind = "close"  // }
+	StmtOther  StmtKind = "other"  // try {, package, import, ...
+)
+
+// Stmt is one Java statement with its expressions parsed
+type Stmt struct {
+	Kind   StmtKind
+	Line   int
+	Type   string   // Declared type (decl), class name (class)
+	Name   string   // Declared variable or method name
+	Params []string // Method parameter names
+	Expr   *Expr    // Value, condition, or expression
+	Opens  bool     // Statement is followed by '{'
+}
+
+var modifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "static": true, "final": true,
+	"abstract": true, "synchronized": true, "native": true, "transient": true,
+	"volatile": true, "strictfp": true, "default": true,
+}
+
+// parseStatement interprets the tokens of one raw statement
+func parseStatement(rs rawStatement) *Stmt {
+	toks := rs.tokens
+	st := &Stmt{Kind: StmtOther, Line: rs.line, Opens: rs.term == "{"}
+	if rs.term == "}" {
+		st.Kind = StmtClose
+		return st
+	}
+
+	// Labels, annotations and modifiers carry no data flow
+	for len(toks) > 0 {
+		switch {
+		case toks[0].text == "case" || (toks[0].text == "default" && len(toks) > 1 && toks[1].text == ":"):
+			j := 0
+			for j < len(toks) && toks[j].text != ":" && toks[j].text != "->" {
+				j++
+			}
+			toks = toks[min(j+1, len(toks)):]
+			continue
+		case toks[0].text == "@" && len(toks) > 1 && toks[1].text != "interface":
+			j := 2
+			for j < len(toks) && toks[j].text == "." && j+1 < len(toks) {
+				j += 2
+			}
+			if j < len(toks) && toks[j].text == "(" {
+				j = skipGroup(toks, j)
+			}
+			toks = toks[j:]
+			continue
+		case modifiers[toks[0].text] && toks[0].kind == tokIdent:
+			toks = toks[1:]
+			continue
+		}
+		break
+	}
+	if len(toks) == 0 {
+		return st
+	}
+
+	head := toks[0].text
+	switch head {
+	case "return", "throw":
+		st.Kind = StmtReturn
+		if head == "throw" {
+			st.Kind = StmtThrow
+		}
+		if len(toks) > 1 {
+			st.Expr = parseExpr(toks[1:])
+		}
+		return st
+	case "if", "while", "for", "switch", "synchronized":
+		st.Kind = map[string]StmtKind{"if": StmtIf, "while": StmtLoop, "for": StmtLoop, "switch": StmtSwitch, "synchronized": StmtOther}[head]
+		if len(toks) > 1 && toks[1].text == "(" {
+			end := skipGroup(toks, 1)
+			inner := toks[2 : end-1]
+			if head == "for" {
+				inner = forCondition(inner)
+			}
+			st.Expr = parseExpr(inner)
+		}
+		return st
+	case "else":
+		st.Kind = StmtElse
+		if len(toks) > 1 && toks[1].text == "if" {
+			rest := parseStatement(rawStatement{tokens: toks[1:], term: rs.term, line: rs.line})
+			rest.Kind = StmtIf
+			return rest
+		}
+		return st
+	case "class", "interface", "enum", "record":
+		st.Kind = StmtClass
+		if len(toks) > 1 {
+			st.Type = toks[1].text
+		}
+		return st
+	case "try", "finally", "do", "package", "import", "catch":
+		return st
+	}
+
+	// Declaration: Type name [= value] / method header: Type name ( params ) [throws ...]
+	if end, ok := scanType(toks, 0); ok && end < len(toks) && toks[end].kind == tokIdent {
+		name := toks[end]
+		rest := toks[end+1:]
+		switch {
+		case len(rest) == 0:
+			st.Kind, st.Type, st.Name = StmtDecl, typeText(toks[:end]), name.text
+			return st
+		case rest[0].text == "=":
+			st.Kind, st.Type, st.Name = StmtDecl, typeText(toks[:end]), name.text
+			st.Expr = parseExpr(rest[1:])
+			return st
+		case rest[0].text == "(" && rs.term == "{":
+			st.Kind, st.Type, st.Name = StmtMethod, typeText(toks[:end]), name.text
+			st.Params = paramNames(rest[1 : skipGroup(rest, 0)-1])
+			return st
+		}
+	}
+	// Constructor header: Name ( params ) {
+	if rs.term == "{" && len(toks) > 1 && toks[0].kind == tokIdent && toks[1].text == "(" {
+		st.Kind, st.Name = StmtMethod, toks[0].text
+		st.Params = paramNames(toks[2 : skipGroup(toks, 1)-1])
+		return st
+	}
+
+	st.Kind = StmtExpr
+	st.Expr = parseExpr(toks)
+	return st
+}
+
+// forCondition extracts the loop condition of a classic for, or the iterable of an enhanced for
+func forCondition(inner []token) []token {
+	depth := 0
+	var parts [][]token
+	start := 0
+	for i, t := range inner {
+		switch t.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case ";":
+			if depth == 0 {
+				parts = append(parts, inner[start:i])
+				start = i + 1
+			}
+		case ":":
+			if depth == 0 && len(parts) == 0 {
+				return inner[i+1:]
+			}
+		}
+	}
+	if len(parts) >= 1 && start <= len(inner) {
+		if len(parts) >= 2 {
+			return parts[1]
+		}
+		return inner[start:]
+	}
+	return inner
+}
+
+func paramNames(toks []token) []string {
+	var names []string
+	depth := 0
+	var last string
+	for _, t := range toks {
+		switch t.text {
+		case "<", "(":
+			depth++
+		case ">", ")":
+			depth--
+		case ",":
+			if depth == 0 && last != "" {
+				names = append(names, last)
+				last = ""
+			}
+			continue
+		}
+		if t.kind == tokIdent && depth == 0 {
+			last = t.text
+		}
+	}
+	if last != "" {
+		names = append(names, last)
+	}
+	return names
+}
+
+// scanType recognizes a type starting at i (qualified name, generics, array dims, varargs)
+func scanType(toks []token, i int) (int, bool) {
+	if i >= len(toks) || toks[i].kind != tokIdent || javaKeywords[toks[i].text] && !primitiveTypes[toks[i].text] {
+		return i, false
+	}
+	i++
+	for i+1 < len(toks) && toks[i].text == "." && toks[i+1].kind == tokIdent {
+		i += 2
+	}
+	if i < len(toks) && toks[i].text == "<" {
+		depth := 0
+		for ; i < len(toks); i++ {
+			if toks[i].text == "<" {
+				depth++
+			} else if toks[i].text == ">" {
+				depth--
+				if depth == 0 {
+					i++
+					break
+				}
+			} else if toks[i].kind == tokOp && !strings.Contains(",.?&[]", toks[i].text) {
+				return i, false
+			}
+		}
+	}
+	for i+1 < len(toks) && toks[i].text == "[" && toks[i+1].text == "]" {
+		i += 2
+	}
+	if i < len(toks) && toks[i].text == "..." {
+		i++
+	}
+	return i, true
+}
+
+func typeText(toks []token) string {
+	var sb strings.Builder
+	for _, t := range toks {
+		sb.WriteString(t.text)
+	}
+	return sb.String()
+}
+
+// skipGroup returns the index just past the bracket group opening at i
+func skipGroup(toks []token, i int) int {
+	open := toks[i].text
+	close := map[string]string{"(": ")", "[": "]", "{": "}", "<": ">"}[open]
+	depth := 0
+	for ; i < len(toks); i++ {
+		switch toks[i].text {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(toks)
+}
+
+var javaKeywords = map[string]bool{
+	"new": true, "null": true, "true": true, "false": true, "this": true, "super": true,
+	"if": true, "else": true, "return": true, "while": true, "for": true, "do": true,
+	"switch": true, "case": true, "default": true, "break": true, "continue": true,
+	"try": true, "catch": true, "finally": true, "throw": true, "throws": true,
+	"class": true, "interface": true, "enum": true, "extends": true, "implements": true,
+	"import": true, "package": true, "instanceof": true, "void": true, "var": true,
+	"int": true, "long": true, "short": true, "byte": true, "char": true,
+	"boolean": true, "float": true, "double": true,
+}
+
+var primitiveTypes = map[string]bool{
+	"int": true, "long": true, "short": true, "byte": true, "char": true,
+	"boolean": true, "float": true, "double": true, "void": true, "var": true,
+}
+
+// --- Expressions ---
+
+// ExprKind classifies an expression node
+type ExprKind string
+
+const (
+	ExprName     ExprKind = "Name"     // x, this
+	ExprLiteral  ExprKind = "Literal"  // "s", 1, true, null
+	ExprField    ExprKind = "Field"    // X.Value
+	ExprCall     ExprKind = "Call"     // X.Value(Args) or Value(Args)
+	ExprNew      ExprKind = "New"      // new Value(Args), new Value[]{Args}
+	ExprBinary   ExprKind = "Binary"   // X Value Y
+	ExprUnary    ExprKind = "Unary"    // Value X (prefix) / X Value (postfix)
+	ExprAssign   ExprKind = "Assign"   // X Value Y, Value is = or a compound operator
+	ExprIndex    ExprKind = "Index"    // X[Y]
+	ExprCond     ExprKind = "Cond"     // Args[0] ? X : Y
+	ExprCast     ExprKind = "Cast"     // (Value) X
+	ExprLambda   ExprKind = "Lambda"   // params -> body
+	ExprArray    ExprKind = "Array"    // {Args}
+	ExprEllipsis ExprKind = "Ellipsis" // ... (patterns only)
+)
+
+// Expr is a node of the Java expression tree
+type Expr struct {
+	Kind  ExprKind
+	Value string // Name, literal text, member name, operator, or type
+	X     *Expr
+	Y     *Expr
+	Args  []*Expr
+	Line  int
+}
+
+// String renders the expression back to compact Java source
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	args := func() string {
+		parts := make([]string, len(e.Args))
+		for i, a := range e.Args {
+			parts[i] = a.String()
+		}
+		return strings.Join(parts, ", ")
+	}
+	switch e.Kind {
+	case ExprName, ExprLiteral:
+		return e.Value
+	case ExprField:
+		return e.X.String() + "." + e.Value
+	case ExprCall:
+		if e.X == nil {
+			return e.Value + "(" + args() + ")"
+		}
+		return e.X.String() + "." + e.Value + "(" + args() + ")"
+	case ExprNew:
+		if strings.HasSuffix(e.Value, "]") {
+			return "new " + e.Value + "{" + args() + "}"
+		}
+		return "new " + e.Value + "(" + args() + ")"
+	case ExprBinary, ExprAssign:
+		return e.X.String() + " " + e.Value + " " + e.Y.String()
+	case ExprUnary:
+		if e.Y != nil { // postfix marker
+			return e.X.String() + e.Value
+		}
+		return e.Value + e.X.String()
+	case ExprIndex:
+		return e.X.String() + "[" + e.Y.String() + "]"
+	case ExprCond:
+		return e.Args[0].String() + " ? " + e.X.String() + " : " + e.Y.String()
+	case ExprCast:
+		return "(" + e.Value + ") " + e.X.String()
+	case ExprLambda:
+		return e.Value + " -> " + e.X.String()
+	case ExprArray:
+		return "{" + args() + "}"
+	case ExprEllipsis:
+		return "..."
+	}
+	return e.Value
+}
+
+// Walk calls fn for e and every sub-expression, parents first
+func (e *Expr) Walk(fn func(*Expr)) {
+	if e == nil {
+		return
+	}
+	fn(e)
+	e.X.Walk(fn)
+	e.Y.Walk(fn)
+	for _, a := range e.Args {
+		a.Walk(fn)
+	}
+}
+
+// ParseExpr parses a single Java expression from source text
+func ParseExpr(src string) *Expr {
+	return parseExpr(tokenize(src, 1))
+}
+
+func parseExpr(toks []token) *Expr {
+	if len(toks) == 0 {
+		return nil
+	}
+	p := &exprParser{toks: toks}
+	e := p.expr(0)
+	if e == nil {
+		return &Expr{Kind: ExprName, Value: typeText(toks), Line: toks[0].line}
+	}
+	return e
+}
+
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *exprParser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{kind: tokOp, text: ""}
+}
+
+func (p *exprParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *exprParser) accept(text string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].text == text && p.toks[p.pos].kind != tokString {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// Binary operator precedence; assignment and ternary are handled separately
+var binaryPrec = map[string]int{
+	"||": 3, "&&": 4, "|": 5, "^": 6, "&": 7,
+	"==": 8, "!=": 8,
+	"<": 9, ">": 9, "<=": 9, ">=": 9, "instanceof": 9,
+	"<<": 10, ">>": 10, ">>>": 10,
+	"+": 11, "-": 11,
+	"*": 12, "/": 12, "%": 12,
+}
+
+var assignOps = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true,
+	"&=": true, "|=": true, "^=": true, "<<=": true, ">>=": true, ">>>=": true,
+}
+
+func (p *exprParser) expr(minPrec int) *Expr {
+	left := p.unary()
+	if left == nil {
+		return nil
+	}
+	for {
+		t := p.peek()
+		if t.kind == tokString || t.kind == tokChar || t.kind == tokNumber {
+			return left
+		}
+		op := t.text
+		// `>>` and `>>>` arrive as separate `>` tokens (see tokenize)
+		if op == ">" && p.pos+1 < len(p.toks) && p.toks[p.pos+1].text == ">" {
+			op = ">>"
+			if p.pos+2 < len(p.toks) && p.toks[p.pos+2].text == ">" {
+				op = ">>>"
+			}
+		}
+		switch {
+		case assignOps[op] && minPrec <= 1:
+			p.pos++
+			right := p.expr(1)
+			left = &Expr{Kind: ExprAssign, Value: op, X: left, Y: right, Line: t.line}
+		case op == "?" && minPrec <= 2:
+			p.pos++
+			then := p.expr(2)
+			p.accept(":")
+			els := p.expr(2)
+			left = &Expr{Kind: ExprCond, X: then, Y: els, Args: []*Expr{left}, Line: t.line}
+		case binaryPrec[op] > 0 && binaryPrec[op] >= minPrec && binaryPrec[op] > 2:
+			if op == ">>" || op == ">>>" {
+				p.pos += len(op) // One `>` token per character
+			} else {
+				p.pos++
+			}
+			if op == "instanceof" {
+				end, _ := scanType(p.toks, p.pos)
+				typ := typeText(p.toks[p.pos:end])
+				p.pos = end
+				if p.peek().kind == tokIdent { // pattern matching: x instanceof Foo f
+					p.pos++
+				}
+				left = &Expr{Kind: ExprBinary, Value: op, X: left, Y: &Expr{Kind: ExprName, Value: typ, Line: t.line}, Line: t.line}
+				continue
+			}
+			right := p.expr(binaryPrec[op] + 1)
+			if right == nil {
+				return left
+			}
+			left = &Expr{Kind: ExprBinary, Value: op, X: left, Y: right, Line: t.line}
+		default:
+			return left
+		}
+	}
+}
+
+func (p *exprParser) unary() *Expr {
+	t := p.peek()
+	if t.kind == tokOp {
+		switch t.text {
+		case "-", "+", "!", "~", "++", "--":
+			p.pos++
+			x := p.unary()
+			if x == nil {
+				return nil
+			}
+			return &Expr{Kind: ExprUnary, Value: t.text, X: x, Line: t.line}
+		case "(":
+			if cast := p.tryCast(); cast != nil {
+				return cast
+			}
+		}
+	}
+	return p.postfix(p.primary())
+}
+
+// tryCast parses `(Type) operand`, restoring the position if the parenthesis is not a cast
+func (p *exprParser) tryCast() *Expr {
+	start := p.pos
+	end, ok := scanType(p.toks, p.pos+1)
+	if ok && end < len(p.toks) && p.toks[end].text == ")" && end+1 < len(p.toks) {
+		nt := p.toks[end+1]
+		castable := nt.kind != tokOp || nt.text == "(" || nt.text == "!" || nt.text == "~"
+		typ := typeText(p.toks[p.pos+1 : end])
+		// (a) + b is an addition, not a cast, unless the type is primitive or capitalized
+		if castable && (primitiveTypes[typ] || typ[0] >= 'A' && typ[0] <= 'Z') {
+			line := p.peek().line
+			p.pos = end + 1
+			if x := p.unary(); x != nil {
+				return &Expr{Kind: ExprCast, Value: typ, X: x, Line: line}
+			}
+		}
+	}
+	p.pos = start
+	return nil
+}
+
+func (p *exprParser) primary() *Expr {
+	t := p.peek()
+	switch t.kind {
+	case tokString, tokChar, tokNumber:
+		p.pos++
+		return &Expr{Kind: ExprLiteral, Value: t.text, Line: t.line}
+	case tokIdent:
+		switch t.text {
+		case "true", "false", "null":
+			p.pos++
+			return &Expr{Kind: ExprLiteral, Value: t.text, Line: t.line}
+		case "new":
+			return p.newExpr()
+		}
+		p.pos++
+		if p.peek().text == "->" { // x -> body
+			p.pos++
+			return &Expr{Kind: ExprLambda, Value: t.text, X: p.lambdaBody(), Line: t.line}
+		}
+		if p.peek().text == "(" {
+			return &Expr{Kind: ExprCall, Value: t.text, Args: p.args("(", ")"), Line: t.line}
+		}
+		return &Expr{Kind: ExprName, Value: t.text, Line: t.line}
+	}
+
+	switch t.text {
+	case "...":
+		p.pos++
+		return &Expr{Kind: ExprEllipsis, Line: t.line}
+	case "(":
+		// (a, b) -> body
+		end := skipGroup(p.toks, p.pos)
+		if end < len(p.toks) && p.toks[end].text == "->" {
+			params := typeText(p.toks[p.pos:end])
+			p.pos = end + 1
+			return &Expr{Kind: ExprLambda, Value: params, X: p.lambdaBody(), Line: t.line}
+		}
+		p.pos++
+		e := p.expr(0)
+		p.accept(")")
+		return e
+	case "{":
+		return &Expr{Kind: ExprArray, Args: p.args("{", "}"), Line: t.line}
+	}
+	return nil
+}
+
+func (p *exprParser) lambdaBody() *Expr {
+	if p.peek().text == "{" {
+		// Block bodies are not analyzed; keep their text for display
+		end := skipGroup(p.toks, p.pos)
+		body := &Expr{Kind: ExprName, Value: typeText(p.toks[p.pos:end]), Line: p.peek().line}
+		p.pos = end
+		return body
+	}
+	return p.expr(1)
+}
+
+func (p *exprParser) newExpr() *Expr {
+	line := p.next().line
+	end, _ := scanType(p.toks, p.pos)
+	typ := typeText(p.toks[p.pos:end])
+	p.pos = end
+	e := &Expr{Kind: ExprNew, Value: typ, Line: line}
+
+	switch p.peek().text {
+	case "(":
+		e.Args = p.args("(", ")")
+		if p.peek().text == "{" { // Anonymous class body
+			p.pos = skipGroup(p.toks, p.pos)
+		}
+	case "[":
+		// new T[n][m] or new T[]{...}
+		for p.peek().text == "[" {
+			p.pos++
+			if p.peek().text != "]" {
+				if dim := p.expr(0); dim != nil {
+					e.Args = append(e.Args, dim)
+				}
+			}
+			p.accept("]")
+			e.Value += "[]"
+		}
+		if p.peek().text == "{" {
+			e.Args = append(e.Args, p.args("{", "}")...)
+		}
+	}
+	return e
+}
+
+// args parses a comma separated list between open and close
+func (p *exprParser) args(open, close string) []*Expr {
+	p.accept(open)
+	var list []*Expr
+	for p.pos < len(p.toks) && !p.accept(close) {
+		start := p.pos
+		if a := p.expr(0); a != nil {
+			list = append(list, a)
+		}
+		if !p.accept(",") && p.peek().text != close {
+			if p.pos == start {
+				p.pos++ // Skip tokens we cannot parse
+			}
+		}
+	}
+	return list
+}
+
+func (p *exprParser) postfix(e *Expr) *Expr {
+	if e == nil {
+		return nil
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOp {
+			return e
+		}
+		switch t.text {
+		case ".":
+			p.pos++
+			if p.peek().text == "<" { // Explicit type arguments: Foo.<T>bar()
+				p.pos = skipGroup(p.toks, p.pos)
+			}
+			name := p.next()
+			if name.text == "new" { // outer.new Inner()
+				p.pos--
+				inner := p.newExpr()
+				inner.X = e
+				e = inner
+				continue
+			}
+			if p.peek().text == "(" {
+				e = &Expr{Kind: ExprCall, Value: name.text, X: e, Args: p.args("(", ")"), Line: name.line}
+			} else {
+				e = &Expr{Kind: ExprField, Value: name.text, X: e, Line: name.line}
+			}
+		case "::":
+			p.pos++
+			name := p.next()
+			e = &Expr{Kind: ExprField, Value: name.text, X: e, Line: name.line}
+		case "[":
+			p.pos++
+			idx := p.expr(0)
+			p.accept("]")
+			e = &Expr{Kind: ExprIndex, X: e, Y: idx, Line: t.line}
+		case "++", "--":
+			p.pos++
+			e = &Expr{Kind: ExprUnary, Value: t.text, X: e, Y: &Expr{Kind: ExprLiteral, Value: "post"}, Line: t.line}
+		default:
+			return e
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package java
+
+import (
+	"fmt"
+	"os"
+	"sast-demo/pkg/core"
+	"strings"
+)
+
+// PatternMatcher matches structural patterns against the statements of one Java file.
+//
+// Patterns are Java expressions (or a local variable declaration) where
+// `$X` matches any expression and `...` matches any run of arguments.
+type PatternMatcher struct {
+	stmts []*Stmt
+	funcs []string // Enclosing method of each statement
+}
+
+func NewPatternMatcher(filePath string) (*PatternMatcher, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	m := &PatternMatcher{stmts: ParseSource(string(src))}
+
+	// Track which method each statement belongs to via the block structure
+	var blocks []string
+	current := ""
+	for _, st := range m.stmts {
+		m.funcs = append(m.funcs, current)
+		switch {
+		case st.Kind == StmtClose:
+			if len(blocks) > 0 {
+				current = blocks[len(blocks)-1]
+				blocks = blocks[:len(blocks)-1]
+			}
+		case st.Opens:
+			blocks = append(blocks, current)
+			if st.Kind == StmtMethod {
+				current = st.Name
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *PatternMatcher) Language() string { return "java" }
+
+// Match returns every expression or declaration in the file matching pattern
+func (m *PatternMatcher) Match(pattern string) ([]core.PatternMatch, error) {
+	pattern = strings.TrimSuffix(strings.TrimSpace(pattern), ";")
+	toks := tokenize(pattern, 1)
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty Java pattern")
+	}
+
+	p := parseStatement(rawStatement{tokens: toks, term: ";", line: 1})
+	if p.Expr == nil && p.Kind != StmtDecl {
+		return nil, fmt.Errorf("invalid Java pattern %q", pattern)
+	}
+
+	var matches []core.PatternMatch
+	for i, st := range m.stmts {
+		if p.Kind == StmtDecl {
+			b := map[string]string{}
+			if st.Kind == StmtDecl && matchWord(p.Type, st.Type, b) && matchWord(p.Name, st.Name, b) &&
+				(p.Expr == nil || matchExpr(p.Expr, st.Expr, b)) {
+				code := st.Type + " " + st.Name
+				if st.Expr != nil {
+					code += " = " + st.Expr.String()
+				}
+				matches = append(matches, newMatch(st.Line, code, m.funcs[i], b))
+			}
+			continue
+		}
+		st.Expr.Walk(func(e *Expr) {
+			b := map[string]string{}
+			if matchExpr(p.Expr, e, b) {
+				line := e.Line
+				if line == 0 {
+					line = st.Line
+				}
+				matches = append(matches, newMatch(line, e.String(), m.funcs[i], b))
+			}
+		})
+	}
+	return matches, nil
+}
+
+func newMatch(line int, code, fn string, b map[string]string) core.PatternMatch {
+	return core.PatternMatch{Line: line, Code: code, Function: fn, Bindings: b}
+}
+
+func isMetavar(s string) bool {
+	return len(s) > 1 && s[0] == '$' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+// bind records a metavariable, failing if it is already bound to different text
+func bind(name, text string, b map[string]string) bool {
+	if prev, ok := b[name]; ok {
+		return prev == text
+	}
+	b[name] = text
+	return true
+}
+
+// matchWord compares names and types, which may themselves be metavariables
+func matchWord(p, n string, b map[string]string) bool {
+	if isMetavar(p) {
+		return bind(p, n, b)
+	}
+	return p == n
+}
+
+// matchExpr reports whether expression n has the shape of pattern p
+func matchExpr(p, n *Expr, b map[string]string) bool {
+	if p == nil || n == nil {
+		return p == nil && n == nil
+	}
+	if p.Kind == ExprEllipsis {
+		return true
+	}
+	if p.Kind == ExprName && isMetavar(p.Value) {
+		return bind(p.Value, n.String(), b)
+	}
+	if p.Kind != n.Kind {
+		return false
+	}
+
+	switch p.Kind {
+	case ExprNew:
+		// new ProcessBuilder(...) also matches new java.lang.ProcessBuilder(...)
+		if p.Value != n.Value && !strings.HasSuffix(n.Value, "."+p.Value) {
+			return false
+		}
+	case ExprCall, ExprField, ExprCast:
+		if !matchWord(p.Value, n.Value, b) {
+			return false
+		}
+	default:
+		if p.Value != n.Value {
+			return false
+		}
+	}
+
+	return matchExpr(p.X, n.X, b) && matchExpr(p.Y, n.Y, b) && matchArgs(p.Args, n.Args, b)
+}
+
+// matchArgs matches argument lists, letting `...` absorb any run of arguments
+func matchArgs(ps, ns []*Expr, b map[string]string) bool {
+	if len(ps) == 0 {
+		return len(ns) == 0
+	}
+	if ps[0].Kind == ExprEllipsis {
+		for k := 0; k <= len(ns); k++ {
+			try := copyBindings(b)
+			if matchArgs(ps[1:], ns[k:], try) {
+				commitBindings(b, try)
+				return true
+			}
+		}
+		return false
+	}
+	if len(ns) == 0 {
+		return false
+	}
+	try := copyBindings(b)
+	if matchExpr(ps[0], ns[0], try) && matchArgs(ps[1:], ns[1:], try) {
+		commitBindings(b, try)
+		return true
+	}
+	return false
+}
+
+func copyBindings(b map[string]string) map[string]string {
+	c := make(map[string]string, len(b))
+	for k, v := range b {
+		c[k] = v
+	}
+	return c
+}
+
+func commitBindings(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
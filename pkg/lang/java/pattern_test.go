@@ -0,0 +1,77 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const patternSource = `import java.security.MessageDigest;
+
+public class Patterns {
+    public byte[] checksum(byte[] data) throws Exception {
+        MessageDigest md = MessageDigest.getInstance("MD5");
+        return md.digest(data);
+    }
+
+    public void runScript(String script) throws Exception {
+        new ProcessBuilder("sh", "-c", script).start();
+        Runtime.getRuntime().exec(new String[]{"bash", "-c", script});
+        new ProcessBuilder("ls", "-l").start();
+    }
+
+    public boolean same(String a, String b) {
+        return a.equals(a) || a.equals(b);
+    }
+}
+`
+
+func TestMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Patterns.java")
+	if err := os.WriteFile(path, []byte(patternSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewPatternMatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pattern  string
+		lines    []int
+		function string // Enclosing method of the first match
+		bindings map[string]string
+	}{
+		{`MessageDigest.getInstance("MD5")`, []int{5}, "checksum", map[string]string{}},
+		{`MessageDigest.getInstance("SHA-1")`, nil, "", nil},
+		{`new ProcessBuilder("sh", "-c", ...)`, []int{10}, "runScript", map[string]string{}},
+		{`new ProcessBuilder(...)`, []int{10, 12}, "runScript", map[string]string{}},
+		{`Runtime.getRuntime().exec(new String[]{"bash", "-c", $CMD})`, []int{11}, "runScript", map[string]string{"$CMD": "script"}},
+		// A metavariable used twice matches the same text twice
+		{`$X.equals($X)`, []int{16}, "same", map[string]string{"$X": "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matches, err := m.Match(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var lines []int
+			for _, match := range matches {
+				lines = append(lines, match.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Fatalf("matched lines %v, want %v", lines, tt.lines)
+			}
+			if len(matches) > 0 {
+				if matches[0].Function != tt.function || !reflect.DeepEqual(matches[0].Bindings, tt.bindings) {
+					t.Errorf("first match in %q with %v, want %q with %v", matches[0].Function, matches[0].Bindings, tt.function, tt.bindings)
+				}
+			}
+		})
+	}
+
+	if _, err := m.Match(";"); err == nil {
+		t.Error("an empty pattern was accepted")
+	}
+}
@@ -0,0 +1,54 @@
+package java
+
+import (
+	"os"
+	"sast-demo/pkg/core"
+	"strings"
+)
+
+// CollectSuppressions returns the `// sast:ignore` comments of a Java file
+func CollectSuppressions(filePath string) ([]core.Suppression, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var sups []core.Suppression
+	line := 1
+	code := false // Non-comment text seen on the current line
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			code = false
+		case c == '"' || c == '\'':
+			// Skip literals so that "//" inside a string is not a comment
+			for i++; i < len(src) && src[i] != c && src[i] != '\n'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+			code = true
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(string(src[i+2:]), "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			line += strings.Count(string(src[i:i+2+end]), "\n")
+			i += end + 3
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			end := i + 2
+			for end < len(src) && src[end] != '\n' {
+				end++
+			}
+			if s, ok := core.ParseSuppression(string(src[i+2:end]), line, code); ok {
+				sups = append(sups, s)
+			}
+			i = end - 1
+		case c != ' ' && c != '\t' && c != '\r':
+			code = true
+		}
+	}
+	return sups, nil
+}
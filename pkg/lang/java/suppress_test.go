@@ -0,0 +1,42 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sast-demo/pkg/core"
+	"testing"
+)
+
+const suppressSource = `public class Runner {
+    public void run(String cmd) throws Exception {
+        Runtime.getRuntime().exec(cmd); // sast:ignore rce reason: cmd is a fixed allow-list entry
+        // sast:ignore rce,sqli reason: checked by the caller
+        Runtime.getRuntime().exec(cmd);
+        /* sast:ignore rce reason: block comments are not suppressions
+           // sast:ignore rce reason: nor line comments inside them */
+        Runtime.getRuntime().exec(cmd);
+        Runtime.getRuntime().exec("// sast:ignore rce reason: inside a string");
+        char c = '"'; // sast:ignore * reason: after a quote character
+    }
+}
+`
+
+func TestCollectSuppressions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Runner.java")
+	if err := os.WriteFile(path, []byte(suppressSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sups, err := CollectSuppressions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []core.Suppression{
+		{Line: 3, CommentLine: 3, Rules: []string{"rce"}, Reason: "cmd is a fixed allow-list entry"},
+		{Line: 5, CommentLine: 4, Rules: []string{"rce", "sqli"}, Reason: "checked by the caller"},
+		{Line: 10, CommentLine: 10, Rules: []string{"*"}, Reason: "after a quote character"},
+	}
+	if !reflect.DeepEqual(sups, want) {
+		t.Errorf("suppressions\n%+v\nwant\n%+v", sups, want)
+	}
+}
@@ -0,0 +1,100 @@
+package jvm
+
+import (
+	"fmt"
+	"sast-demo/pkg/core"
+)
+
+// ASTGenerator builds a structural tree (classes, methods, disassembled instructions)
+// for compiled classes, since bytecode has no syntax tree of its own.
+type ASTGenerator struct {
+}
+
+func NewASTGenerator() *ASTGenerator {
+	return &ASTGenerator{}
+}
+
+func (g *ASTGenerator) Generate(filePath string) (*core.ASTNode, error) {
+	classes, skipped, err := LoadClasses(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &core.ASTNode{
+		Key:   "root",
+		Title: "File: " + filePath,
+		Line:  1,
+	}
+
+	for ci, cf := range classes {
+		classNode := &core.ASTNode{
+			Key:   fmt.Sprintf("0-%d", ci),
+			Title: "ClassDecl: " + dottedName(cf.Name),
+		}
+		if cf.SourceFile != "" {
+			classNode.Title += fmt.Sprintf(" (Source: %s)", cf.SourceFile)
+		}
+		root.Children = append(root.Children, classNode)
+
+		for mi, m := range cf.Methods {
+			methodNode := &core.ASTNode{
+				Key:   fmt.Sprintf("%s-%d", classNode.Key, mi),
+				Title: fmt.Sprintf("MethodDecl: %s%s", m.Name, m.Descriptor),
+				Line:  m.LineAt(0),
+			}
+			classNode.Children = append(classNode.Children, methodNode)
+
+			insts, err := decode(m.Code)
+			if m.Err != nil {
+				err = m.Err
+			}
+			if err != nil {
+				methodNode.Title += fmt.Sprintf(" (decode error: %v)", err)
+				continue
+			}
+			for ii, ri := range insts {
+				methodNode.Children = append(methodNode.Children, &core.ASTNode{
+					Key:   fmt.Sprintf("%s-%d", methodNode.Key, ii),
+					Title: fmt.Sprintf("%d: %s", ri.pc, describe(cf, ri)),
+					Line:  m.LineAt(ri.pc),
+				})
+			}
+		}
+	}
+
+	for i, err := range skipped {
+		root.Children = append(root.Children, &core.ASTNode{
+			Key:   fmt.Sprintf("0-%d", len(classes)+i),
+			Title: fmt.Sprintf("Skipped: %v", err),
+		})
+	}
+
+	return root, nil
+}
+
+// describe renders an instruction in javap style, resolving constant pool references
+func describe(cf *ClassFile, ri rawInst) string {
+	name := ri.name()
+	switch {
+	case ri.op >= opGetstatic && ri.op <= opInvokeinterface:
+		owner, member, desc := cf.memberRef(uint16(ri.index))
+		return fmt.Sprintf("%s %s.%s %s", name, dottedName(owner), member, desc)
+	case ri.op == opInvokedynamic:
+		member, desc := cf.dynamicRef(uint16(ri.index))
+		return fmt.Sprintf("%s %s %s", name, member, desc)
+	case ri.op == opNew || ri.op == opAnewarray || ri.op == opCheckcast || ri.op == opInstanceof || ri.op == opMultianewarray:
+		return fmt.Sprintf("%s %s", name, dottedName(cf.className(uint16(ri.index))))
+	case ri.op == opLdc || ri.op == opLdcW || ri.op == opLdc2W:
+		lit, _ := cf.constant(uint16(ri.index))
+		return fmt.Sprintf("%s %s", name, lit)
+	case ri.op == opBipush || ri.op == opSipush:
+		return fmt.Sprintf("%s %d", name, ri.value)
+	case ri.op == opIinc:
+		return fmt.Sprintf("%s %d, %d", name, ri.index, ri.value)
+	case len(ri.targets) > 0:
+		return fmt.Sprintf("%s %v", name, ri.targets)
+	case (ri.op >= opIload && ri.op <= opAload) || (ri.op >= opIstore && ri.op <= opAstore) || ri.op == opRet:
+		return fmt.Sprintf("%s %d", name, ri.index)
+	}
+	return name
+}
@@ -0,0 +1,410 @@
+package jvm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Constant pool tags (JVMS §4.4)
+const (
+	tagUtf8               = 1
+	tagInteger            = 3
+	tagFloat              = 4
+	tagLong               = 5
+	tagDouble             = 6
+	tagClass              = 7
+	tagString             = 8
+	tagFieldref           = 9
+	tagMethodref          = 10
+	tagInterfaceMethodref = 11
+	tagNameAndType        = 12
+	tagMethodHandle       = 15
+	tagMethodType         = 16
+	tagDynamic            = 17
+	tagInvokeDynamic      = 18
+	tagModule             = 19
+	tagPackage            = 20
+)
+
+const accStatic = 0x0008
+
+// cpEntry is a single constant pool slot. Only the fields relevant to the tag are set.
+type cpEntry struct {
+	tag  byte
+	str  string  // Utf8
+	num  int64   // Integer, Long
+	fnum float64 // Float, Double
+	idx1 uint16  // Class name, String, ref class, NameAndType name, bootstrap index
+	idx2 uint16  // ref NameAndType, NameAndType descriptor
+}
+
+// ExceptionHandler is one entry of a Code attribute's exception table
+type ExceptionHandler struct {
+	StartPC   int
+	EndPC     int
+	HandlerPC int
+	CatchType string // Empty for finally / catch-all
+}
+
+// LocalVariable is one entry of a LocalVariableTable attribute
+type LocalVariable struct {
+	StartPC int
+	Length  int
+	Name    string
+	Slot    int
+}
+
+// Method is a parsed method_info with its Code attribute (if any)
+type Method struct {
+	Name        string
+	Descriptor  string
+	AccessFlags uint16
+	MaxLocals   int
+	Code        []byte
+	Handlers    []ExceptionHandler
+	Lines       []LineEntry     // Sorted by StartPC
+	Locals      []LocalVariable // Empty unless compiled with -g
+	// Err is why the Code attribute could not be read; Code is nil then
+	Err error
+}
+
+// LineEntry maps a bytecode offset to a source line
+type LineEntry struct {
+	StartPC int
+	Line    int
+}
+
+// ClassFile is the subset of a .class file needed for IR generation
+type ClassFile struct {
+	Name       string // Internal name, e.g. com/example/Foo
+	SuperName  string
+	SourceFile string
+	Methods    []*Method
+
+	pool []cpEntry
+}
+
+// IsStatic reports whether the method has the ACC_STATIC flag
+func (m *Method) IsStatic() bool {
+	return m.AccessFlags&accStatic != 0
+}
+
+// LineAt returns the source line for a bytecode offset, or 0 if unknown
+func (m *Method) LineAt(pc int) int {
+	line := 0
+	for _, e := range m.Lines {
+		if e.StartPC > pc {
+			break
+		}
+		line = e.Line
+	}
+	return line
+}
+
+// LocalName returns the debug name of a local slot at pc, if the class was compiled with -g
+func (m *Method) LocalName(slot, pc int) string {
+	for _, lv := range m.Locals {
+		if lv.Slot == slot && pc >= lv.StartPC && pc < lv.StartPC+lv.Length {
+			return lv.Name
+		}
+	}
+	return ""
+}
+
+var errTruncated = errors.New("truncated class file")
+
+// classReader is a big-endian cursor over the raw class bytes
+type classReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *classReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("%w: %d bytes needed at offset %d, %d left", errTruncated, n, r.pos, len(r.data)-r.pos)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *classReader) u1() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *classReader) u2() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *classReader) u4() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// ParseClass decodes a .class file without loading it into a JVM
+func ParseClass(data []byte) (*ClassFile, error) {
+	r := &classReader{data: data}
+	if r.u4() != 0xCAFEBABE {
+		return nil, fmt.Errorf("not a class file (bad magic)")
+	}
+	r.u2() // minor_version
+	r.u2() // major_version
+
+	cf := &ClassFile{}
+	count := int(r.u2())
+	cf.pool = make([]cpEntry, count)
+	for i := 1; i < count; i++ {
+		e := cpEntry{tag: r.u1()}
+		switch e.tag {
+		case tagUtf8:
+			e.str = string(r.bytes(int(r.u2())))
+		case tagInteger:
+			e.num = int64(int32(r.u4()))
+		case tagFloat:
+			e.fnum = float64(math.Float32frombits(r.u4()))
+		case tagLong:
+			e.num = int64(uint64(r.u4())<<32 | uint64(r.u4()))
+		case tagDouble:
+			e.fnum = math.Float64frombits(uint64(r.u4())<<32 | uint64(r.u4()))
+		case tagClass, tagString, tagMethodType, tagModule, tagPackage:
+			e.idx1 = r.u2()
+		case tagFieldref, tagMethodref, tagInterfaceMethodref, tagNameAndType, tagDynamic, tagInvokeDynamic:
+			e.idx1 = r.u2()
+			e.idx2 = r.u2()
+		case tagMethodHandle:
+			r.u1()
+			e.idx1 = r.u2()
+		default:
+			if r.err != nil {
+				return nil, fmt.Errorf("constant pool entry %d: %w", i, r.err)
+			}
+			return nil, fmt.Errorf("unknown constant pool tag %d at index %d (offset %d)", e.tag, i, r.pos-1)
+		}
+		cf.pool[i] = e
+		if e.tag == tagLong || e.tag == tagDouble {
+			i++ // 8-byte constants take two slots
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("constant pool: %w", r.err)
+	}
+
+	r.u2() // access_flags
+	cf.Name = cf.className(r.u2())
+	cf.SuperName = cf.className(r.u2())
+	for n := int(r.u2()); n > 0; n-- {
+		r.u2() // interfaces
+	}
+
+	// Fields are not needed for IR generation
+	for n := int(r.u2()); n > 0; n-- {
+		r.bytes(6)
+		cf.skipAttributes(r)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%s: fields: %w", dottedName(cf.Name), r.err)
+	}
+
+	for n := int(r.u2()); n > 0; n-- {
+		m := &Method{AccessFlags: r.u2()}
+		m.Name = cf.utf8(r.u2())
+		m.Descriptor = cf.utf8(r.u2())
+		for a := int(r.u2()); a > 0; a-- {
+			name := cf.utf8(r.u2())
+			body := r.bytes(int(r.u4()))
+			if name == "Code" && body != nil {
+				// A malformed Code attribute has a length of its own: the method
+				// is left without code and the rest of the class still reads
+				if err := cf.parseCode(m, body); err != nil {
+					m.Code, m.Err = nil, fmt.Errorf("Code attribute: %w", err)
+				}
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%s.%s%s: %w", dottedName(cf.Name), m.Name, m.Descriptor, r.err)
+		}
+		cf.Methods = append(cf.Methods, m)
+	}
+
+	for a := int(r.u2()); a > 0; a-- {
+		name := cf.utf8(r.u2())
+		body := r.bytes(int(r.u4()))
+		if name == "SourceFile" && len(body) == 2 {
+			cf.SourceFile = cf.utf8(binary.BigEndian.Uint16(body))
+		}
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("%s: class attributes: %w", dottedName(cf.Name), r.err)
+	}
+	return cf, nil
+}
+
+func (cf *ClassFile) skipAttributes(r *classReader) {
+	for n := int(r.u2()); n > 0; n-- {
+		r.u2()
+		r.bytes(int(r.u4()))
+	}
+}
+
+func (cf *ClassFile) parseCode(m *Method, body []byte) error {
+	r := &classReader{data: body}
+	r.u2() // max_stack
+	m.MaxLocals = int(r.u2())
+	m.Code = r.bytes(int(r.u4()))
+
+	for n := int(r.u2()); n > 0; n-- {
+		h := ExceptionHandler{
+			StartPC:   int(r.u2()),
+			EndPC:     int(r.u2()),
+			HandlerPC: int(r.u2()),
+		}
+		if t := r.u2(); t != 0 {
+			h.CatchType = cf.className(t)
+		}
+		m.Handlers = append(m.Handlers, h)
+	}
+
+	for n := int(r.u2()); n > 0; n-- {
+		name := cf.utf8(r.u2())
+		attr := &classReader{data: r.bytes(int(r.u4()))}
+		switch name {
+		case "LineNumberTable":
+			for k := int(attr.u2()); k > 0; k-- {
+				m.Lines = append(m.Lines, LineEntry{StartPC: int(attr.u2()), Line: int(attr.u2())})
+			}
+		case "LocalVariableTable":
+			for k := int(attr.u2()); k > 0; k-- {
+				lv := LocalVariable{StartPC: int(attr.u2()), Length: int(attr.u2())}
+				lv.Name = cf.utf8(attr.u2())
+				attr.u2() // descriptor
+				lv.Slot = int(attr.u2())
+				m.Locals = append(m.Locals, lv)
+			}
+		}
+	}
+
+	// LineNumberTable entries are not required to be ordered
+	sort.Slice(m.Lines, func(i, j int) bool { return m.Lines[i].StartPC < m.Lines[j].StartPC })
+	return r.err
+}
+
+// --- Constant pool accessors ---
+
+func (cf *ClassFile) entry(idx uint16) cpEntry {
+	if int(idx) < len(cf.pool) {
+		return cf.pool[idx]
+	}
+	return cpEntry{}
+}
+
+func (cf *ClassFile) utf8(idx uint16) string {
+	return cf.entry(idx).str
+}
+
+func (cf *ClassFile) className(idx uint16) string {
+	e := cf.entry(idx)
+	if e.tag != tagClass {
+		return ""
+	}
+	return cf.utf8(e.idx1)
+}
+
+// memberRef resolves a Fieldref/Methodref/InterfaceMethodref to owner, name and descriptor
+func (cf *ClassFile) memberRef(idx uint16) (owner, name, desc string) {
+	e := cf.entry(idx)
+	nt := cf.entry(e.idx2)
+	return cf.className(e.idx1), cf.utf8(nt.idx1), cf.utf8(nt.idx2)
+}
+
+// dynamicRef resolves an InvokeDynamic entry to its name and descriptor
+func (cf *ClassFile) dynamicRef(idx uint16) (name, desc string) {
+	nt := cf.entry(cf.entry(idx).idx2)
+	return cf.utf8(nt.idx1), cf.utf8(nt.idx2)
+}
+
+// constant renders an ldc operand as a literal, reporting whether it is a category-2 value
+func (cf *ClassFile) constant(idx uint16) (string, bool) {
+	e := cf.entry(idx)
+	switch e.tag {
+	case tagString:
+		return fmt.Sprintf("%q", cf.utf8(e.idx1)), false
+	case tagInteger:
+		return fmt.Sprintf("%d", e.num), false
+	case tagLong:
+		return fmt.Sprintf("%dL", e.num), true
+	case tagFloat:
+		return fmt.Sprintf("%gf", e.fnum), false
+	case tagDouble:
+		return fmt.Sprintf("%g", e.fnum), true
+	case tagClass:
+		return simpleName(cf.className(idx)) + ".class", false
+	case tagMethodType:
+		return cf.utf8(e.idx1), false
+	}
+	return "?", false
+}
+
+// --- Descriptor helpers ---
+
+// parseMethodDescriptor returns the parameter types and the return type of a method descriptor
+func parseMethodDescriptor(desc string) (params []string, ret string) {
+	if !strings.HasPrefix(desc, "(") {
+		return nil, "V"
+	}
+	i := 1
+	for i < len(desc) && desc[i] != ')' {
+		start := i
+		for i < len(desc) && desc[i] == '[' {
+			i++
+		}
+		if i < len(desc) && desc[i] == 'L' {
+			end := strings.IndexByte(desc[i:], ';')
+			if end < 0 {
+				return params, "V"
+			}
+			i += end
+		}
+		i++
+		params = append(params, desc[start:min(i, len(desc))])
+	}
+	if i+1 > len(desc) {
+		return params, "V"
+	}
+	return params, desc[i+1:]
+}
+
+// isWide reports whether a field type occupies two stack/local slots
+func isWide(t string) bool {
+	return t == "J" || t == "D"
+}
+
+// simpleName turns an internal name like java/net/URL into URL
+func simpleName(internal string) string {
+	if i := strings.LastIndexByte(internal, '/'); i >= 0 {
+		internal = internal[i+1:]
+	}
+	return internal
+}
+
+// dottedName turns an internal name like java/net/URL into java.net.URL
+func dottedName(internal string) string {
+	return strings.ReplaceAll(internal, "/", ".")
+}
@@ -0,0 +1,172 @@
+package jvm
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testMethod is a static method `()V` of a test class
+type testMethod struct {
+	name string
+	code []byte
+	// codeLen, if set, overrides the code length written in the Code attribute
+	codeLen int
+}
+
+// assemble builds a minimal class file T with the given methods
+func assemble(methods ...testMethod) []byte {
+	var b []byte
+	u1 := func(v int) { b = append(b, byte(v)) }
+	u2 := func(v int) { b = binary.BigEndian.AppendUint16(b, uint16(v)) }
+	u4 := func(v int) { b = binary.BigEndian.AppendUint32(b, uint32(v)) }
+	utf8 := func(s string) { u1(tagUtf8); u2(len(s)); b = append(b, s...) }
+
+	u4(0xCAFEBABE)
+	u2(0)
+	u2(52)
+	// 1 "T", 2 class T, 3 "java/lang/Object", 4 class Object, 5 "Code", 6 "()V", 7.. method names
+	u2(7 + len(methods))
+	utf8("T")
+	u1(tagClass)
+	u2(1)
+	utf8("java/lang/Object")
+	u1(tagClass)
+	u2(3)
+	utf8("Code")
+	utf8("()V")
+	for _, m := range methods {
+		utf8(m.name)
+	}
+	u2(0x21) // public super
+	u2(2)
+	u2(4)
+	u2(0) // interfaces
+	u2(0) // fields
+	u2(len(methods))
+	for i, m := range methods {
+		u2(accStatic | 0x1)
+		u2(7 + i)
+		u2(6)
+		u2(1) // attributes
+		u2(5)
+		u4(12 + len(m.code))
+		u2(1) // max_stack
+		u2(0) // max_locals
+		if m.codeLen != 0 {
+			u4(m.codeLen)
+		} else {
+			u4(len(m.code))
+		}
+		b = append(b, m.code...)
+		u2(0) // exception table
+		u2(0) // attributes
+	}
+	u2(0) // class attributes
+	return b
+}
+
+var (
+	okMethod        = testMethod{name: "ok", code: []byte{opReturn}}
+	switchMethod    = testMethod{name: "badSwitch", code: []byte{opTableswitch, 0, 0, 0, 0, 0, 0, 0}}
+	emptyMethod     = testMethod{name: "empty"}
+	badCodeLength   = testMethod{name: "badLength", code: []byte{opReturn}, codeLen: 100}
+	wideTruncated   = testMethod{name: "badWide", code: []byte{opWide, 0x15}}
+	expectedSkipped = map[string]string{
+		"T.badSwitch()V": "tableswitch at pc 0",
+		"T.empty()V":     "empty Code attribute",
+		"T.badLength()V": "Code attribute: truncated class file: 100 bytes needed at offset 8",
+		"T.badWide()V":   "wide at pc 0",
+	}
+)
+
+func TestParseClassTruncated(t *testing.T) {
+	data := assemble(okMethod)
+	if _, err := ParseClass(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{9, 20, len(data) - 20, len(data) - 1} {
+		_, err := ParseClass(data[:n])
+		if !errors.Is(err, errTruncated) || !strings.Contains(err.Error(), "offset") {
+			t.Errorf("class cut to %d bytes: %v, want a truncation with its offset", n, err)
+		}
+	}
+}
+
+func TestGenerateSkipsBadMethods(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "T.class")
+	data := assemble(okMethod, switchMethod, emptyMethod, badCodeLength, wideTruncated)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := NewIRGenerator()
+	prog, err := g.Generate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prog.Functions) != 1 || prog.Functions["T.ok"] == nil {
+		t.Errorf("functions %v, want only T.ok", prog.FunctionNames())
+	}
+	checkSkipped(t, g.Skipped, expectedSkipped)
+
+	// The structure view shows the bad methods with their errors
+	ast, err := NewASTGenerator().Generate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := ast.Children[0].Children[1].Title; !strings.Contains(title, "tableswitch at pc 0") {
+		t.Errorf("method node %q", title)
+	}
+}
+
+func TestJarSkipsBadClass(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.jar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	good := assemble(okMethod)
+	for name, data := range map[string][]byte{"a/Good.class": good, "a/Cut.class": good[:len(good)/2]} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	g := NewIRGenerator()
+	prog, err := g.Generate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prog.Functions["T.ok"] == nil {
+		t.Errorf("functions %v, want T.ok from the readable class", prog.FunctionNames())
+	}
+	if len(g.Skipped) != 1 || !strings.Contains(g.Skipped[0].Error(), "a/Cut.class") || !errors.Is(g.Skipped[0], errTruncated) {
+		t.Errorf("skipped %v, want the truncated a/Cut.class", g.Skipped)
+	}
+}
+
+// checkSkipped matches skipped errors to the methods they name
+func checkSkipped(t *testing.T, skipped []error, want map[string]string) {
+	t.Helper()
+	if len(skipped) != len(want) {
+		t.Errorf("skipped %v, want %d methods", skipped, len(want))
+	}
+	for _, err := range skipped {
+		msg := err.Error()
+		method, _, _ := strings.Cut(msg, ":")
+		if detail, ok := want[method]; !ok || !strings.Contains(msg, detail) {
+			t.Errorf("skipped %q, want %q", msg, detail)
+		}
+	}
+}
@@ -0,0 +1,863 @@
+package jvm
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sast-demo/pkg/core"
+	"sort"
+	"strings"
+)
+
+// maxCalleeText bounds the rendered receiver chain (e.g. a.b().c().d()) used in call names
+const maxCalleeText = 80
+
+// IRGenerator translates compiled Java classes (.class files or .jar archives) into IR
+// by simulating the JVM operand stack. No JVM is required.
+type IRGenerator struct {
+	prog      *core.ProgramIR
+	instCount int
+
+	// Skipped lists the classes of an archive and the methods that could not be
+	// decoded and were left out of the IR
+	Skipped []error
+
+	// Context for current method
+	class    *ClassFile
+	method   *Method
+	fn       *core.FunctionIR
+	block    *core.BasicBlock
+	stack    []value
+	newCount int
+}
+
+// value is an abstract operand stack entry
+type value struct {
+	name    string // IR variable holding the value
+	text    string // Java-like rendering, used to build call names like Runtime.getRuntime().exec
+	wide    bool   // long/double occupy two stack slots
+	origin  string // Variable the value was loaded from, for array stores
+	pending int    // Non-zero for an object created by `new` whose <init> has not run yet
+}
+
+// methodBlock is a basic block before translation
+type methodBlock struct {
+	bb         *core.BasicBlock
+	insts      []rawInst
+	handler    bool
+	entryStack []value // Stack slots live on entry (spilled, or pending `new` objects)
+	hasEntry   bool
+}
+
+func NewIRGenerator() *IRGenerator {
+	return &IRGenerator{
+		prog: core.NewProgramIR(),
+	}
+}
+
+// Generate loads a .class file, or every class inside a .jar, and builds the IR.
+// Classes of a .jar and methods that cannot be decoded are left out and listed
+// in Skipped.
+func (g *IRGenerator) Generate(filePath string) (*core.ProgramIR, error) {
+	classes, skipped, err := LoadClasses(filePath)
+	if err != nil {
+		return nil, err
+	}
+	g.Skipped = append(g.Skipped, skipped...)
+	for _, cf := range classes {
+		g.processClass(cf)
+	}
+	return g.prog, nil
+}
+
+// LoadClasses parses a single .class file or all classes contained in a .jar.
+// The classes of a .jar that cannot be read are returned as skipped, each error
+// naming its entry; only a .class file that cannot be read, or an archive that
+// cannot be opened, fails the load.
+func LoadClasses(filePath string) (classes []*ClassFile, skipped []error, err error) {
+	if strings.ToLower(filepath.Ext(filePath)) != ".jar" {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		cf, err := ParseClass(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		return []*ClassFile{cf}, nil, nil
+	}
+
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zr.Close()
+
+	var entries []*zip.File
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".class") && !strings.HasPrefix(f.Name, "META-INF/") {
+			entries = append(entries, f)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	for _, f := range entries {
+		cf, err := readEntry(f)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %s: %w", filePath, f.Name, err))
+			continue
+		}
+		classes = append(classes, cf)
+	}
+	return classes, skipped, nil
+}
+
+// readEntry parses a class stored in an archive
+func readEntry(f *zip.File) (*ClassFile, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("reading %d of %d bytes: %w", len(data), f.UncompressedSize64, err)
+	}
+	return ParseClass(data)
+}
+
+// processClass translates the methods of a class, skipping those that cannot be decoded
+func (g *IRGenerator) processClass(cf *ClassFile) {
+	g.class = cf
+	for _, m := range cf.Methods {
+		err := m.Err
+		if err == nil && m.Code != nil {
+			err = g.processMethod(m)
+		}
+		if err != nil {
+			g.Skipped = append(g.Skipped, fmt.Errorf("%s.%s%s: %w", dottedName(cf.Name), m.Name, m.Descriptor, err))
+		}
+	}
+}
+
+func (g *IRGenerator) processMethod(m *Method) error {
+	insts, err := decode(m.Code)
+	if err != nil {
+		return err
+	}
+	if len(insts) == 0 {
+		return fmt.Errorf("empty Code attribute")
+	}
+
+	funcName := dottedName(g.class.Name) + "." + m.Name
+	for n := 2; g.prog.Functions[funcName] != nil; n++ {
+		funcName = fmt.Sprintf("%s.%s#%d", dottedName(g.class.Name), m.Name, n) // Overloads
+	}
+
+	g.method = m
+	g.fn = &core.FunctionIR{
+		Name:   funcName,
+		Blocks: make(map[string]*core.BasicBlock),
+	}
+	g.stack = nil
+	g.newCount = 0
+
+	blocks := g.splitBlocks(insts)
+	g.fn.Entry = blocks[0].bb.ID
+
+	// Parameters are defined in the entry block, like the Go frontend does
+	g.block = blocks[0].bb
+	params, _ := parseMethodDescriptor(m.Descriptor)
+	slot := 0
+	if !m.IsStatic() {
+		slot = 1
+		g.fn.Receiver = "this"
+	}
+	for i, p := range params {
+		name := m.LocalName(slot, 0)
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		g.emit(core.OpParam, name, nil, "", 0)
+		slot++
+		if isWide(p) {
+			slot++
+		}
+	}
+
+	blocks[0].hasEntry = true
+	for i, mb := range blocks {
+		g.translateBlock(mb, blocks, i)
+	}
+
+	g.prog.Functions[funcName] = g.fn
+	return nil
+}
+
+// splitBlocks finds leaders from jump targets and the exception table and wires up the CFG
+func (g *IRGenerator) splitBlocks(insts []rawInst) []*methodBlock {
+	leaders := map[int]bool{0: true}
+	handlers := map[int]bool{}
+	for i, ri := range insts {
+		for _, t := range ri.targets {
+			leaders[t] = true
+		}
+		if endsBlock(ri.op) && i+1 < len(insts) {
+			leaders[insts[i+1].pc] = true
+		}
+	}
+	for _, h := range g.method.Handlers {
+		leaders[h.StartPC] = true
+		leaders[h.EndPC] = true
+		leaders[h.HandlerPC] = true
+		handlers[h.HandlerPC] = true
+	}
+
+	var blocks []*methodBlock
+	byPC := make(map[int]*methodBlock)
+	for _, ri := range insts {
+		if leaders[ri.pc] || len(blocks) == 0 {
+			mb := &methodBlock{
+				bb: &core.BasicBlock{
+					ID:           fmt.Sprintf("B%d", len(blocks)),
+					Instructions: make([]*core.Instruction, 0),
+					Predecessors: make([]string, 0),
+					Successors:   make([]string, 0),
+				},
+				handler: handlers[ri.pc],
+			}
+			g.fn.Blocks[mb.bb.ID] = mb.bb
+			blocks = append(blocks, mb)
+			byPC[ri.pc] = mb
+		}
+		cur := blocks[len(blocks)-1]
+		cur.insts = append(cur.insts, ri)
+	}
+
+	link := func(from, to *methodBlock) {
+		if to == nil {
+			return
+		}
+		for _, s := range from.bb.Successors {
+			if s == to.bb.ID {
+				return
+			}
+		}
+		from.bb.Successors = append(from.bb.Successors, to.bb.ID)
+		to.bb.Predecessors = append(to.bb.Predecessors, from.bb.ID)
+	}
+
+	for i, mb := range blocks {
+		last := mb.insts[len(mb.insts)-1]
+		for _, t := range last.targets {
+			link(mb, byPC[t])
+		}
+		if !isTerminal(last.op) && i+1 < len(blocks) {
+			link(mb, blocks[i+1])
+		}
+		// Every block covered by a try range may transfer to the handler
+		start := mb.insts[0].pc
+		for _, h := range g.method.Handlers {
+			if start >= h.StartPC && start < h.EndPC {
+				link(mb, byPC[h.HandlerPC])
+			}
+		}
+	}
+	return blocks
+}
+
+// endsBlock reports whether the instruction after op starts a new basic block
+func endsBlock(op byte) bool {
+	switch {
+	case op >= opIfeq && op <= opLookupswitch:
+		return true
+	case op >= opIreturn && op <= opReturn:
+		return true
+	case op == opAthrow || op == opIfnull || op == opIfnonnull || op == opGotoW || op == opJsrW:
+		return true
+	}
+	return false
+}
+
+// isTerminal reports whether control never falls through to the next instruction
+func isTerminal(op byte) bool {
+	switch {
+	case op == opGoto || op == opGotoW || op == opRet || op == opAthrow:
+		return true
+	case op == opTableswitch || op == opLookupswitch:
+		return true
+	case op >= opIreturn && op <= opReturn:
+		return true
+	}
+	return false
+}
+
+func (g *IRGenerator) translateBlock(mb *methodBlock, blocks []*methodBlock, idx int) {
+	g.block = mb.bb
+	g.stack = nil
+	pc := mb.insts[0].pc
+
+	if mb.handler {
+		res := g.tempVar()
+		g.emit(core.OpLoad, res, []string{"exception"}, "", pc)
+		g.push(value{name: res, text: "exception"})
+	} else {
+		// Values left on the stack by predecessors were spilled to s0, s1, ...
+		for i, v := range mb.entryStack {
+			if v.pending != 0 {
+				g.push(v)
+				continue
+			}
+			res := g.tempVar()
+			slot := fmt.Sprintf("s%d", i)
+			g.emit(core.OpLoad, res, []string{slot}, "", pc)
+			g.push(value{name: res, text: slot, wide: v.wide})
+		}
+	}
+
+	terminated := false
+	for _, ri := range mb.insts {
+		terminated = g.translate(ri, blocks)
+	}
+
+	if !terminated {
+		g.spillStack(blocks)
+		if idx+1 < len(blocks) {
+			last := mb.insts[len(mb.insts)-1]
+			g.emit(core.OpJump, "", []string{blocks[idx+1].bb.ID}, "", last.pc)
+		}
+	}
+}
+
+// spillStack stores live stack values into s0, s1, ... so successor blocks can reload them
+func (g *IRGenerator) spillStack(blocks []*methodBlock) {
+	live := make([]value, len(g.stack))
+	copy(live, g.stack)
+	for i, v := range g.stack {
+		// Uninitialized objects are not real values yet; they are carried over as-is
+		if v.pending == 0 {
+			g.emit(core.OpStore, fmt.Sprintf("s%d", i), []string{v.name}, "", -1)
+		}
+	}
+	for _, succ := range g.block.Successors {
+		for _, mb := range blocks {
+			if mb.bb.ID == succ && !mb.hasEntry && !mb.handler {
+				mb.entryStack = live
+				mb.hasEntry = true
+			}
+		}
+	}
+}
+
+// translate emits IR for one bytecode instruction, returning true if it ended the block
+func (g *IRGenerator) translate(ri rawInst, blocks []*methodBlock) bool {
+	cf := g.class
+	op := ri.op
+	pc := ri.pc
+
+	blockAt := func(target int) string {
+		for _, mb := range blocks {
+			if mb.insts[0].pc == target {
+				return mb.bb.ID
+			}
+		}
+		return "?"
+	}
+
+	switch {
+	case op == opNop:
+
+	case op == opAconstNull:
+		g.pushConst("null", false, pc)
+	case op >= opIconstM1 && op <= opIconst5:
+		g.pushConst(fmt.Sprintf("%d", int(op)-int(opIconstM1)-1), false, pc)
+	case op == opLconst0 || op == opLconst1:
+		g.pushConst(fmt.Sprintf("%dL", op-opLconst0), true, pc)
+	case op >= opFconst0 && op <= opFconst2:
+		g.pushConst(fmt.Sprintf("%d.0f", op-opFconst0), false, pc)
+	case op == opDconst0 || op == opDconst1:
+		g.pushConst(fmt.Sprintf("%d.0", op-opDconst0), true, pc)
+	case op == opBipush || op == opSipush:
+		g.pushConst(fmt.Sprintf("%d", ri.value), false, pc)
+	case op == opLdc || op == opLdcW || op == opLdc2W:
+		lit, wide := cf.constant(uint16(ri.index))
+		g.pushConst(lit, wide, pc)
+
+	case op >= opIload && op <= opAload3:
+		slot, kind := ri.index, op-opIload
+		if op >= opIload0 {
+			slot, kind = int(op-opIload0)%4, (op-opIload0)/4
+		}
+		name := g.localName(slot, pc)
+		res := g.tempVar()
+		g.emit(core.OpLoad, res, []string{name}, "", pc)
+		g.push(value{name: res, text: name, origin: name, wide: kind == 1 || kind == 3})
+
+	case op >= opIaload && op <= opSaload:
+		g.pop() // index
+		arr := g.pop()
+		res := g.tempVar()
+		g.emit(core.OpLoad, res, []string{arr.name}, "", pc)
+		g.push(value{name: res, text: arr.text + "[]", wide: op == opIaload+1 || op == opIaload+3})
+
+	case op >= opIstore && op <= opAstore3:
+		slot := ri.index
+		if op >= opIstore0 {
+			slot = int(op-opIstore0) % 4
+		}
+		v := g.pop()
+		// The debug scope of a local starts right after the store that initializes it
+		g.emit(core.OpStore, g.localName(slot, pc+ri.length), []string{v.name}, "", pc)
+
+	case op >= opIastore && op <= opSastore:
+		v := g.pop()
+		g.pop() // index
+		arr := g.pop()
+		target := arr.origin
+		if target == "" {
+			target = arr.name
+		}
+		g.emit(core.OpStore, target, []string{v.name}, "", pc)
+
+	case op >= opPop && op <= opSwap:
+		g.stackOp(op)
+
+	case op >= opIadd && op <= opDrem, op >= opIshl && op <= opLxor:
+		b := g.pop()
+		a := g.pop()
+		res := g.tempVar()
+		g.emit(core.OpBinOp, res, []string{a.name, arithSymbol(op), b.name}, "", pc)
+		g.push(value{name: res, text: res, wide: a.wide})
+
+	case op >= opIneg && op <= opDneg:
+		a := g.pop()
+		res := g.tempVar()
+		g.emit(core.OpBinOp, res, []string{"0", "-", a.name}, "", pc)
+		g.push(value{name: res, text: res, wide: a.wide})
+
+	case op == opIinc:
+		name := g.localName(ri.index, pc)
+		res := g.tempVar()
+		g.emit(core.OpBinOp, res, []string{name, "+", fmt.Sprintf("%d", ri.value)}, "", pc)
+		g.emit(core.OpStore, name, []string{res}, "", pc)
+
+	case op >= opI2l && op <= opI2s:
+		// Conversions keep the value (and its taint); only the slot width changes
+		a := g.pop()
+		a.wide = convertsToWide(op)
+		g.push(a)
+
+	case op >= opLcmp && op <= opDcmpg:
+		b := g.pop()
+		a := g.pop()
+		res := g.tempVar()
+		g.emit(core.OpBinOp, res, []string{a.name, "cmp", b.name}, "", pc)
+		g.push(value{name: res, text: res})
+
+	case op >= opIfeq && op <= opIfle, op == opIfnull || op == opIfnonnull:
+		a := g.pop()
+		zero := "0"
+		if op == opIfnull || op == opIfnonnull {
+			zero = "null"
+		}
+		g.branch(a.name, compareSymbol(op), zero, blockAt(ri.targets[0]), pc, blocks)
+		return true
+
+	case op >= opIfIcmpeq && op <= opIfAcmpne:
+		b := g.pop()
+		a := g.pop()
+		g.branch(a.name, compareSymbol(op), b.name, blockAt(ri.targets[0]), pc, blocks)
+		return true
+
+	case op == opGoto || op == opGotoW:
+		g.spillStack(blocks)
+		g.emit(core.OpJump, "", []string{blockAt(ri.targets[0])}, "", pc)
+		return true
+
+	case op == opJsr || op == opJsrW:
+		// Legacy subroutines: push a return address and jump
+		g.pushConst("retaddr", false, pc)
+		g.spillStack(blocks)
+		g.emit(core.OpJump, "", []string{blockAt(ri.targets[0])}, "", pc)
+		return true
+
+	case op == opRet:
+		return true
+
+	case op == opTableswitch || op == opLookupswitch:
+		key := g.pop()
+		g.spillStack(blocks)
+		ops := []string{key.name}
+		var cases []string
+		for i, t := range ri.targets {
+			ops = append(ops, blockAt(t))
+			if i > 0 {
+				cases = append(cases, fmt.Sprintf("%d:%s", ri.keys[i-1], blockAt(t)))
+			}
+		}
+		g.emit(core.OpBranch, "", ops, fmt.Sprintf("switch %s default %s cases %v", key.name, ops[1], cases), pc)
+		return true
+
+	case op >= opIreturn && op <= opAreturn:
+		v := g.pop()
+		g.emit(core.OpRet, "", []string{v.name}, "", pc)
+		return true
+
+	case op == opReturn:
+		g.emit(core.OpRet, "", nil, "", pc)
+		return true
+
+	case op == opGetstatic:
+		owner, name, desc := cf.memberRef(uint16(ri.index))
+		field := simpleName(owner) + "." + name
+		res := g.tempVar()
+		g.emit(core.OpLoad, res, []string{field}, "", pc)
+		g.push(value{name: res, text: field, origin: field, wide: isWide(desc)})
+
+	case op == opPutstatic:
+		owner, name, _ := cf.memberRef(uint16(ri.index))
+		v := g.pop()
+		g.emit(core.OpStore, simpleName(owner)+"."+name, []string{v.name}, "", pc)
+
+	case op == opGetfield:
+		_, name, desc := cf.memberRef(uint16(ri.index))
+		obj := g.pop()
+		field := g.chainText(obj) + "." + name
+		res := g.tempVar()
+		// The field below the object's value is an operand as well, so taint on the
+		// whole object, or on this field of it, reaches the load
+		g.emit(core.OpLoad, res, []string{field, obj.name + "." + name}, "", pc)
+		g.push(value{name: res, text: field, origin: field, wide: isWide(desc)})
+
+	case op == opPutfield:
+		_, name, _ := cf.memberRef(uint16(ri.index))
+		v := g.pop()
+		obj := g.pop()
+		g.emit(core.OpStore, g.chainText(obj)+"."+name, []string{v.name}, "", pc)
+
+	case op >= opInvokevirtual && op <= opInvokeinterface:
+		owner, name, desc := cf.memberRef(uint16(ri.index))
+		g.invoke(op, owner, name, desc, pc)
+
+	case op == opInvokedynamic:
+		name, desc := cf.dynamicRef(uint16(ri.index))
+		params, ret := parseMethodDescriptor(desc)
+		args := g.popArgs(len(params))
+		res := g.tempVar()
+		g.emit(core.OpCall, res, append([]string{name}, args...), "", pc)
+		if ret != "V" {
+			g.push(value{name: res, text: name + "()", wide: isWide(ret)})
+		}
+
+	case op == opNew:
+		g.newCount++
+		g.push(value{name: "new", text: "new " + simpleName(cf.className(uint16(ri.index))), pending: g.newCount})
+
+	case op == opNewarray || op == opAnewarray || op == opMultianewarray:
+		dims := 1
+		if op == opMultianewarray {
+			dims = ri.value
+		}
+		args := g.popArgs(dims)
+		res := g.tempVar()
+		g.emit(core.OpCall, res, append([]string{"new[]"}, args...), "", pc)
+		g.push(value{name: res, text: res})
+
+	case op == opArraylength:
+		arr := g.pop()
+		res := g.tempVar()
+		g.emit(core.OpLoad, res, []string{g.chainText(arr) + ".length"}, "", pc)
+		g.push(value{name: res, text: res})
+
+	case op == opAthrow:
+		v := g.pop()
+		g.emit(core.OpCall, "", []string{"throw", v.name}, "", pc)
+		return true
+
+	case op == opCheckcast:
+		// Casts do not change the value
+
+	case op == opInstanceof:
+		a := g.pop()
+		res := g.tempVar()
+		g.emit(core.OpBinOp, res, []string{a.name, "instanceof", simpleName(cf.className(uint16(ri.index)))}, "", pc)
+		g.push(value{name: res, text: res})
+
+	case op == opMonitorenter || op == opMonitorexit:
+		g.pop()
+	}
+	return false
+}
+
+// invoke models invokevirtual/special/static/interface. The receiver is kept separate from
+// the arguments so that operand positions line up with the Go frontend: Operands[0] is the
+// callee name and Operands[1:] are the declared parameters.
+func (g *IRGenerator) invoke(op byte, owner, name, desc string, pc int) {
+	params, ret := parseMethodDescriptor(desc)
+	args := g.popArgs(len(params))
+
+	var recv value
+	callee := simpleName(owner) + "." + name
+	if op != opInvokestatic {
+		recv = g.pop()
+		switch {
+		case name == "<init>" && recv.pending != 0:
+			callee = "new " + simpleName(owner)
+		case op == opInvokespecial && recv.text == "this" && owner != g.class.Name:
+			callee = "super." + name
+		default:
+			callee = g.chainText(recv) + "." + name
+		}
+	}
+
+	res := g.tempVar()
+	g.emit(core.OpCall, res, append([]string{callee}, args...), "", pc)
+	if recv.pending == 0 && recv.name != "" {
+		g.block.Instructions[len(g.block.Instructions)-1].Receiver = recv.name
+	}
+
+	if name == "<init>" && recv.pending != 0 {
+		// Every copy of the uninitialized reference (from dup) now refers to the constructed object
+		obj := value{name: res, text: callee + "()"}
+		for i := range g.stack {
+			if g.stack[i].pending == recv.pending {
+				g.stack[i] = obj
+			}
+		}
+		return
+	}
+	if ret != "V" {
+		g.push(value{name: res, text: callee + "()", wide: isWide(ret)})
+	}
+}
+
+// branch emits the comparison and the conditional jump that ends the block
+func (g *IRGenerator) branch(a, sym, b, target string, pc int, blocks []*methodBlock) {
+	cond := g.tempVar()
+	g.emit(core.OpBinOp, cond, []string{a, sym, b}, "", pc)
+	g.spillStack(blocks)
+
+	next := "?"
+	for i, mb := range blocks {
+		if mb.bb == g.block && i+1 < len(blocks) {
+			next = blocks[i+1].bb.ID
+		}
+	}
+	g.emit(core.OpBranch, "", []string{cond, target, next}, "", pc)
+}
+
+// stackOp implements pop/dup/swap, which behave differently for long/double values
+func (g *IRGenerator) stackOp(op byte) {
+	switch op {
+	case opPop:
+		g.pop()
+	case opPop2:
+		if v := g.pop(); !v.wide {
+			g.pop()
+		}
+	case opDup:
+		v := g.pop()
+		g.push(v, v)
+	case opDupX1:
+		v1, v2 := g.pop(), g.pop()
+		g.push(v1, v2, v1)
+	case opDupX2:
+		v1, v2 := g.pop(), g.pop()
+		if v2.wide {
+			g.push(v1, v2, v1)
+		} else {
+			v3 := g.pop()
+			g.push(v1, v3, v2, v1)
+		}
+	case opDup2:
+		v1 := g.pop()
+		if v1.wide {
+			g.push(v1, v1)
+		} else {
+			v2 := g.pop()
+			g.push(v2, v1, v2, v1)
+		}
+	case opDup2X1:
+		v1 := g.pop()
+		if v1.wide {
+			v2 := g.pop()
+			g.push(v1, v2, v1)
+		} else {
+			v2, v3 := g.pop(), g.pop()
+			g.push(v2, v1, v3, v2, v1)
+		}
+	case opDup2X2:
+		v1 := g.pop()
+		if v1.wide {
+			v2 := g.pop()
+			if v2.wide {
+				g.push(v1, v2, v1)
+			} else {
+				v3 := g.pop()
+				g.push(v1, v3, v2, v1)
+			}
+		} else {
+			v2, v3 := g.pop(), g.pop()
+			if v3.wide {
+				g.push(v2, v1, v3, v2, v1)
+			} else {
+				v4 := g.pop()
+				g.push(v2, v1, v4, v3, v2, v1)
+			}
+		}
+	case opSwap:
+		v1, v2 := g.pop(), g.pop()
+		g.push(v1, v2)
+	}
+}
+
+// --- Helpers ---
+
+func (g *IRGenerator) push(vs ...value) {
+	g.stack = append(g.stack, vs...)
+}
+
+func (g *IRGenerator) pop() value {
+	if len(g.stack) == 0 {
+		// Unbalanced stack (e.g. unsupported jsr patterns); keep going with an unknown value
+		return value{name: "?", text: "?"}
+	}
+	v := g.stack[len(g.stack)-1]
+	g.stack = g.stack[:len(g.stack)-1]
+	return v
+}
+
+// popArgs pops n call arguments and returns them in declaration order
+func (g *IRGenerator) popArgs(n int) []string {
+	args := make([]string, n)
+	for i := n - 1; i >= 0; i-- {
+		args[i] = g.pop().name
+	}
+	return args
+}
+
+func (g *IRGenerator) pushConst(lit string, wide bool, pc int) {
+	res := g.tempVar()
+	g.emit(core.OpConst, res, []string{lit}, "", pc)
+	g.push(value{name: res, text: lit, wide: wide})
+}
+
+// chainText renders a receiver for call and field names, falling back to its temp if too long
+func (g *IRGenerator) chainText(v value) string {
+	if v.text == "" || len(v.text) > maxCalleeText {
+		return v.name
+	}
+	return v.text
+}
+
+// localName names a local slot using the debug table, or this/argN/localN without one
+func (g *IRGenerator) localName(slot, pc int) string {
+	if name := g.method.LocalName(slot, pc); name != "" {
+		return name
+	}
+	if slot == 0 && !g.method.IsStatic() {
+		return "this"
+	}
+	params, _ := parseMethodDescriptor(g.method.Descriptor)
+	s := 0
+	if !g.method.IsStatic() {
+		s = 1
+	}
+	for i, p := range params {
+		if s == slot {
+			return fmt.Sprintf("arg%d", i)
+		}
+		s++
+		if isWide(p) {
+			s++
+		}
+	}
+	return fmt.Sprintf("local%d", slot)
+}
+
+func (g *IRGenerator) emit(op core.OpCode, result string, operands []string, code string, pc int) {
+	if code == "" {
+		code = g.formatCode(op, result, operands)
+	}
+	line := 0
+	if pc >= 0 {
+		line = g.method.LineAt(pc)
+	} else if n := len(g.block.Instructions); n > 0 {
+		line = g.block.Instructions[n-1].Line
+	}
+	inst := &core.Instruction{
+		ID:       fmt.Sprintf("i%d", g.instCount),
+		Op:       op,
+		Result:   result,
+		Operands: operands,
+		Line:     line,
+		Code:     code,
+	}
+	g.instCount++
+	g.block.Instructions = append(g.block.Instructions, inst)
+}
+
+func (g *IRGenerator) tempVar() string {
+	return fmt.Sprintf("t%d", g.instCount)
+}
+
+func (g *IRGenerator) formatCode(op core.OpCode, res string, ops []string) string {
+	switch op {
+	case core.OpParam:
+		return fmt.Sprintf("param %s", res)
+	case core.OpStore:
+		return fmt.Sprintf("%s = %s", res, ops[0])
+	case core.OpLoad:
+		return fmt.Sprintf("%s = load %s", res, ops[0])
+	case core.OpCall:
+		if res == "" {
+			return fmt.Sprintf("%s %v", ops[0], ops[1:])
+		}
+		return fmt.Sprintf("%s = call %s(%v)", res, ops[0], ops[1:])
+	case core.OpConst:
+		return fmt.Sprintf("%s = const %s", res, ops[0])
+	case core.OpBinOp:
+		return fmt.Sprintf("%s = %s %s %s", res, ops[0], ops[1], ops[2])
+	case core.OpBranch:
+		return fmt.Sprintf("if %s goto %s else %s", ops[0], ops[1], ops[2])
+	case core.OpJump:
+		return fmt.Sprintf("goto %s", ops[0])
+	case core.OpRet:
+		return fmt.Sprintf("return %v", ops)
+	default:
+		return fmt.Sprintf("%s = %s %v", res, op, ops)
+	}
+}
+
+func arithSymbol(op byte) string {
+	if op >= opIshl {
+		return []string{"<<", ">>", ">>>", "&", "|", "^"}[(op-opIshl)/2]
+	}
+	return []string{"+", "-", "*", "/", "%"}[(op-opIadd)/4]
+}
+
+func compareSymbol(op byte) string {
+	switch op {
+	case opIfnull:
+		return "=="
+	case opIfnonnull:
+		return "!="
+	}
+	if op >= opIfIcmpeq {
+		if op >= opIfIcmpeq+6 {
+			return []string{"==", "!="}[op-opIfIcmpeq-6]
+		}
+		op -= opIfIcmpeq - opIfeq
+	}
+	return []string{"==", "!=", "<", ">=", ">", "<="}[op-opIfeq]
+}
+
+func convertsToWide(op byte) bool {
+	switch op {
+	case opI2l, opI2l + 2, opI2l + 5, opI2l + 7, opI2l + 8, opI2l + 10: // i2l, i2d, l2d, f2l, f2d, d2l
+		return true
+	}
+	return false
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sast-demo/pkg/baseline"
 	"sast-demo/pkg/core"
 	"sast-demo/pkg/engine"
+	"sast-demo/pkg/lang/external"
 	"sast-demo/pkg/lang/golang"
 	"sast-demo/pkg/lang/java"
+	"sast-demo/pkg/lang/jvm"
 	"strings"
 )
 
@@ -17,9 +20,47 @@ type AnalysisResult struct {
 	AST             *core.ASTNode        `json:"ast"` // Abstract Syntax Tree
 	Vulnerabilities []core.Vulnerability `json:"vulnerabilities"`
 	Logs            []string             `json:"logs"`
+	// Dataflow holds the results of the dataflow analyses requested in Options.Dataflow
+	Dataflow []*engine.DataflowView `json:"dataflow,omitempty"`
+
+	// Set when scanning against a baseline: accepted findings and baseline entries no longer found
+	Suppressed    []core.Vulnerability `json:"suppressed,omitempty"`
+	FixedBaseline []baseline.Entry     `json:"fixed_baseline,omitempty"`
+}
+
+// Options configures a scan beyond the built-in defaults
+type Options struct {
+	// Frontends are external parser processes, matched by file extension before the built-in ones
+	Frontends []external.Config
+	// PathsPerSink and PathBudget override the engine's path search limits when non-zero
+	PathsPerSink int
+	PathBudget   int
+	// AccessPathDepth overrides how many fields taint facts keep when non-zero
+	AccessPathDepth int
+	// ContextDepth overrides how many nested calls function summaries analyze when non-zero
+	ContextDepth int
+	// Root is the directory fingerprints take file paths relative to, so the same
+	// code in two directories is told apart; FingerprintRoot(Baseline) when empty
+	Root string
+	// Baseline, if set, moves accepted findings from Vulnerabilities to Suppressed
+	Baseline *baseline.Baseline
+	// ImplicitFlows also reports sinks decided by branches on sources (engine.Config.ImplicitRules)
+	ImplicitFlows bool
+	// LocalInputRule reports command-line input reaching command execution under
+	// the MEDIUM rule "rce-local" instead of "rce" (engine.Config.SplitLocalInput)
+	LocalInputRule bool
+	// ElementPrecision overrides how container elements are told apart when set
+	// (engine.ElementsByKey or engine.ElementsWhole)
+	ElementPrecision string
+	// Dataflow names built-in dataflow analyses (engine.DataflowAnalyses) to run on the IR for display
+	Dataflow []string
 }
 
 func Analyze(filePath string) (*AnalysisResult, error) {
+	return AnalyzeWithOptions(filePath, Options{})
+}
+
+func AnalyzeWithOptions(filePath string, opts Options) (*AnalysisResult, error) {
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		return nil, err
@@ -32,13 +73,46 @@ func Analyze(filePath string) (*AnalysisResult, error) {
 
 	ext := strings.ToLower(filepath.Ext(absPath))
 	cfg := engine.DefaultRules()
+	if opts.PathsPerSink > 0 {
+		cfg.PathsPerSink = opts.PathsPerSink
+	}
+	if opts.PathBudget > 0 {
+		cfg.PathBudget = opts.PathBudget
+	}
+	if opts.AccessPathDepth > 0 {
+		cfg.AccessPathDepth = opts.AccessPathDepth
+	}
+	if opts.ContextDepth > 0 {
+		cfg.ContextDepth = opts.ContextDepth
+	}
+	cfg.ImplicitFlows = opts.ImplicitFlows
+	if opts.LocalInputRule {
+		cfg.SplitLocalInput()
+	}
+	if opts.ElementPrecision != "" {
+		cfg.ElementPrecision = opts.ElementPrecision
+	}
 	eng := engine.NewEngine(cfg)
 
 	result.Logs = append(result.Logs, fmt.Sprintf("Starting analysis for %s (Type: %s)", absPath, ext))
 
 	var vulns []core.Vulnerability
 
-	if ext == ".go" {
+	if fe := external.Find(opts.Frontends, ext); fe != nil {
+		result.Logs = append(result.Logs, fmt.Sprintf("Using external frontend %s (%s)...", fe.Name, fe.Command))
+		resp, err := external.NewFrontend(*fe).Run(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("External frontend failed: %v", err)
+		}
+		result.IR = resp.IR
+		result.AST = resp.AST
+		result.Logs = append(result.Logs, resp.Logs...)
+		result.Logs = append(result.Logs, fmt.Sprintf("Generated IR with %d functions", len(resp.IR.Functions)))
+
+		vulns = eng.AnalyzeIR(resp.IR, absPath)
+		result.Logs = append(result.Logs, fmt.Sprintf("Engine found %d vulnerabilities", len(vulns)))
+		logSearchStats(eng, result)
+	} else if ext == ".go" {
 		result.Logs = append(result.Logs, "Using Go IR Generator...")
 		gen := golang.NewIRGenerator()
 		ir, err := gen.Generate(absPath)
@@ -60,6 +134,13 @@ func Analyze(filePath string) (*AnalysisResult, error) {
 
 		vulns = eng.AnalyzeIR(ir, absPath)
 		result.Logs = append(result.Logs, fmt.Sprintf("Engine found %d vulnerabilities", len(vulns)))
+		logSearchStats(eng, result)
+
+		if pm, err := golang.NewPatternMatcher(absPath); err == nil {
+			vulns = append(vulns, runPatternRules(eng, pm, absPath, result)...)
+		} else {
+			result.Logs = append(result.Logs, fmt.Sprintf("Go pattern matcher failed: %v", err))
+		}
 
 	} else if ext == ".java" {
 		result.Logs = append(result.Logs, "Using Java IR Generator (Experimental)...")
@@ -83,17 +164,165 @@ func Analyze(filePath string) (*AnalysisResult, error) {
 
 		vulns = eng.AnalyzeIR(ir, absPath)
 		result.Logs = append(result.Logs, fmt.Sprintf("Engine found %d vulnerabilities", len(vulns)))
+		logSearchStats(eng, result)
+
+		if pm, err := java.NewPatternMatcher(absPath); err == nil {
+			vulns = append(vulns, runPatternRules(eng, pm, absPath, result)...)
+		} else {
+			result.Logs = append(result.Logs, fmt.Sprintf("Java pattern matcher failed: %v", err))
+		}
+	} else if ext == ".class" || ext == ".jar" {
+		result.Logs = append(result.Logs, "Using JVM Bytecode IR Generator...")
+		gen := jvm.NewIRGenerator()
+		ir, err := gen.Generate(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("Bytecode IR Gen failed: %v", err)
+		}
+		result.IR = ir
+		for _, err := range gen.Skipped {
+			result.Logs = append(result.Logs, fmt.Sprintf("Skipped %v", err))
+		}
+		result.Logs = append(result.Logs, fmt.Sprintf("Generated IR with %d functions", len(ir.Functions)))
+
+		// AST Generation (class structure and disassembly)
+		result.Logs = append(result.Logs, "Generating Bytecode AST...")
+		astGen := jvm.NewASTGenerator()
+		astRoot, err := astGen.Generate(absPath)
+		if err == nil {
+			result.AST = astRoot
+		} else {
+			result.Logs = append(result.Logs, fmt.Sprintf("Bytecode AST Gen failed: %v", err))
+		}
+
+		vulns = eng.AnalyzeIR(ir, absPath)
+		result.Logs = append(result.Logs, fmt.Sprintf("Engine found %d vulnerabilities", len(vulns)))
+		logSearchStats(eng, result)
 	} else {
 		return nil, fmt.Errorf("Unsupported file type: %s", ext)
 	}
 
+	// Dead blocks are already marked by the taint analysis, so the dataflow
+	// results leave them out as well
+	for _, name := range opts.Dataflow {
+		view, err := engine.RunDataflow(result.IR, name)
+		if err != nil {
+			result.Logs = append(result.Logs, fmt.Sprintf("Dataflow analysis skipped: %v", err))
+			continue
+		}
+		result.Dataflow = append(result.Dataflow, view)
+	}
+
 	// Post-process: Enrich Path with Source Code
-	enrichVulnerabilities(absPath, vulns)
-	result.Vulnerabilities = vulns
+	// Compiled classes have no source text to map lines onto, so their IR code is kept.
+	if ext != ".class" && ext != ".jar" {
+		enrichVulnerabilities(absPath, vulns)
+	}
+	// Fingerprints use the enriched source lines, so they are computed last
+	engine.SortVulnerabilities(vulns)
+	root := opts.Root
+	if root == "" {
+		root = FingerprintRoot(opts.Baseline)
+	} else if root, err = filepath.Abs(root); err != nil {
+		return nil, err
+	}
+	engine.AssignFingerprints(vulns, root)
+
+	active, suppressed := engine.ApplySuppressions(vulns, collectSuppressions(ext, absPath, result))
+	inline := 0
+	for _, v := range suppressed {
+		if v.Suppression.Kind == "inline" {
+			inline++
+		}
+	}
+	if inline > 0 {
+		result.Logs = append(result.Logs, fmt.Sprintf("%d findings suppressed by sast:ignore comments", inline))
+	}
+	result.Vulnerabilities = active
+	result.Suppressed = suppressed
+
+	if opts.Baseline != nil {
+		split := opts.Baseline.Apply(append(active, suppressed...), []string{absPath})
+		result.Vulnerabilities = split.New
+		result.Suppressed = split.Suppressed
+		result.FixedBaseline = split.Fixed
+		result.Logs = append(result.Logs, fmt.Sprintf("Baseline: %d new, %d suppressed, %d fixed",
+			len(split.New), len(split.Suppressed), len(split.Fixed)))
+	}
 
 	return result, nil
 }
 
+// FingerprintRoot is the directory fingerprints are relative to: the directory of
+// the baseline b, whose entries are stored relative to it as well, or the working
+// directory without one. Whether a directory, a subdirectory or a single file is
+// scanned, a file keeps its fingerprints, so baselines match across such scans.
+func FingerprintRoot(b *baseline.Baseline) string {
+	if b != nil {
+		if dir, err := filepath.Abs(filepath.Dir(b.Path())); err == nil {
+			return dir
+		}
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// collectSuppressions reads the sast:ignore comments of source files and logs the unusable ones
+func collectSuppressions(ext, absPath string, result *AnalysisResult) []core.Suppression {
+	var sups []core.Suppression
+	var err error
+	switch ext {
+	case ".go":
+		sups, err = golang.CollectSuppressions(absPath)
+	case ".java":
+		sups, err = java.CollectSuppressions(absPath)
+	default:
+		return nil
+	}
+	if err != nil {
+		result.Logs = append(result.Logs, fmt.Sprintf("Reading suppressions failed: %v", err))
+		return nil
+	}
+	for _, s := range sups {
+		if s.Reason == "" {
+			result.Logs = append(result.Logs, fmt.Sprintf("Ignoring sast:ignore on line %d: a \"reason:\" is required", s.CommentLine))
+		}
+	}
+	return sups
+}
+
+// logSearchStats reports how much taint solving the engine did and whether it was cut short
+func logSearchStats(eng *engine.Engine, result *AnalysisResult) {
+	result.Logs = append(result.Logs, fmt.Sprintf("Processed %d taint dataflow nodes", eng.Stats.NodesExplored))
+	if eng.Stats.BudgetExhausted > 0 {
+		result.Logs = append(result.Logs, fmt.Sprintf("Node budget exhausted in %d taint solves; some sinks may be missing", eng.Stats.BudgetExhausted))
+	}
+	if eng.Stats.Guarded > 0 {
+		result.Logs = append(result.Logs, fmt.Sprintf("%d sinks are guarded by validators", eng.Stats.Guarded))
+	}
+	if eng.Stats.Summaries > 0 {
+		result.Logs = append(result.Logs, fmt.Sprintf("Computed %d function summaries, reused them for %d more calls", eng.Stats.Summaries, eng.Stats.SummariesReused))
+	}
+	if eng.Stats.ImplicitFlows > 0 {
+		result.Logs = append(result.Logs, fmt.Sprintf("%d implicit flows through branches on sources", eng.Stats.ImplicitFlows))
+	}
+	if eng.Stats.StoredFlows > 0 {
+		result.Logs = append(result.Logs, fmt.Sprintf("%d second-order flows through databases, caches or files", eng.Stats.StoredFlows))
+	}
+	if eng.Stats.DeadBlocks > 0 {
+		result.Logs = append(result.Logs, fmt.Sprintf("%d unreachable blocks pruned by constant propagation", eng.Stats.DeadBlocks))
+	}
+}
+
+// runPatternRules applies the structural rules and logs any pattern that failed to parse
+func runPatternRules(eng *engine.Engine, pm engine.PatternMatcher, absPath string, result *AnalysisResult) []core.Vulnerability {
+	found, errs := eng.AnalyzePatterns(pm, absPath)
+	for _, err := range errs {
+		result.Logs = append(result.Logs, fmt.Sprintf("Pattern rule skipped: %v", err))
+	}
+	result.Logs = append(result.Logs, fmt.Sprintf("Pattern rules found %d issues", len(found)))
+	return found
+}
+
 func enrichVulnerabilities(filePath string, vulns []core.Vulnerability) {
 	// Read file content once
 	fileContent, err := os.ReadFile(filePath)
@@ -104,23 +333,20 @@ func enrichVulnerabilities(filePath string, vulns []core.Vulnerability) {
 
 	for i := range vulns {
 		v := &vulns[i]
-		var newPath []*core.Node
-		lastLine := -1
-
-		// Update Path Nodes and Deduplicate
-		for _, node := range v.Path {
-			if node.Line > 0 && node.Line <= len(lines) {
-				// Replace Code with actual source line (trimmed)
-				node.Code = strings.TrimSpace(lines[node.Line-1])
-			}
-
-			// Deduplication logic
-			if node.Line != lastLine {
-				newPath = append(newPath, node)
-				lastLine = node.Line
+		v.Path = enrichPath(v.Path, lines)
+		// Locations shown next to the path: the guard, the implicit-flow branch and the store accesses
+		marks := []*core.Node{v.Guard, v.Branch}
+		if v.Stored != nil {
+			marks = append(marks, v.Stored.Write, v.Stored.Read)
+		}
+		for _, n := range marks {
+			if n != nil && n.Line > 0 && n.Line <= len(lines) {
+				n.Code = strings.TrimSpace(lines[n.Line-1])
 			}
 		}
-		v.Path = newPath
+		for j := range v.AlternatePaths {
+			v.AlternatePaths[j] = enrichPath(v.AlternatePaths[j], lines)
+		}
 
 		// Also update Source and Sink if needed
 		if v.Source.Line > 0 && v.Source.Line <= len(lines) {
@@ -132,6 +358,26 @@ func enrichVulnerabilities(filePath string, vulns []core.Vulnerability) {
 	}
 }
 
+// enrichPath replaces IR code with source lines and collapses consecutive steps on the same line
+func enrichPath(path []*core.Node, lines []string) []*core.Node {
+	var newPath []*core.Node
+	lastLine := -1
+
+	for _, node := range path {
+		if node.Line > 0 && node.Line <= len(lines) {
+			// Replace Code with actual source line (trimmed)
+			node.Code = strings.TrimSpace(lines[node.Line-1])
+		}
+
+		// Deduplication logic
+		if node.Line != lastLine {
+			newPath = append(newPath, node)
+			lastLine = node.Line
+		}
+	}
+	return newPath
+}
+
 func ReadFile(filePath string) (string, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
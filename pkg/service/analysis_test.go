@@ -0,0 +1,114 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"sast-demo/pkg/baseline"
+	"strings"
+	"testing"
+)
+
+const sqliSource = `package main
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+func handler(r *http.Request, db *sql.DB) {
+	id := r.URL.Query().Get("id")
+	db.Query("SELECT * FROM t WHERE id = " + id)
+}
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fingerprints(t *testing.T, file string, opts Options) []string {
+	t.Helper()
+	res, err := AnalyzeWithOptions(file, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fps []string
+	for _, v := range res.Vulnerabilities {
+		fps = append(fps, v.Fingerprint)
+	}
+	return fps
+}
+
+// A file keeps its fingerprints against a baseline whatever the working directory,
+// and copies of it in other directories get others
+func TestFingerprintRootFollowsBaseline(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a", "h.go"), filepath.Join(dir, "b", "h.go")
+	writeFile(t, a, sqliSource)
+	writeFile(t, b, sqliSource)
+	base := baseline.New(filepath.Join(dir, "baseline.json"))
+
+	fa := fingerprints(t, a, Options{Baseline: base})
+	if len(fa) != 1 {
+		t.Fatalf("findings in a/h.go: %v", fa)
+	}
+	fb := fingerprints(t, b, Options{Baseline: base})
+	if len(fb) != 1 || fb[0] == fa[0] {
+		t.Errorf("a/h.go %v and its copy b/h.go %v", fa, fb)
+	}
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(filepath.Dir(a))
+	if again := fingerprints(t, a, Options{Baseline: base}); len(again) != 1 || again[0] != fa[0] {
+		t.Errorf("from another working directory: %v, want %v", again, fa)
+	}
+	if root := FingerprintRoot(base); root != dir {
+		t.Errorf("FingerprintRoot = %s, want %s", root, dir)
+	}
+}
+
+func TestBaselineSuppressesOnlyAcceptedFile(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a", "h.go"), filepath.Join(dir, "b", "h.go")
+	writeFile(t, a, sqliSource)
+	base := baseline.New(filepath.Join(dir, "baseline.json"))
+
+	res, err := AnalyzeWithOptions(a, Options{Baseline: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.Update(res.Vulnerabilities, []string{a})
+
+	writeFile(t, b, sqliSource)
+	for file, wantNew := range map[string]int{a: 0, b: 1} {
+		res, err := AnalyzeWithOptions(file, Options{Baseline: base})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Vulnerabilities) != wantNew || len(res.FixedBaseline) != 0 {
+			t.Errorf("%s: %d new, %d fixed; want %d new", file, len(res.Vulnerabilities), len(res.FixedBaseline), wantNew)
+		}
+	}
+}
+
+// Suppressed findings are reported apart with their justification, not dropped
+func TestInlineSuppression(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "h.go")
+	writeFile(t, file, strings.Replace(sqliSource, `+ id)`, `+ id) // sast:ignore sqli reason: id is numeric`, 1))
+	res, err := AnalyzeWithOptions(file, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Vulnerabilities) != 0 || len(res.Suppressed) != 1 {
+		t.Fatalf("%d active, %d suppressed; want 0 and 1", len(res.Vulnerabilities), len(res.Suppressed))
+	}
+	if s := res.Suppressed[0].Suppression; s.Kind != "inline" || s.Reason != "id is numeric" || s.Line != 10 {
+		t.Errorf("suppression %+v", *s)
+	}
+}
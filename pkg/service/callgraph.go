@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"sast-demo/pkg/core"
+	"sast-demo/pkg/engine"
+	"sast-demo/pkg/lang/external"
+	"sast-demo/pkg/lang/golang"
+	"sast-demo/pkg/lang/java"
+	"sast-demo/pkg/lang/jvm"
+	"strings"
+)
+
+// CallGraphResult is the call graph of a file or of every file under a directory
+type CallGraphResult struct {
+	Files []string `json:"files"`
+	*engine.CallGraph
+	Errors []string `json:"errors,omitempty"`
+}
+
+// BuildCallGraph merges the IR of every supported file under path into one program,
+// so calls between files resolve, and builds its call graph. A function name defined
+// by several files is kept apart as "name@file" for every file after the first.
+func BuildCallGraph(path string, opts Options) (*CallGraphResult, error) {
+	files, err := CollectFiles(path, opts.Frontends)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CallGraphResult{Files: files}
+	prog := core.NewProgramIR()
+	fileOf := make(map[string]string)
+	for _, file := range files {
+		ir, err := GenerateIR(file, opts.Frontends)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		for _, name := range ir.FunctionNames() {
+			fn := ir.Functions[name]
+			if _, taken := prog.Functions[name]; taken {
+				name += "@" + filepath.Base(file)
+				fn.Name = name
+			}
+			prog.Functions[name] = fn
+			fileOf[name] = file
+		}
+	}
+	if len(prog.Functions) == 0 && len(result.Errors) > 0 {
+		return nil, fmt.Errorf("no file could be analyzed: %s", result.Errors[0])
+	}
+
+	result.CallGraph = engine.BuildCallGraph(prog, engine.DefaultRules())
+	if len(files) > 1 {
+		for _, n := range result.Nodes {
+			n.File = fileOf[n.ID]
+		}
+	}
+	return result, nil
+}
+
+// GenerateIR runs the frontend registered for the file's extension and returns its IR only
+func GenerateIR(filePath string, frontends []external.Config) (*core.ProgramIR, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(absPath))
+	if fe := external.Find(frontends, ext); fe != nil {
+		resp, err := external.NewFrontend(*fe).Run(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("External frontend failed: %v", err)
+		}
+		return resp.IR, nil
+	}
+	switch ext {
+	case ".go":
+		ir, err := golang.NewIRGenerator().Generate(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("Go IR Gen failed: %v", err)
+		}
+		return ir, nil
+	case ".java":
+		ir, err := java.NewJavaIRGenerator().Generate(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("Java IR Gen failed: %v", err)
+		}
+		return ir, nil
+	case ".class", ".jar":
+		ir, err := jvm.NewIRGenerator().Generate(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("Bytecode IR Gen failed: %v", err)
+		}
+		return ir, nil
+	}
+	return nil, fmt.Errorf("Unsupported file type: %s", ext)
+}
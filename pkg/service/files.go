@@ -0,0 +1,46 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"sast-demo/pkg/lang/external"
+	"sort"
+	"strings"
+)
+
+// Extensions handled by the built-in frontends
+var builtinExtensions = map[string]bool{".go": true, ".java": true, ".class": true, ".jar": true}
+
+// Directories that never contain first-party sources
+var skipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// CollectFiles expands a directory into the files some frontend can analyze
+func CollectFiles(root string, frontends []external.Config) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+
+	var files []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if builtinExtensions[ext] || external.Find(frontends, ext) != nil {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sast-demo/pkg/engine"
+	"strings"
+)
+
+// SliceResult is a program slice of one source line, with the source lines it covers
+type SliceResult struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	*engine.Slice
+	Source []SourceLine `json:"source"`
+}
+
+// SourceLine is a line of the slice; Criterion marks the line sliced from
+type SourceLine struct {
+	Line      int    `json:"line"`
+	Code      string `json:"code"`
+	Criterion bool   `json:"criterion,omitempty"`
+}
+
+// SliceLine slices a file from the instructions generated for one of its lines.
+// A backward slice holds what may influence the line, a forward slice what it may influence.
+func SliceLine(filePath string, line int, backward bool, opts Options) (*SliceResult, error) {
+	prog, err := GenerateIR(filePath, opts.Frontends)
+	if err != nil {
+		return nil, err
+	}
+	engine.MarkDeadCode(prog)
+	pdg := engine.BuildPDG(prog, engine.AnalyzePointsTo(prog))
+	criterion := pdg.InstructionsAt(line)
+	if len(criterion) == 0 {
+		return nil, fmt.Errorf("no reachable instruction on line %d", line)
+	}
+	dir := engine.Forward
+	if backward {
+		dir = engine.Backward
+	}
+
+	result := &SliceResult{File: filePath, Line: line, Slice: pdg.Slice(criterion, dir)}
+	// Compiled classes have no source text; their lines show the IR instead
+	var lines []string
+	if ext := strings.ToLower(filepath.Ext(filePath)); ext != ".class" && ext != ".jar" {
+		if content, err := os.ReadFile(filePath); err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+	}
+	for _, l := range result.Lines {
+		src := SourceLine{Line: l, Criterion: l == line}
+		if l <= len(lines) {
+			src.Code = strings.TrimRight(lines[l-1], " \t\r")
+		} else {
+			var codes []string
+			for _, n := range result.Nodes {
+				if n.Line == l {
+					codes = append(codes, n.Code)
+				}
+			}
+			src.Code = strings.Join(codes, "; ")
+		}
+		result.Source = append(result.Source, src)
+	}
+	return result, nil
+}